@@ -2,8 +2,10 @@ package fileio
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"os"
 	"strconv"
 	"strings"
 )
@@ -13,29 +15,145 @@ const (
 	MaxArrayLength = 100000 // Maximum reasonable array length to prevent memory issues
 )
 
+// ErrTruncated is returned when the file ends in the middle of a section
+type ErrTruncated struct {
+	Section string
+	Offset  int64
+}
+
+func (e *ErrTruncated) Error() string {
+	return fmt.Sprintf("file is truncated in %s at offset 0x%X", e.Section, e.Offset)
+}
+
+// ErrImplausibleLength is returned when a length or count read from the file is too large to be
+// real, which usually means the file is corrupt or the parser lost its place
+type ErrImplausibleLength struct {
+	Section string
+	Offset  int64
+	Length  int64
+}
+
+func (e *ErrImplausibleLength) Error() string {
+	return fmt.Sprintf("implausible length %d for %s at offset 0x%X", e.Length, e.Section, e.Offset)
+}
+
+// currentOffset returns the position of the reader from the start of its section
+func currentOffset(reader *io.SectionReader) int64 {
+	// Seeking relative to the current position can't fail for a section reader
+	offset, _ := reader.Seek(0, io.SeekCurrent)
+	return offset
+}
+
+// remainingBytes returns the number of bytes left to read in the section
+func remainingBytes(reader *io.SectionReader) int64 {
+	return reader.Size() - currentOffset(reader)
+}
+
+// newReadError describes a failed read of section that started at offset
+func newReadError(section string, offset int64, err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return &ErrTruncated{Section: section, Offset: offset}
+	}
+	return fmt.Errorf("failed to read %s at offset 0x%X: %w", section, offset, err)
+}
+
+// readStruct reads fixed-size data from the binary stream
+func readStruct(reader *io.SectionReader, data interface{}, section string) error {
+	offset := currentOffset(reader)
+	if size := binary.Size(data); size >= 0 && int64(size) > remainingBytes(reader) {
+		return &ErrTruncated{Section: section, Offset: offset}
+	}
+	if err := binary.Read(reader, binary.LittleEndian, data); err != nil {
+		return newReadError(section, offset, err)
+	}
+	return nil
+}
+
+// readUint32 reads a uint32 from the binary stream
+func readUint32(reader *io.SectionReader, section string) (uint32, error) {
+	var value uint32
+	err := readStruct(reader, &value, section)
+	return value, err
+}
+
+// readUint16 reads a uint16 from the binary stream
+func readUint16(reader *io.SectionReader, section string) (uint16, error) {
+	var value uint16
+	err := readStruct(reader, &value, section)
+	return value, err
+}
+
+// readInt32 reads an int32 from the binary stream
+func readInt32(reader *io.SectionReader, section string) (int32, error) {
+	var value int32
+	err := readStruct(reader, &value, section)
+	return value, err
+}
+
+// readByteArray reads a byte array of specified size from the binary stream. The size is checked
+// against the rest of the file first, so a corrupt size can't cause a huge allocation.
+func readByteArray(reader *io.SectionReader, size uint32, section string) ([]byte, error) {
+	if int64(size) > remainingBytes(reader) {
+		return nil, &ErrTruncated{Section: section, Offset: currentOffset(reader)}
+	}
+	dataBytes := make([]byte, size)
+	if err := readStruct(reader, dataBytes, section); err != nil {
+		return nil, err
+	}
+	return dataBytes, nil
+}
+
+// readCount reads a uint32 element count and checks it against MaxArrayLength
+func readCount(reader *io.SectionReader, section string) (int, error) {
+	offset := currentOffset(reader)
+	count, err := readUint32(reader, section+" count")
+	if err != nil {
+		return 0, err
+	}
+	if count > MaxArrayLength {
+		return 0, &ErrImplausibleLength{Section: section, Offset: offset, Length: int64(count)}
+	}
+	return int(count), nil
+}
+
 // readVarString reads a variable-length string from the binary stream
 // Format: [length:uint32][string:bytes]
 func readVarString(reader *io.SectionReader, varName string) (string, error) {
-	variableLength := uint32(0)
-	if err := binary.Read(reader, binary.LittleEndian, &variableLength); err != nil {
-		return "", fmt.Errorf("failed to load variable length for %s: %w", varName, err)
+	variableLength, err := readUint32(reader, varName+" length")
+	if err != nil {
+		return "", err
 	}
 
-	stringValue := make([]byte, variableLength)
-	if err := binary.Read(reader, binary.LittleEndian, &stringValue); err != nil {
-		return "", fmt.Errorf("failed to load string value for %s (length: %v): %w", varName, variableLength, err)
+	stringValue, err := readByteArray(reader, variableLength, varName)
+	if err != nil {
+		return "", err
 	}
 
 	return string(stringValue[:]), nil
 }
 
+// readVarStringArray reads a count-prefixed array of variable-length strings
+func readVarStringArray(reader *io.SectionReader, arrayName string) ([]string, error) {
+	count, err := readCount(reader, arrayName)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]string, count)
+	for i := range values {
+		if values[i], err = readVarString(reader, arrayName); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
 // readArray reads an array of file config entries from the binary stream
 func readArray(reader *io.SectionReader, arrayName string, fileConfigEntries []Civ5ReplayFileConfigEntry) error {
-	arrayLength := unsafeReadUint32(reader)
-	if arrayLength > MaxArrayLength {
-		return fmt.Errorf("array length may be too long for %s: %d", arrayName, arrayLength)
+	arrayLength, err := readCount(reader, arrayName)
+	if err != nil {
+		return err
 	}
-	for i := 0; i < int(arrayLength); i++ {
+	for i := 0; i < arrayLength; i++ {
 		if _, err := readFileConfig(reader, fileConfigEntries); err != nil {
 			return fmt.Errorf("failed to read array element %d for %s: %w", i, arrayName, err)
 		}
@@ -45,10 +163,7 @@ func readArray(reader *io.SectionReader, arrayName string, fileConfigEntries []C
 
 // readFileConfig reads a file configuration entry from the binary stream
 func readFileConfig(reader *io.SectionReader, fileConfigEntries []Civ5ReplayFileConfigEntry) ([]string, error) {
-	pos, err := reader.Seek(0, io.SeekCurrent)
-	if err != nil {
-		panic(err)
-	}
+	pos := currentOffset(reader)
 
 	fieldValues := make([]string, 0)
 
@@ -62,37 +177,43 @@ func readFileConfig(reader *io.SectionReader, fileConfigEntries []Civ5ReplayFile
 			fieldValues = append(fieldValues, fmt.Sprintf("%v(str):%v", fileConfigEntry.VariableName, value))
 		} else if fileConfigEntry.VariableType == "float32" {
 			value := float32(0)
-			if err := binary.Read(reader, binary.LittleEndian, &value); err != nil {
-				return nil, fmt.Errorf("failed to load float32 for %s: %w", fileConfigEntry.VariableName, err)
+			if err := readStruct(reader, &value, fileConfigEntry.VariableName); err != nil {
+				return nil, err
 			}
 			fieldValues = append(fieldValues, fmt.Sprintf("%v(f32):%f", fileConfigEntry.VariableName, value))
 		} else if fileConfigEntry.VariableType == "uint32" {
-			value := unsafeReadUint32(reader)
+			value, err := readUint32(reader, fileConfigEntry.VariableName)
+			if err != nil {
+				return nil, err
+			}
 			fieldValues = append(fieldValues, fmt.Sprintf("%v(u32):%d", fileConfigEntry.VariableName, value))
 		} else if fileConfigEntry.VariableType == "int32" {
-			signedIntValue := int32(0)
-			if err := binary.Read(reader, binary.LittleEndian, &signedIntValue); err != nil {
-				return nil, fmt.Errorf("failed to load int32 for %s: %w", fileConfigEntry.VariableName, err)
+			signedIntValue, err := readInt32(reader, fileConfigEntry.VariableName)
+			if err != nil {
+				return nil, err
 			}
 			fieldValues = append(fieldValues, fmt.Sprintf("%v(i32):%d", fileConfigEntry.VariableName, signedIntValue))
 		} else if fileConfigEntry.VariableType == "uint16" {
-			value := unsafeReadUint16(reader)
+			value, err := readUint16(reader, fileConfigEntry.VariableName)
+			if err != nil {
+				return nil, err
+			}
 			fieldValues = append(fieldValues, fmt.Sprintf("%v(u16):%d", fileConfigEntry.VariableName, value))
 		} else if fileConfigEntry.VariableType == "uint8" {
 			unsignedIntValue := uint8(0)
-			if err := binary.Read(reader, binary.LittleEndian, &unsignedIntValue); err != nil {
-				return nil, fmt.Errorf("failed to load uint8 for %s: %w", fileConfigEntry.VariableName, err)
+			if err := readStruct(reader, &unsignedIntValue, fileConfigEntry.VariableName); err != nil {
+				return nil, err
 			}
 			fieldValues = append(fieldValues, fmt.Sprintf("%v(u8):%d", fileConfigEntry.VariableName, unsignedIntValue))
 		} else if strings.Contains(fileConfigEntry.VariableType, "bytearray") {
 			byteArrayLength, err := strconv.Atoi(fileConfigEntry.VariableType[len("bytearray:"):])
-			if err != nil {
-				return nil, fmt.Errorf("invalid byte array type in file config for %s: %w", fileConfigEntry.VariableName, err)
+			if err != nil || byteArrayLength < 0 {
+				return nil, fmt.Errorf("invalid byte array type in file config for %s: %q", fileConfigEntry.VariableName, fileConfigEntry.VariableType)
 			}
 
-			byteBlock := make([]byte, byteArrayLength)
-			if err := binary.Read(reader, binary.LittleEndian, &byteBlock); err != nil {
-				return nil, fmt.Errorf("invalid byte array data for %s: %w", fileConfigEntry.VariableName, err)
+			byteBlock, err := readByteArray(reader, uint32(byteArrayLength), fileConfigEntry.VariableName)
+			if err != nil {
+				return nil, err
 			}
 
 			fieldValues = append(fieldValues, fmt.Sprintf("%v(bytearray):%v", fileConfigEntry.VariableName, byteBlock))
@@ -106,22 +227,16 @@ func readFileConfig(reader *io.SectionReader, fileConfigEntries []Civ5ReplayFile
 	return fieldValues, nil
 }
 
-// unsafeReadUint32 reads a uint32 from the binary stream, panicking on error
-// This is used for internal operations where errors should not occur
-func unsafeReadUint32(reader *io.SectionReader) uint32 {
-	unsignedIntValue := uint32(0)
-	if err := binary.Read(reader, binary.LittleEndian, &unsignedIntValue); err != nil {
-		panic(fmt.Sprintf("failed to load uint32: %v", err))
+// openInputFile opens a file for reading and returns it with its size
+func openInputFile(filename string) (*os.File, int64, error) {
+	inputFile, err := os.Open(filename)
+	if err != nil {
+		return nil, 0, err
 	}
-	return unsignedIntValue
-}
-
-// unsafeReadUint16 reads a uint16 from the binary stream, panicking on error
-// This is used for internal operations where errors should not occur
-func unsafeReadUint16(reader *io.SectionReader) uint16 {
-	unsignedIntValue := uint16(0)
-	if err := binary.Read(reader, binary.LittleEndian, &unsignedIntValue); err != nil {
-		panic(fmt.Sprintf("failed to load uint16: %v", err))
+	fi, err := inputFile.Stat()
+	if err != nil {
+		inputFile.Close()
+		return nil, 0, fmt.Errorf("failed to get file info for %q: %w", filename, err)
 	}
-	return unsignedIntValue
+	return inputFile, fi.Size(), nil
 }
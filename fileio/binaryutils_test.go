@@ -3,6 +3,7 @@ package fileio
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"io"
 	"testing"
 )
@@ -54,35 +55,100 @@ func TestReadVarStringTruncated(t *testing.T) {
 	}
 }
 
-func TestUnsafeReadUint32(t *testing.T) {
+func TestReadUint32(t *testing.T) {
 	var buf bytes.Buffer
 	binary.Write(&buf, binary.LittleEndian, uint32(0xDEADBEEF))
 
 	reader := newSectionReader(buf.Bytes())
-	got := unsafeReadUint32(reader)
+	got, err := readUint32(reader, "value")
+	if err != nil {
+		t.Fatalf("readUint32 returned error: %v", err)
+	}
 	if got != 0xDEADBEEF {
-		t.Errorf("unsafeReadUint32() = %#x, want %#x", got, 0xDEADBEEF)
+		t.Errorf("readUint32() = %#x, want %#x", got, 0xDEADBEEF)
 	}
 }
 
-func TestUnsafeReadUint32Panics(t *testing.T) {
-	defer func() {
-		if r := recover(); r == nil {
-			t.Errorf("expected panic reading uint32 from empty reader")
-		}
-	}()
-	reader := newSectionReader([]byte{})
-	unsafeReadUint32(reader)
+func TestReadUint32Truncated(t *testing.T) {
+	// Two bytes of padding are read first so the error has a non-zero offset.
+	reader := newSectionReader([]byte{0x01, 0x02, 0x03})
+	if _, err := readUint16(reader, "padding"); err != nil {
+		t.Fatalf("readUint16 returned error: %v", err)
+	}
+
+	_, err := readUint32(reader, "value")
+	var truncated *ErrTruncated
+	if !errors.As(err, &truncated) {
+		t.Fatalf("readUint32() error = %v, want *ErrTruncated", err)
+	}
+	if truncated.Section != "value" || truncated.Offset != 2 {
+		t.Errorf("ErrTruncated = %+v, want section %q at offset 2", truncated, "value")
+	}
 }
 
-func TestUnsafeReadUint16(t *testing.T) {
+func TestReadUint16(t *testing.T) {
 	var buf bytes.Buffer
 	binary.Write(&buf, binary.LittleEndian, uint16(0xBEEF))
 
 	reader := newSectionReader(buf.Bytes())
-	got := unsafeReadUint16(reader)
+	got, err := readUint16(reader, "value")
+	if err != nil {
+		t.Fatalf("readUint16 returned error: %v", err)
+	}
 	if got != 0xBEEF {
-		t.Errorf("unsafeReadUint16() = %#x, want %#x", got, 0xBEEF)
+		t.Errorf("readUint16() = %#x, want %#x", got, 0xBEEF)
+	}
+}
+
+func TestReadByteArrayTruncated(t *testing.T) {
+	// A corrupt size larger than the file must not be allocated.
+	reader := newSectionReader([]byte{0x01, 0x02})
+	_, err := readByteArray(reader, 0xFFFFFFFF, "block")
+	var truncated *ErrTruncated
+	if !errors.As(err, &truncated) {
+		t.Fatalf("readByteArray() error = %v, want *ErrTruncated", err)
+	}
+	if truncated.Section != "block" || truncated.Offset != 0 {
+		t.Errorf("ErrTruncated = %+v, want section %q at offset 0", truncated, "block")
+	}
+}
+
+func TestReadCountImplausible(t *testing.T) {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint32(7))
+	binary.Write(&buf, binary.LittleEndian, uint32(MaxArrayLength+1))
+
+	reader := newSectionReader(buf.Bytes())
+	count, err := readCount(reader, "first")
+	if err != nil || count != 7 {
+		t.Fatalf("readCount() = %d, %v, want 7, nil", count, err)
+	}
+
+	_, err = readCount(reader, "second")
+	var implausible *ErrImplausibleLength
+	if !errors.As(err, &implausible) {
+		t.Fatalf("readCount() error = %v, want *ErrImplausibleLength", err)
+	}
+	if implausible.Section != "second" || implausible.Offset != 4 || implausible.Length != MaxArrayLength+1 {
+		t.Errorf("ErrImplausibleLength = %+v, want section %q at offset 4 with length %d", implausible, "second", MaxArrayLength+1)
+	}
+}
+
+func TestReadVarStringArray(t *testing.T) {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint32(2))
+	for _, value := range []string{"a", "bc"} {
+		binary.Write(&buf, binary.LittleEndian, uint32(len(value)))
+		buf.WriteString(value)
+	}
+
+	reader := newSectionReader(buf.Bytes())
+	got, err := readVarStringArray(reader, "names")
+	if err != nil {
+		t.Fatalf("readVarStringArray returned error: %v", err)
+	}
+	if len(got) != 2 || got[0] != "a" || got[1] != "bc" {
+		t.Errorf("readVarStringArray() = %q, want [a bc]", got)
 	}
 }
 
@@ -0,0 +1,96 @@
+package fileio
+
+import (
+	"fmt"
+	"math"
+)
+
+// DefaultStartYear is the year of the first turn of a game started in the ancient era
+const DefaultStartYear = -4000
+
+// standardCalendar is how many years each turn lasts at standard speed. A standard game goes
+// from 4000 BC to 2050 AD in 500 turns.
+var standardCalendar = []struct {
+	Turns        int
+	YearsPerTurn float64
+}{
+	{75, 40},
+	{60, 25},
+	{25, 20},
+	{50, 10},
+	{60, 5},
+	{50, 2},
+	{120, 1},
+	{60, 0.5},
+}
+
+// gameSpeedTurns is the number of turns of a full game at each game speed
+var gameSpeedTurns = map[string]int{
+	"GAMESPEED_QUICK":    330,
+	"GAMESPEED_STANDARD": 500,
+	"GAMESPEED_EPIC":     750,
+	"GAMESPEED_MARATHON": 1500,
+}
+
+// standardYearAt returns the year after standardTurns turns at standard speed, which may be a
+// fraction of a turn
+func standardYearAt(standardTurns float64) float64 {
+	year := float64(DefaultStartYear)
+	for _, period := range standardCalendar {
+		if standardTurns <= float64(period.Turns) {
+			return year + standardTurns*period.YearsPerTurn
+		}
+		year += float64(period.Turns) * period.YearsPerTurn
+		standardTurns -= float64(period.Turns)
+	}
+	lastPeriod := standardCalendar[len(standardCalendar)-1]
+	return year + standardTurns*lastPeriod.YearsPerTurn
+}
+
+// standardTurnsTo is the inverse of standardYearAt
+func standardTurnsTo(year float64) float64 {
+	periodStart := float64(DefaultStartYear)
+	turns := 0.0
+	for _, period := range standardCalendar {
+		periodEnd := periodStart + float64(period.Turns)*period.YearsPerTurn
+		if year <= periodEnd {
+			return turns + (year-periodStart)/period.YearsPerTurn
+		}
+		turns += float64(period.Turns)
+		periodStart = periodEnd
+	}
+	lastPeriod := standardCalendar[len(standardCalendar)-1]
+	return turns + (year-periodStart)/lastPeriod.YearsPerTurn
+}
+
+// GameYear returns the calendar year of turn in the game the metadata is from, negative for BC.
+// The standard speed calendar is followed from the start turn and year of the game. Other game
+// speeds stretch it over their number of turns, which is close to the game's own calendar but
+// may be off by a few years. Replays without a start year are taken to start in 4000 BC.
+func GameYear(metadata Civ5ReplayMetadata, turn int) int {
+	startYear := metadata.StartYear
+	if startYear == 0 && metadata.StartTurn == 0 {
+		startYear = DefaultStartYear
+	}
+	speedTurns, found := gameSpeedTurns[metadata.GameSpeed]
+	if !found {
+		speedTurns = gameSpeedTurns["GAMESPEED_STANDARD"]
+	}
+	scale := float64(gameSpeedTurns["GAMESPEED_STANDARD"]) / float64(speedTurns)
+
+	standardTurns := standardTurnsTo(float64(startYear)) + float64(turn-metadata.StartTurn)*scale
+	year := int(math.Floor(standardYearAt(standardTurns)))
+	// There is no year 0, 1 BC is followed by 1 AD
+	if year == 0 {
+		year = 1
+	}
+	return year
+}
+
+// FormatGameYear formats a year from GameYear as the game shows it, e.g. 4000 BC or 1850 AD
+func FormatGameYear(year int) string {
+	if year < 0 {
+		return fmt.Sprintf("%d BC", -year)
+	}
+	return fmt.Sprintf("%d AD", year)
+}
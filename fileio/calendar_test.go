@@ -0,0 +1,37 @@
+package fileio
+
+import "testing"
+
+func TestGameYear(t *testing.T) {
+	standard := Civ5ReplayMetadata{GameSpeed: "GAMESPEED_STANDARD", StartYear: -4000}
+	quick := Civ5ReplayMetadata{GameSpeed: "GAMESPEED_QUICK", StartYear: -4000}
+	tests := []struct {
+		name     string
+		metadata Civ5ReplayMetadata
+		turn     int
+		want     int
+	}{
+		{"first turn", standard, 0, -4000},
+		{"ancient era", standard, 10, -3600},
+		{"end of 40 year turns", standard, 75, -1000},
+		{"end of 25 year turns", standard, 135, 500},
+		{"last turn", standard, 500, 2050},
+		{"no start year", Civ5ReplayMetadata{}, 75, -1000},
+		{"unknown speed", Civ5ReplayMetadata{GameSpeed: "GAMESPEED_MOD", StartYear: -4000}, 75, -1000},
+		{"quick speed", quick, 330, 2050},
+		{"later start", Civ5ReplayMetadata{StartTurn: 75, StartYear: -1000}, 135, 500},
+	}
+	for _, test := range tests {
+		if got := GameYear(test.metadata, test.turn); got != test.want {
+			t.Errorf("%s: GameYear(turn %d) = %d, want %d", test.name, test.turn, got, test.want)
+		}
+	}
+}
+
+func TestFormatGameYear(t *testing.T) {
+	for year, want := range map[int]string{-4000: "4000 BC", -1: "1 BC", 1: "1 AD", 2050: "2050 AD"} {
+		if got := FormatGameYear(year); got != want {
+			t.Errorf("FormatGameYear(%d) = %q, want %q", year, got, want)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package fileio
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ChronicleGrouping is how the events of a chronicle are split into sections
+type ChronicleGrouping string
+
+const (
+	// ChronicleByEra starts a section each time the first civ enters a new era
+	ChronicleByEra ChronicleGrouping = "era"
+	// ChronicleByTurns starts a section every ChronicleOptions.TurnsPerSection turns
+	ChronicleByTurns ChronicleGrouping = "turns"
+)
+
+// DefaultChronicleTurnsPerSection is the section length used when grouping by turns
+const DefaultChronicleTurnsPerSection = 50
+
+// ChronicleOptions configures BuildChronicle
+type ChronicleOptions struct {
+	GroupBy         ChronicleGrouping
+	TurnsPerSection int
+}
+
+// ChronicleEntry is one event of the history
+type ChronicleEntry struct {
+	Turn int
+	Year string
+	Text string
+}
+
+// ChronicleSection is a period of the game, e.g. an era
+type ChronicleSection struct {
+	Title   string
+	Entries []ChronicleEntry
+}
+
+// ChronicleStanding is the final result of one civ
+type ChronicleStanding struct {
+	Rank           int
+	CivName        string
+	Score          int
+	EliminatedTurn int // -1 if the civ survived
+	EliminatedYear string
+}
+
+// Chronicle is a game history built from a replay, which can be written as Markdown or HTML
+type Chronicle struct {
+	Title     string
+	Summary   []string
+	Sections  []ChronicleSection
+	Standings []ChronicleStanding
+}
+
+// chronicleEventKinds are the events that make it into a chronicle. Claimed and transferred
+// tiles are left out since there are too many of them to read.
+var chronicleEventKinds = map[ReplayEventKind]bool{
+	ReplayEventKindCityFounded:     true,
+	ReplayEventKindCityCaptured:    true,
+	ReplayEventKindCityRazed:       true,
+	ReplayEventKindCivEliminated:   true,
+	ReplayEventKindWarDeclared:     true,
+	ReplayEventKindPeaceMade:       true,
+	ReplayEventKindWonderCompleted: true,
+	ReplayEventKindGreatPersonBorn: true,
+	ReplayEventKindPantheonFounded: true,
+	ReplayEventKindReligionFounded: true,
+	ReplayEventKindEraEntered:      true,
+}
+
+// displayName turns a game key like GAMESPEED_STANDARD into Standard
+func displayName(key string) string {
+	if _, suffix, found := strings.Cut(key, "_"); found {
+		key = suffix
+	}
+	words := strings.Fields(strings.ReplaceAll(strings.ToLower(key), "_", " "))
+	for i, word := range words {
+		words[i] = strings.ToUpper(word[:1]) + word[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// isChronicleEntry reports whether a classified event goes in the chronicle. Captured and
+// razed tiles only do if the text names the city.
+func isChronicleEntry(typedEvent Civ5TypedReplayEvent) bool {
+	switch typedEvent.Kind {
+	case ReplayEventKindCityTransferred, ReplayEventKindTilesRazed:
+		return typedEvent.CityName != ""
+	}
+	return chronicleEventKinds[typedEvent.Kind]
+}
+
+// scoreDatasetName returns the dataset used to rank civs, which is the score if the replay has
+// one and the first dataset otherwise
+func scoreDatasetName(replayData *Civ5ReplayData) string {
+	datasetNames := replayDatasetNames(replayData)
+	for _, name := range datasetNames {
+		if strings.Contains(strings.ToUpper(name), "SCORE") {
+			return name
+		}
+	}
+	if len(datasetNames) > 0 {
+		return datasetNames[0]
+	}
+	return ""
+}
+
+// BuildChronicle turns the events and datasets of replayData into a game history
+func BuildChronicle(replayData *Civ5ReplayData, options ChronicleOptions) *Chronicle {
+	metadata := replayData.Metadata
+	formatTurn := func(turn int) string {
+		return FormatGameYear(GameYear(metadata, turn))
+	}
+
+	chronicle := &Chronicle{Title: "Game Chronicle"}
+	if metadata.CivName != "" {
+		chronicle.Title = fmt.Sprintf("Chronicle of %s", metadata.CivName)
+	}
+	var settings []string
+	for _, setting := range []struct{ Name, Value string }{
+		{"Map", metadata.MapFilename},
+		{"World size", displayName(metadata.WorldSize)},
+		{"Game speed", displayName(metadata.GameSpeed)},
+		{"Difficulty", displayName(metadata.Difficulty)},
+	} {
+		if setting.Value != "" {
+			settings = append(settings, fmt.Sprintf("%s: %s", setting.Name, setting.Value))
+		}
+	}
+	if len(settings) > 0 {
+		chronicle.Summary = append(chronicle.Summary, strings.Join(settings, ", "))
+	}
+
+	var entries []ChronicleEntry
+	var eraStarts []ChronicleSection // Entries holds the first entry of each era section
+	eliminated := map[int]int{}
+	lastTurn := metadata.StartTurn
+	for _, typedEvent := range ClassifyReplayEvents(replayData) {
+		turn := typedEvent.Event.Turn
+		lastTurn = max(lastTurn, turn)
+		if !isChronicleEntry(typedEvent) {
+			continue
+		}
+		entry := ChronicleEntry{Turn: turn, Year: formatTurn(turn), Text: typedEvent.Description()}
+		entries = append(entries, entry)
+
+		switch typedEvent.Kind {
+		case ReplayEventKindCivEliminated:
+			if _, found := eliminated[typedEvent.CivId]; !found && typedEvent.CivId >= 0 {
+				eliminated[typedEvent.CivId] = turn
+			}
+		case ReplayEventKindEraEntered:
+			isNewEra := true
+			for _, section := range eraStarts {
+				isNewEra = isNewEra && section.Title != typedEvent.EraName
+			}
+			if isNewEra {
+				eraStarts = append(eraStarts, ChronicleSection{Title: typedEvent.EraName, Entries: []ChronicleEntry{entry}})
+			}
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Turn < entries[j].Turn })
+	chronicle.Summary = append(chronicle.Summary, fmt.Sprintf("Turns %d to %d (%s to %s)",
+		metadata.StartTurn, lastTurn, formatTurn(metadata.StartTurn), formatTurn(lastTurn)))
+
+	// A section holds the entries from its start turn to the start of the next section
+	type sectionStart struct {
+		Title string
+		Turn  int
+	}
+	var starts []sectionStart
+	if options.GroupBy == ChronicleByTurns || len(eraStarts) == 0 {
+		turnsPerSection := options.TurnsPerSection
+		if turnsPerSection <= 0 {
+			turnsPerSection = DefaultChronicleTurnsPerSection
+		}
+		for turn := metadata.StartTurn; turn <= lastTurn; turn += turnsPerSection {
+			starts = append(starts, sectionStart{Turn: turn})
+		}
+	} else {
+		firstEra := "Beginning"
+		if metadata.StartEra != "" {
+			firstEra = displayName(metadata.StartEra) + " Era"
+		}
+		starts = append(starts, sectionStart{Title: firstEra, Turn: metadata.StartTurn})
+		for _, era := range eraStarts {
+			starts = append(starts, sectionStart{Title: era.Title, Turn: era.Entries[0].Turn})
+		}
+	}
+
+	entryIndex := 0
+	for i, start := range starts {
+		endTurn := lastTurn
+		if i+1 < len(starts) {
+			endTurn = starts[i+1].Turn - 1
+		}
+		section := ChronicleSection{}
+		for entryIndex < len(entries) && (i+1 == len(starts) || entries[entryIndex].Turn < starts[i+1].Turn) {
+			section.Entries = append(section.Entries, entries[entryIndex])
+			entryIndex++
+		}
+		if len(section.Entries) == 0 {
+			continue
+		}
+		turnRange := fmt.Sprintf("turns %d to %d, %s to %s", start.Turn, max(start.Turn, endTurn),
+			formatTurn(start.Turn), formatTurn(max(start.Turn, endTurn)))
+		if start.Title != "" {
+			section.Title = fmt.Sprintf("%s (%s)", start.Title, turnRange)
+		} else {
+			section.Title = strings.ToUpper(turnRange[:1]) + turnRange[1:]
+		}
+		chronicle.Sections = append(chronicle.Sections, section)
+	}
+
+	chronicle.Standings = buildChronicleStandings(replayData, eliminated, formatTurn)
+	return chronicle
+}
+
+// buildChronicleStandings ranks the civs by their last score. Civs without a score are left out.
+func buildChronicleStandings(replayData *Civ5ReplayData, eliminated map[int]int, formatTurn func(int) string) []ChronicleStanding {
+	scoreDataset := scoreDatasetName(replayData)
+	var standings []ChronicleStanding
+	for _, civDataset := range replayData.DatasetValues {
+		scores := civDataset.DatasetValues[scoreDataset]
+		if len(scores) == 0 {
+			continue
+		}
+		lastScore := scores[0]
+		for _, entry := range scores {
+			if entry.Turn >= lastScore.Turn {
+				lastScore = entry
+			}
+		}
+
+		standing := ChronicleStanding{
+			CivName:        replayCivName(replayData, civDataset.CivIndex),
+			Score:          lastScore.Value,
+			EliminatedTurn: -1,
+		}
+		if turn, found := eliminated[civDataset.CivIndex]; found {
+			standing.EliminatedTurn = turn
+			standing.EliminatedYear = formatTurn(turn)
+		}
+		standings = append(standings, standing)
+	}
+
+	// Survivors rank above eliminated civs, then by score
+	sort.SliceStable(standings, func(i, j int) bool {
+		iSurvived, jSurvived := standings[i].EliminatedTurn == -1, standings[j].EliminatedTurn == -1
+		if iSurvived != jSurvived {
+			return iSurvived
+		}
+		return standings[i].Score > standings[j].Score
+	})
+	for i := range standings {
+		standings[i].Rank = i + 1
+	}
+	return standings
+}
+
+// escapeMarkdown escapes the characters that would change the meaning of text in a Markdown
+// list item or table cell
+func escapeMarkdown(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "|", `\|`, "[", `\[`, "]", `\]`, "<", "&lt;", "`", "\\`")
+	return replacer.Replace(text)
+}
+
+// WriteMarkdown writes the chronicle as a Markdown document
+func (chronicle *Chronicle) WriteMarkdown(writer io.Writer) error {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "# %s\n\n", escapeMarkdown(chronicle.Title))
+	for _, line := range chronicle.Summary {
+		fmt.Fprintf(&builder, "%s\n\n", escapeMarkdown(line))
+	}
+
+	for _, section := range chronicle.Sections {
+		fmt.Fprintf(&builder, "## %s\n\n", escapeMarkdown(section.Title))
+		for _, entry := range section.Entries {
+			fmt.Fprintf(&builder, "- **Turn %d** (%s): %s\n", entry.Turn, entry.Year, escapeMarkdown(entry.Text))
+		}
+		builder.WriteString("\n")
+	}
+
+	if len(chronicle.Standings) > 0 {
+		builder.WriteString("## Final Standings\n\n")
+		builder.WriteString("| Rank | Civ | Score | Eliminated |\n")
+		builder.WriteString("|---:|---|---:|---|\n")
+		for _, standing := range chronicle.Standings {
+			eliminated := ""
+			if standing.EliminatedTurn >= 0 {
+				eliminated = fmt.Sprintf("Turn %d (%s)", standing.EliminatedTurn, standing.EliminatedYear)
+			}
+			fmt.Fprintf(&builder, "| %d | %s | %d | %s |\n", standing.Rank, escapeMarkdown(standing.CivName), standing.Score, eliminated)
+		}
+	}
+
+	_, err := io.WriteString(writer, builder.String())
+	return err
+}
+
+// WriteHtml writes the chronicle as a standalone HTML page
+func (chronicle *Chronicle) WriteHtml(writer io.Writer) error {
+	var builder strings.Builder
+	title := html.EscapeString(chronicle.Title)
+	fmt.Fprintf(&builder, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n", title)
+	builder.WriteString("<style>body { font-family: sans-serif; max-width: 50em; margin: auto; } " +
+		"table { border-collapse: collapse; } th, td { border: 1px solid #999; padding: 0.2em 0.6em; }</style>\n")
+	fmt.Fprintf(&builder, "</head>\n<body>\n<h1>%s</h1>\n", title)
+	for _, line := range chronicle.Summary {
+		fmt.Fprintf(&builder, "<p>%s</p>\n", html.EscapeString(line))
+	}
+
+	for _, section := range chronicle.Sections {
+		fmt.Fprintf(&builder, "<h2>%s</h2>\n<ul>\n", html.EscapeString(section.Title))
+		for _, entry := range section.Entries {
+			fmt.Fprintf(&builder, "<li><b>Turn %d</b> (%s): %s</li>\n", entry.Turn, entry.Year, html.EscapeString(entry.Text))
+		}
+		builder.WriteString("</ul>\n")
+	}
+
+	if len(chronicle.Standings) > 0 {
+		builder.WriteString("<h2>Final Standings</h2>\n<table>\n")
+		builder.WriteString("<tr><th>Rank</th><th>Civ</th><th>Score</th><th>Eliminated</th></tr>\n")
+		for _, standing := range chronicle.Standings {
+			eliminated := ""
+			if standing.EliminatedTurn >= 0 {
+				eliminated = fmt.Sprintf("Turn %d (%s)", standing.EliminatedTurn, standing.EliminatedYear)
+			}
+			fmt.Fprintf(&builder, "<tr><td>%d</td><td>%s</td><td>%d</td><td>%s</td></tr>\n",
+				standing.Rank, html.EscapeString(standing.CivName), standing.Score, eliminated)
+		}
+		builder.WriteString("</table>\n")
+	}
+	builder.WriteString("</body>\n</html>\n")
+
+	_, err := io.WriteString(writer, builder.String())
+	return err
+}
+
+// ExportChronicle writes the chronicle of replayData to outputFilename, as HTML if the filename
+// ends in .html or .htm and as Markdown otherwise
+func ExportChronicle(replayData *Civ5ReplayData, outputFilename string, options ChronicleOptions) error {
+	chronicle := BuildChronicle(replayData, options)
+
+	outputFile, err := os.Create(outputFilename)
+	if err != nil {
+		return fmt.Errorf("failed to create %q: %w", outputFilename, err)
+	}
+	defer outputFile.Close()
+
+	switch strings.ToLower(filepath.Ext(outputFilename)) {
+	case ".html", ".htm":
+		err = chronicle.WriteHtml(outputFile)
+	default:
+		err = chronicle.WriteMarkdown(outputFile)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write chronicle to %q: %w", outputFilename, err)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package fileio
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newChronicleTestReplay() *Civ5ReplayData {
+	return &Civ5ReplayData{
+		Metadata: Civ5ReplayMetadata{
+			CivName:   "Rome",
+			StartEra:  "ERA_ANCIENT",
+			GameSpeed: "GAMESPEED_STANDARD",
+			StartYear: -4000,
+		},
+		AllCivs: eventTestCivs,
+		AllReplayEvents: []Civ5ReplayEvent{
+			{Turn: 0, TypeId: ReplayEventCityFounded, CivId: 0, Text: "Rome is founded."},
+			{Turn: 1, TypeId: ReplayEventTilesClaimed, CivId: 0},
+			{Turn: 2, TypeId: ReplayEventCityFounded, CivId: 1, Text: "Athens is founded."},
+			{Turn: 60, CivId: 0, Text: "Rome has entered the Classical Era!"},
+			{Turn: 70, CivId: -1, Text: "Rome has declared war on Greece!"},
+			{Turn: 80, CivId: 1, Text: "Greece has entered the Classical Era!"},
+			{Turn: 90, CivId: 0, Text: "Athens was captured by the Roman!!!"},
+			{Turn: 91, CivId: -1, Text: "Greece has been destroyed!"},
+			{Turn: 120, CivId: 0, Text: "Rome has entered the Medieval Era!"},
+		},
+		DatasetNames: []string{"REPLAYDATASET_SCORE", "REPLAYDATASET_CITIES"},
+		DatasetValues: []Civ5ReplayCivDataset{
+			{CivIndex: 0, DatasetValues: map[string][]Civ5ReplayDataEntry{
+				"REPLAYDATASET_SCORE":  {{Turn: 0, Value: 1}, {Turn: 120, Value: 300}},
+				"REPLAYDATASET_CITIES": {{Turn: 120, Value: 2}},
+			}},
+			{CivIndex: 1, DatasetValues: map[string][]Civ5ReplayDataEntry{
+				"REPLAYDATASET_SCORE": {{Turn: 0, Value: 1}, {Turn: 90, Value: 500}},
+			}},
+			{CivIndex: 2, DatasetValues: map[string][]Civ5ReplayDataEntry{}},
+		},
+	}
+}
+
+func TestBuildChronicleByEra(t *testing.T) {
+	chronicle := BuildChronicle(newChronicleTestReplay(), ChronicleOptions{GroupBy: ChronicleByEra})
+
+	if chronicle.Title != "Chronicle of Rome" {
+		t.Errorf("Title = %q", chronicle.Title)
+	}
+	wantSections := []struct {
+		title   string
+		entries int
+	}{
+		{"Ancient Era (turns 0 to 59, 4000 BC to 1640 BC)", 2},
+		{"Classical Era (turns 60 to 119, 1600 BC to 100 AD)", 5},
+		{"Medieval Era (turns 120 to 120, 125 AD to 125 AD)", 1},
+	}
+	if len(chronicle.Sections) != len(wantSections) {
+		t.Fatalf("got %d sections, want %d: %+v", len(chronicle.Sections), len(wantSections), chronicle.Sections)
+	}
+	for i, want := range wantSections {
+		section := chronicle.Sections[i]
+		if section.Title != want.title || len(section.Entries) != want.entries {
+			t.Errorf("section %d = %q with %d entries, want %q with %d", i, section.Title, len(section.Entries), want.title, want.entries)
+		}
+	}
+	if entry := chronicle.Sections[0].Entries[1]; entry.Turn != 2 || entry.Year != "3920 BC" || entry.Text != "Greece founded Athens" {
+		t.Errorf("entry = %+v", entry)
+	}
+
+	wantStandings := []ChronicleStanding{
+		{Rank: 1, CivName: "Rome", Score: 300, EliminatedTurn: -1},
+		{Rank: 2, CivName: "Greece", Score: 500, EliminatedTurn: 91, EliminatedYear: "600 BC"},
+	}
+	if len(chronicle.Standings) != len(wantStandings) {
+		t.Fatalf("Standings = %+v", chronicle.Standings)
+	}
+	for i, want := range wantStandings {
+		if chronicle.Standings[i] != want {
+			t.Errorf("Standings[%d] = %+v, want %+v", i, chronicle.Standings[i], want)
+		}
+	}
+}
+
+func TestBuildChronicleByTurns(t *testing.T) {
+	chronicle := BuildChronicle(newChronicleTestReplay(), ChronicleOptions{GroupBy: ChronicleByTurns, TurnsPerSection: 50})
+
+	// Turns 100 to 119 have no events, so that section is left out
+	wantTitles := []string{
+		"Turns 0 to 49, 4000 BC to 2040 BC",
+		"Turns 50 to 99, 2000 BC to 400 BC",
+		"Turns 100 to 120, 375 BC to 125 AD",
+	}
+	if len(chronicle.Sections) != len(wantTitles) {
+		t.Fatalf("got %d sections, want %d: %+v", len(chronicle.Sections), len(wantTitles), chronicle.Sections)
+	}
+	for i, want := range wantTitles {
+		if chronicle.Sections[i].Title != want {
+			t.Errorf("section %d = %q, want %q", i, chronicle.Sections[i].Title, want)
+		}
+	}
+}
+
+func TestWriteChronicle(t *testing.T) {
+	replayData := newChronicleTestReplay()
+	replayData.AllCivs = append([]Civ5ReplayCiv{}, replayData.AllCivs...)
+	// The civ is still found by its long name
+	replayData.AllCivs[1].Name = "<Greece>"
+	replayData.AllCivs[1].LongName = "Greece"
+	chronicle := BuildChronicle(replayData, ChronicleOptions{})
+
+	var markdown strings.Builder
+	if err := chronicle.WriteMarkdown(&markdown); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# Chronicle of Rome\n",
+		"## Ancient Era (turns 0 to 59, 4000 BC to 1640 BC)\n",
+		"- **Turn 0** (4000 BC): Rome founded Rome\n",
+		"| 2 | &lt;Greece> | 500 | Turn 91 (600 BC) |\n",
+	} {
+		if !strings.Contains(markdown.String(), want) {
+			t.Errorf("markdown is missing %q:\n%s", want, markdown.String())
+		}
+	}
+
+	var html strings.Builder
+	if err := chronicle.WriteHtml(&html); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<title>Chronicle of Rome</title>",
+		"<li><b>Turn 0</b> (4000 BC): Rome founded Rome</li>",
+		"<tr><td>2</td><td>&lt;Greece&gt;</td><td>500</td><td>Turn 91 (600 BC)</td></tr>",
+	} {
+		if !strings.Contains(html.String(), want) {
+			t.Errorf("html is missing %q:\n%s", want, html.String())
+		}
+	}
+}
+
+func TestExportChronicle(t *testing.T) {
+	dir := t.TempDir()
+	for filename, prefix := range map[string]string{"chronicle.md": "# ", "chronicle.html": "<!DOCTYPE html>"} {
+		outputFilename := filepath.Join(dir, filename)
+		if err := ExportChronicle(newChronicleTestReplay(), outputFilename, ChronicleOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		contents, err := os.ReadFile(outputFilename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(contents), prefix) {
+			t.Errorf("%s starts with %q, want %q", filename, string(contents)[:min(len(contents), 20)], prefix)
+		}
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
-	"os"
 	"sort"
 	"strings"
 
@@ -38,6 +37,7 @@ const (
 	CivNameSize      = 64
 	CivTypeSize      = 64
 	TeamColorSize    = 64
+	TeamNameSize     = 64
 	EraSize          = 64
 	HandicapSize     = 64
 	PromotionSizeV11 = 32
@@ -47,9 +47,21 @@ const (
 	IsPuppetStateFlag = 1
 	IsOccupiedFlag    = 2
 
+	// Unit status bit positions
+	UnitFortifiedFlag  = 0
+	UnitEmbarkedFlag   = 1
+	UnitGarrisonedFlag = 2
+
+	// Tile elevation values
+	ElevationFlat     = 0
+	ElevationHills    = 1
+	ElevationMountain = 2
+
 	// Special values
 	InvalidCityId = -1    // Sentinel used in our parsed data model
 	RawNoCityId   = 65535 // Sentinel used in the raw file format (uint16 max)
+	InvalidUnitId = -1    // Sentinel used in our parsed data model
+	RawNoUnitId   = 65535 // Sentinel used in the raw file format (uint16 max)
 
 	// Data structure sizes
 	CivDataSize = 436
@@ -140,13 +152,21 @@ type Civ5UnitHeaderV12 struct {
 }
 
 type Civ5UnitData struct {
-	Name            string
+	Id              int // Index referenced by the UnitId of the tile the unit stands on
+	X               int // -1 if no tile references the unit
+	Y               int
+	Name            string // Custom unit name, empty if the unit has none
+	NameIndex       int
 	Experience      int
 	Health          int
 	UnitType        int
+	UnitTypeName    string
 	Owner           int
 	FacingDirection int
 	Status          int
+	IsFortified     bool
+	IsEmbarked      bool
+	IsGarrisoned    bool
 	PromotionInfo   []byte
 }
 
@@ -172,7 +192,7 @@ type Civ5CityData struct {
 
 type Civ5MapTileHeader struct {
 	CityId      uint16
-	Unknown     [2]byte // seems to be unused
+	UnitId      uint16
 	Owner       uint8
 	Improvement uint8
 	RouteType   uint8
@@ -197,9 +217,26 @@ type Civ5PlayerHeader struct {
 }
 
 type Civ5PlayerData struct {
-	Index     int
-	CivType   string
-	TeamColor string
+	Index          int
+	LeaderName     string
+	CivName        string
+	CivType        string
+	TeamColor      string
+	Era            string
+	Handicap       string
+	Culture        int
+	Gold           int
+	StartPositionX int
+	StartPositionY int
+	Team           int
+	Playable       bool
+	Policies       []string
+}
+
+type Civ5TeamData struct {
+	Index         int
+	Name          string
+	PlayerIndices []int // Indices into Civ5PlayerData of the players on this team
 }
 
 type Civ5MapTileImprovement struct {
@@ -207,6 +244,7 @@ type Civ5MapTileImprovement struct {
 	Y           int
 	CityId      int
 	CityName    string
+	UnitId      int
 	Owner       int
 	Improvement int
 	RouteType   int
@@ -227,18 +265,70 @@ type CivColorInfo struct {
 	Blue          float64
 }
 
+// DefaultFeatureWonderList is the natural wonder list of maps made for the base game and its
+// expansions. Json maps exported before the list was kept in Civ5MapData index into it.
+var DefaultFeatureWonderList = []string{
+	"FEATURE_CRATER", "FEATURE_FUJI", "FEATURE_MESA", "FEATURE_REEF", "FEATURE_VOLCANO",
+	"FEATURE_GIBRALTAR", "FEATURE_GEYSER", "FEATURE_FOUNTAIN_YOUTH", "FEATURE_POTOSI",
+	"FEATURE_EL_DORADO", "FEATURE_SRI_PADA", "FEATURE_MT_SINAI", "FEATURE_MT_KAILASH",
+	"FEATURE_ULURU", "FEATURE_LAKE_VICTORIA", "FEATURE_KILIMANJARO", "FEATURE_SOLOMONS_MINES",
+}
+
 type Civ5MapData struct {
-	MapHeader           Civ5MapHeader
-	TerrainList         []string
-	FeatureTerrainList  []string
-	ResourceList        []string
-	TileImprovementList []string
-	MapTiles            [][]*Civ5MapTilePhysical
-	MapTileImprovements [][]*Civ5MapTileImprovement
-	CityData            []*Civ5CityData
-	Civ5PlayerData      []*Civ5PlayerData
-	CityOwnerIndexMap   map[int]int
-	CivColorOverrides   []CivColorOverride
+	MapHeader             Civ5MapHeader
+	GameDescriptionHeader Civ5GameDescriptionHeader
+	MapName               string
+	MapDescription        string
+	WorldSize             string
+	ModData               string
+	TerrainList           []string
+	FeatureTerrainList    []string
+	FeatureWonderList     []string
+	ResourceList          []string
+	TileImprovementList   []string // Improvement type list from the game description
+	UnitTypeList          []string
+	TechList              []string
+	PolicyList            []string
+	BuildingList          []string
+	PromotionList         []string
+	VictoryList           []string
+	GameOptionList        []string
+	MapTiles              [][]*Civ5MapTilePhysical
+	MapTileImprovements   [][]*Civ5MapTileImprovement
+	CityData              []*Civ5CityData
+	Units                 []*Civ5UnitData
+	UnknownBlock          []byte // Raw bytes between the game description and the team data
+	Teams                 []*Civ5TeamData
+	Civ5PlayerData        []*Civ5PlayerData
+	CityOwnerIndexMap     map[int]int
+	CivColorOverrides     []CivColorOverride
+}
+
+// civ5MapMetadata holds the mod data, map name, map description, and world size fields that
+// follow the geography string lists
+type civ5MapMetadata struct {
+	ModData        string
+	MapName        string
+	MapDescription string
+	WorldSize      string
+}
+
+// civ5GameDescription holds the game description header together with the type lists and the raw
+// unit, unit name, and city data that follow it
+type civ5GameDescription struct {
+	Header          Civ5GameDescriptionHeader
+	ImprovementList []string
+	UnitTypeList    []string
+	TechList        []string
+	PolicyList      []string
+	BuildingList    []string
+	PromotionList   []string
+	VictoryList     []string
+	GameOptionList  []string
+	UnitData        []byte
+	UnitNameData    []byte
+	CityData        []byte
+	CityDataOffset  int64 // Offset of CityData in the file
 }
 
 // byteArrayToStringArray splits a null-separated byte buffer into a list of strings
@@ -269,9 +359,12 @@ func ParseUnitData(unitData []byte, version int) ([]*Civ5UnitData, error) {
 	if len(unitData) == 0 {
 		return nil, nil
 	}
+	if _, err := mapLayoutForVersion(version); err != nil {
+		return nil, err
+	}
 	streamReader := io.NewSectionReader(bytes.NewReader(unitData), int64(0), int64(len(unitData)))
 
-	numberUnits, err := readUint32(streamReader)
+	numberUnits, err := readUint32(streamReader, "unit count")
 	if err != nil {
 		return nil, err
 	}
@@ -299,56 +392,119 @@ func ParseUnitData(unitData []byte, version int) ([]*Civ5UnitData, error) {
 
 // maxUnitCountForVersion returns how many units could possibly fit in a buffer of the given size
 func maxUnitCountForVersion(dataLen, version int) int {
-	if version == MapVersion12 {
-		return dataLen / UnitDataSizeV12
+	if layout, ok := civ5MapLayouts[version]; ok {
+		return dataLen / layout.UnitDataSize
 	}
 	return dataLen / UnitDataSizeV11
 }
 
-// readUnit reads a single unit record using the binary layout for the given version.
-// Unrecognized versions yield a nil entry, matching the file's historical behavior.
+// readUnit reads a single unit record using the binary layout for the given version
 func readUnit(reader *io.SectionReader, version int) (*Civ5UnitData, error) {
-	switch version {
-	case MapVersion12:
+	layout, err := mapLayoutForVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	switch layout.UnitDataSize {
+	case UnitDataSizeV12:
 		header := Civ5UnitHeaderV12{}
-		if err := readStruct(reader, &header); err != nil {
+		if err := readStruct(reader, &header, "unit"); err != nil {
 			return nil, err
 		}
-		return &Civ5UnitData{
-			Experience:      int(header.Experience),
-			Health:          int(header.Health),
-			UnitType:        int(header.UnitType),
-			Owner:           int(header.Owner),
-			FacingDirection: int(header.FacingDirection),
-			Status:          int(header.Status),
-		}, nil
-	case MapVersion11:
+		return newUnitData(header.NameIndex, header.Experience, header.Health, header.UnitType,
+			header.Owner, header.FacingDirection, header.Status, header.Promotion[:]), nil
+	default:
 		header := Civ5UnitHeaderV11{}
-		if err := readStruct(reader, &header); err != nil {
+		if err := readStruct(reader, &header, "unit"); err != nil {
 			return nil, err
 		}
-		return &Civ5UnitData{
-			Experience:      int(header.Experience),
-			Health:          int(header.Health),
-			UnitType:        int(header.UnitType),
-			Owner:           int(header.Owner),
-			FacingDirection: int(header.FacingDirection),
-			Status:          int(header.Status),
-		}, nil
-	default:
-		return nil, nil
+		return newUnitData(header.NameIndex, header.Experience, header.Health, uint32(header.UnitType),
+			header.Owner, header.FacingDirection, header.Status, header.Promotion[:]), nil
 	}
 }
 
-// ParseCityData parses the raw city section of a map file into city data
+// newUnitData builds a unit from the header fields shared by every unit record version,
+// decoding the status bits. The unit isn't placed on a tile until resolveUnits is called.
+func newUnitData(nameIndex uint16, experience, health, unitType uint32, owner, facingDirection, status uint8, promotion []byte) *Civ5UnitData {
+	return &Civ5UnitData{
+		X:               -1,
+		Y:               -1,
+		NameIndex:       int(nameIndex),
+		Experience:      int(experience),
+		Health:          int(health),
+		UnitType:        int(unitType),
+		Owner:           int(owner),
+		FacingDirection: int(facingDirection),
+		Status:          int(status),
+		IsFortified:     (status>>UnitFortifiedFlag)&1 != 0,
+		IsEmbarked:      (status>>UnitEmbarkedFlag)&1 != 0,
+		IsGarrisoned:    (status>>UnitGarrisonedFlag)&1 != 0,
+		PromotionInfo:   append([]byte{}, promotion...),
+	}
+}
+
+// resolveUnits fills in each unit's id, tile position, unit type name, and custom name.
+// A tile references at most one unit through its UnitId.
+func resolveUnits(units []*Civ5UnitData, unitNames, unitTypeList []string, mapTileImprovements [][]*Civ5MapTileImprovement) {
+	for i, unit := range units {
+		if unit == nil {
+			continue
+		}
+		unit.Id = i
+		if unit.UnitType >= 0 && unit.UnitType < len(unitTypeList) {
+			unit.UnitTypeName = unitTypeList[unit.UnitType]
+		}
+		if unit.NameIndex < len(unitNames) {
+			unit.Name = unitNames[unit.NameIndex]
+		}
+	}
+
+	for i, row := range mapTileImprovements {
+		for j, tile := range row {
+			if tile.UnitId == InvalidUnitId || tile.UnitId >= len(units) || units[tile.UnitId] == nil {
+				continue
+			}
+			units[tile.UnitId].X = j
+			units[tile.UnitId].Y = i
+		}
+	}
+}
+
+// reportUnits prints a human-readable summary of the resolved units
+func reportUnits(units []*Civ5UnitData) {
+	fmt.Printf("\n=== Units (%d units) ===\n", len(units))
+	for i, unit := range units {
+		if unit == nil {
+			continue
+		}
+		fmt.Printf("  %d. %s owned by %d at (%d, %d)", i+1, unit.UnitTypeName, unit.Owner, unit.X, unit.Y)
+		if unit.Name != "" {
+			fmt.Printf(" named %s", unit.Name)
+		}
+		fmt.Println()
+	}
+}
+
+// ParseCityData parses the raw city section of a map file into city data. Offsets in its errors
+// are from the start of cityData.
 func ParseCityData(cityData []byte, version int, maxCityId int) ([]*Civ5CityData, error) {
 	if len(cityData) == 0 {
 		return nil, nil
 	}
 	streamReader := io.NewSectionReader(bytes.NewReader(cityData), int64(0), int64(len(cityData)))
+	return readCities(streamReader, version, maxCityId)
+}
+
+// readCities reads the city section that starts at the position of streamReader and runs to the
+// end of it
+func readCities(streamReader *io.SectionReader, version int, maxCityId int) ([]*Civ5CityData, error) {
+	layout, err := mapLayoutForVersion(version)
+	if err != nil {
+		return nil, err
+	}
 
 	// This number is not always accurate because it sometimes underestimates the number of cities
-	numberCities, err := readUint32(streamReader)
+	countOffset := currentOffset(streamReader)
+	numberCities, err := readUint32(streamReader, "city count")
 	if err != nil {
 		return nil, err
 	}
@@ -359,7 +515,11 @@ func ParseCityData(cityData []byte, version int, maxCityId int) ([]*Civ5CityData
 		fmt.Println("Number of cities should be", maxCityId+1)
 	}
 
-	buildingDataSize := buildingDataSizeForVersion(version)
+	buildingDataSize := layout.BuildingDataSize
+	cityRecordSize := int64(binary.Size(Civ5CityHeader{}) + buildingDataSize)
+	if int64(numberCities) > remainingBytes(streamReader)/cityRecordSize {
+		return nil, &ErrImplausibleLength{Section: "city count", Offset: countOffset, Length: int64(numberCities)}
+	}
 	allCities := make([]*Civ5CityData, int(numberCities))
 	for i := 0; i < int(numberCities); i++ {
 		city, err := readCity(streamReader, buildingDataSize)
@@ -373,8 +533,8 @@ func ParseCityData(cityData []byte, version int, maxCityId int) ([]*Civ5CityData
 
 // buildingDataSizeForVersion returns the per-city building data size for the given format version
 func buildingDataSizeForVersion(version int) int {
-	if version == MapVersion12 {
-		return BuildingDataSizeV12
+	if layout, ok := civ5MapLayouts[version]; ok {
+		return layout.BuildingDataSize
 	}
 	return BuildingDataSizeV11
 }
@@ -390,11 +550,11 @@ func adjustedCityOwner(owner uint8) uint8 {
 // readCity reads a single city record and its trailing building data
 func readCity(reader *io.SectionReader, buildingDataSize int) (*Civ5CityData, error) {
 	header := Civ5CityHeader{}
-	if err := readStruct(reader, &header); err != nil {
+	if err := readStruct(reader, &header, "city"); err != nil {
 		return nil, err
 	}
 
-	buildingInfo, err := readByteArray(reader, uint32(buildingDataSize))
+	buildingInfo, err := readByteArray(reader, uint32(buildingDataSize), "city buildings")
 	if err != nil {
 		return nil, err
 	}
@@ -412,34 +572,62 @@ func readCity(reader *io.SectionReader, buildingDataSize int) (*Civ5CityData, er
 	}, nil
 }
 
-// ParseCivData parses the raw civilization section of a map file into player data
-func ParseCivData(inputData []byte) ([]*Civ5PlayerData, error) {
+// ParseCivData parses the raw civilization section of a map file into player data,
+// decoding each player's policies against the policy list
+func ParseCivData(inputData []byte, policyList []string) ([]*Civ5PlayerData, error) {
 	allCivs, err := parseCivHeaders(inputData)
 	if err != nil {
 		return nil, err
 	}
 	reportCivData(allCivs)
-	return civHeadersToPlayerData(allCivs), nil
+	return civHeadersToPlayerData(allCivs, policyList), nil
 }
 
 // parseCivHeaders reads the fixed-size civilization headers from the raw byte buffer
 func parseCivHeaders(inputData []byte) ([]Civ5PlayerHeader, error) {
 	streamReader := io.NewSectionReader(bytes.NewReader(inputData), int64(0), int64(len(inputData)))
 	allCivs := make([]Civ5PlayerHeader, len(inputData)/CivDataSize)
-	if err := readStruct(streamReader, &allCivs); err != nil {
+	if err := readStruct(streamReader, &allCivs, "player data"); err != nil {
 		return nil, err
 	}
 	return allCivs, nil
 }
 
+// decodePolicies returns the names of the policies whose bits are set. Bit i of the bitfield,
+// counting from the lowest bit of the first byte, refers to the i-th entry of the policy list.
+// Bits beyond the end of the policy list are ignored.
+func decodePolicies(policies [32]byte, policyList []string) []string {
+	names := make([]string, 0)
+	for i, name := range policyList {
+		if i >= len(policies)*8 {
+			break
+		}
+		if (policies[i/8]>>(i%8))&1 != 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
 // civHeadersToPlayerData maps raw civilization headers to the public player data model
-func civHeadersToPlayerData(allCivs []Civ5PlayerHeader) []*Civ5PlayerData {
+func civHeadersToPlayerData(allCivs []Civ5PlayerHeader, policyList []string) []*Civ5PlayerData {
 	allPlayerData := make([]*Civ5PlayerData, len(allCivs))
 	for i, civ := range allCivs {
 		allPlayerData[i] = &Civ5PlayerData{
-			Index:     i,
-			CivType:   nullTerminatedString(civ.CivType[:]),
-			TeamColor: nullTerminatedString(civ.TeamColor[:]),
+			Index:          i,
+			LeaderName:     nullTerminatedString(civ.LeaderName[:]),
+			CivName:        nullTerminatedString(civ.CivName[:]),
+			CivType:        nullTerminatedString(civ.CivType[:]),
+			TeamColor:      nullTerminatedString(civ.TeamColor[:]),
+			Era:            nullTerminatedString(civ.Era[:]),
+			Handicap:       nullTerminatedString(civ.Handicap[:]),
+			Culture:        int(civ.Culture),
+			Gold:           int(civ.Gold),
+			StartPositionX: int(int32(civ.StartPositionX)),
+			StartPositionY: int(int32(civ.StartPositionY)),
+			Team:           int(civ.Team),
+			Playable:       civ.Playable != 0,
+			Policies:       decodePolicies(civ.Policies, policyList),
 		}
 	}
 	return allPlayerData
@@ -450,7 +638,11 @@ func reportCivData(allCivs []Civ5PlayerHeader) {
 	fmt.Printf("\n=== Civilizations (%d civs) ===\n", len(allCivs))
 	for i, civ := range allCivs {
 		fmt.Printf("  %d. %s\n", i+1, nullTerminatedString(civ.CivType[:]))
+		fmt.Printf("      Leader: %s\n", nullTerminatedString(civ.LeaderName[:]))
 		fmt.Printf("      Team Color: %s\n", nullTerminatedString(civ.TeamColor[:]))
+		fmt.Printf("      Era: %s\n", nullTerminatedString(civ.Era[:]))
+		fmt.Printf("      Handicap: %s\n", nullTerminatedString(civ.Handicap[:]))
+		fmt.Printf("      Start Position: (%d, %d)\n", civ.StartPositionX, civ.StartPositionY)
 		fmt.Printf("      Team: %d\n", civ.Team)
 		fmt.Printf("      Playable: %t\n", civ.Playable != 0)
 		if i < len(allCivs)-1 {
@@ -473,7 +665,7 @@ func ParseMapTileProperties(inputData []byte, height int, width int) ([][]*Civ5M
 		mapTiles[i] = make([]*Civ5MapTileImprovement, width)
 		for j := 0; j < width; j++ {
 			tileInfo := Civ5MapTileHeader{}
-			if err := readStruct(streamReader, &tileInfo); err != nil {
+			if err := readStruct(streamReader, &tileInfo, "tile properties"); err != nil {
 				return nil, err
 			}
 
@@ -481,11 +673,16 @@ func ParseMapTileProperties(inputData []byte, height int, width int) ([][]*Civ5M
 			if tileInfo.CityId == RawNoCityId {
 				newCityId = InvalidCityId
 			}
+			newUnitId := int(tileInfo.UnitId)
+			if tileInfo.UnitId == RawNoUnitId {
+				newUnitId = InvalidUnitId
+			}
 
 			mapTiles[i][j] = &Civ5MapTileImprovement{
 				X:           j,
 				Y:           i,
 				CityId:      newCityId,
+				UnitId:      newUnitId,
 				Owner:       int(tileInfo.Owner),
 				Improvement: int(tileInfo.Improvement),
 				RouteType:   int(tileInfo.RouteType),
@@ -497,32 +694,9 @@ func ParseMapTileProperties(inputData []byte, height int, width int) ([][]*Civ5M
 	return mapTiles, nil
 }
 
-// readUint32 reads a uint32 from the binary stream
-func readUint32(reader *io.SectionReader) (uint32, error) {
-	var value uint32
-	if err := binary.Read(reader, binary.LittleEndian, &value); err != nil {
-		return 0, err
-	}
-	return value, nil
-}
-
-// readByteArray reads a byte array of specified size from the binary stream
-func readByteArray(reader *io.SectionReader, size uint32) ([]byte, error) {
-	dataBytes := make([]byte, size)
-	if err := binary.Read(reader, binary.LittleEndian, &dataBytes); err != nil {
-		return nil, err
-	}
-	return dataBytes, nil
-}
-
-// readStruct reads a struct from the binary stream
-func readStruct(reader *io.SectionReader, data interface{}) error {
-	return binary.Read(reader, binary.LittleEndian, data)
-}
-
 // readStringList reads a null-separated string list of the given byte size from the binary stream
-func readStringList(reader *io.SectionReader, size uint32) ([]string, error) {
-	dataBytes, err := readByteArray(reader, size)
+func readStringList(reader *io.SectionReader, size uint32, name string) ([]string, error) {
+	dataBytes, err := readByteArray(reader, size, name)
 	if err != nil {
 		return nil, err
 	}
@@ -547,7 +721,7 @@ func reportStringList(name string, list []string) {
 
 // readReportedStringList reads a named string list section and logs a summary of its contents
 func readReportedStringList(reader *io.SectionReader, size uint32, name string) ([]string, error) {
-	list, err := readStringList(reader, size)
+	list, err := readStringList(reader, size, name)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read %s data: %w", name, err)
 	}
@@ -557,12 +731,18 @@ func readReportedStringList(reader *io.SectionReader, size uint32, name string)
 
 // parsePhysicalMapTiles reads and parses the physical terrain data from map tiles
 func parsePhysicalMapTiles(reader *io.SectionReader, header *Civ5MapHeader) ([][]*Civ5MapTilePhysical, error) {
+	// Check the dimensions against the rest of the file before allocating the grid
+	maxTileCount := remainingBytes(reader) / int64(binary.Size(Civ5MapTile{}))
+	rowWidth := max(int64(header.Width), 1)
+	if int64(header.Height) > maxTileCount/rowWidth {
+		return nil, &ErrTruncated{Section: "map tiles", Offset: currentOffset(reader)}
+	}
 	mapTiles := make([][]*Civ5MapTilePhysical, header.Height)
 	for i := 0; i < int(header.Height); i++ {
 		mapTiles[i] = make([]*Civ5MapTilePhysical, header.Width)
 		for j := 0; j < int(header.Width); j++ {
 			tile := Civ5MapTile{}
-			if err := readStruct(reader, &tile); err != nil {
+			if err := readStruct(reader, &tile, "map tile"); err != nil {
 				return nil, fmt.Errorf("failed to read map tile at position (%d, %d): %w", i, j, err)
 			}
 			mapTiles[i][j] = &Civ5MapTilePhysical{
@@ -609,7 +789,7 @@ func isEndOfFile(reader *io.SectionReader) (bool, error) {
 // used when the file ends before any game/city data is present
 func createPhysicalMapData(header *Civ5MapHeader, terrainList, featureTerrainList, resourceList []string, mapTiles [][]*Civ5MapTilePhysical) *Civ5MapData {
 	fmt.Println("Reached end of file. Skip reading game description header.")
-	return buildMapData(header, terrainList, featureTerrainList, resourceList, mapTiles,
+	return buildMapData(header, &Civ5GameDescriptionHeader{}, terrainList, featureTerrainList, resourceList, mapTiles,
 		[][]*Civ5MapTileImprovement{}, []*Civ5CityData{}, []*Civ5PlayerData{}, map[int]int{})
 }
 
@@ -734,26 +914,59 @@ func GetSortedKeys[K comparable, V any](m map[K]V) []K {
 }
 
 // buildMapData assembles the final map data structure from its parsed components
-func buildMapData(header *Civ5MapHeader, terrainList, featureTerrainList, resourceList []string,
+func buildMapData(header *Civ5MapHeader, gameDescriptionHeader *Civ5GameDescriptionHeader, terrainList, featureTerrainList, resourceList []string,
 	mapTiles [][]*Civ5MapTilePhysical, improvements [][]*Civ5MapTileImprovement,
 	cityData []*Civ5CityData, playerData []*Civ5PlayerData,
 	cityOwnerIndexMap map[int]int) *Civ5MapData {
 
 	return &Civ5MapData{
-		MapHeader:           *header,
-		TerrainList:         terrainList,
-		FeatureTerrainList:  featureTerrainList,
-		ResourceList:        resourceList,
-		TileImprovementList: []string{}, // This could be populated if needed
-		MapTiles:            mapTiles,
-		MapTileImprovements: improvements,
-		CityData:            cityData,
-		Civ5PlayerData:      playerData,
-		CityOwnerIndexMap:   cityOwnerIndexMap,
-		CivColorOverrides:   []CivColorOverride{}, // No overrides by default
+		MapHeader:             *header,
+		GameDescriptionHeader: *gameDescriptionHeader,
+		TerrainList:           terrainList,
+		FeatureTerrainList:    featureTerrainList,
+		FeatureWonderList:     []string{},
+		ResourceList:          resourceList,
+		TileImprovementList:   []string{},
+		UnitTypeList:          []string{},
+		TechList:              []string{},
+		PolicyList:            []string{},
+		BuildingList:          []string{},
+		PromotionList:         []string{},
+		VictoryList:           []string{},
+		GameOptionList:        []string{},
+		MapTiles:              mapTiles,
+		MapTileImprovements:   improvements,
+		CityData:              cityData,
+		Units:                 []*Civ5UnitData{},
+		UnknownBlock:          []byte{},
+		Teams:                 []*Civ5TeamData{},
+		Civ5PlayerData:        playerData,
+		CityOwnerIndexMap:     cityOwnerIndexMap,
+		CivColorOverrides:     []CivColorOverride{}, // No overrides by default
 	}
 }
 
+// setMapMetadata copies the map metadata and the feature wonder list into the map data
+func setMapMetadata(mapData *Civ5MapData, metadata civ5MapMetadata, featureWonderList []string) {
+	mapData.ModData = metadata.ModData
+	mapData.MapName = metadata.MapName
+	mapData.MapDescription = metadata.MapDescription
+	mapData.WorldSize = metadata.WorldSize
+	mapData.FeatureWonderList = featureWonderList
+}
+
+// setGameDescriptionLists copies the type lists from the game description into the map data
+func setGameDescriptionLists(mapData *Civ5MapData, section *civ5GameDescription) {
+	mapData.TileImprovementList = section.ImprovementList
+	mapData.UnitTypeList = section.UnitTypeList
+	mapData.TechList = section.TechList
+	mapData.PolicyList = section.PolicyList
+	mapData.BuildingList = section.BuildingList
+	mapData.PromotionList = section.PromotionList
+	mapData.VictoryList = section.VictoryList
+	mapData.GameOptionList = section.GameOptionList
+}
+
 // mapVersion extracts the binary format version from the scenario version byte
 func mapVersion(scenarioVersion uint8) int {
 	return int(scenarioVersion & VersionMask)
@@ -764,6 +977,45 @@ func mapScenario(scenarioVersion uint8) int {
 	return int(scenarioVersion >> ScenarioBits)
 }
 
+// FormatVersion returns the binary format version stored in the low bits of ScenarioVersion
+func (header *Civ5MapHeader) FormatVersion() int {
+	return mapVersion(header.ScenarioVersion)
+}
+
+// ErrUnsupportedMapVersion is returned for a map whose format version has no known layout
+type ErrUnsupportedMapVersion struct {
+	Version int
+}
+
+func (e *ErrUnsupportedMapVersion) Error() string {
+	return fmt.Sprintf("unsupported map version %d", e.Version)
+}
+
+// civ5MapLayout describes the parts of the binary layout that differ between map versions
+type civ5MapLayout struct {
+	HasWorldSize         bool // World size string after the map description
+	HasVictoryAndOptions bool // Victory and game option lists in the game description
+	UnitDataSize         int
+	BuildingDataSize     int
+}
+
+// civ5MapLayouts holds the layout of every supported map version. Maps made before Gods & Kings
+// have an earlier version without the world size or the victory and game option lists, but they
+// are rejected until a layout for them has been checked against a real map.
+var civ5MapLayouts = map[int]civ5MapLayout{
+	MapVersion11: {HasWorldSize: true, HasVictoryAndOptions: true, UnitDataSize: UnitDataSizeV11, BuildingDataSize: BuildingDataSizeV11},
+	MapVersion12: {HasWorldSize: true, HasVictoryAndOptions: true, UnitDataSize: UnitDataSizeV12, BuildingDataSize: BuildingDataSizeV12},
+}
+
+// mapLayoutForVersion returns the layout of a map version, or an error if it isn't supported
+func mapLayoutForVersion(version int) (civ5MapLayout, error) {
+	layout, ok := civ5MapLayouts[version]
+	if !ok {
+		return civ5MapLayout{}, &ErrUnsupportedMapVersion{Version: version}
+	}
+	return layout, nil
+}
+
 // reportMapHeaderInfo prints a human-readable summary of the map header
 func reportMapHeaderInfo(header *Civ5MapHeader, version, scenario int) {
 	fmt.Println("Scenario: ", scenario)
@@ -773,80 +1025,71 @@ func reportMapHeaderInfo(header *Civ5MapHeader, version, scenario int) {
 	fmt.Println("Has random goodies: ", header.Settings[0]>>2&1 != 0)
 }
 
-// openMapFileReader opens a map file and returns a section reader spanning its entire contents
-func openMapFileReader(filename string) (*os.File, int64, *io.SectionReader, error) {
-	inputFile, err := os.Open(filename)
-	if err != nil {
-		return nil, 0, nil, fmt.Errorf("failed to load map: %w", err)
-	}
-	fi, err := inputFile.Stat()
-	if err != nil {
-		inputFile.Close()
-		return nil, 0, nil, fmt.Errorf("failed to get file info for %q: %w", filename, err)
-	}
-	fileLength := fi.Size()
-	return inputFile, fileLength, io.NewSectionReader(inputFile, int64(0), fileLength), nil
-}
-
 // readTerrainTypeLists reads the terrain, feature terrain, feature wonder, and resource type lists
-func readTerrainTypeLists(reader *io.SectionReader, header *Civ5MapHeader) (terrainList, featureTerrainList, resourceList []string, err error) {
+func readTerrainTypeLists(reader *io.SectionReader, header *Civ5MapHeader) (terrainList, featureTerrainList, featureWonderList, resourceList []string, err error) {
 	terrainList, err = readReportedStringList(reader, header.TerrainDataSize, "Terrain data")
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, nil, nil, nil, err
 	}
 
 	featureTerrainList, err = readReportedStringList(reader, header.FeatureTerrainDataSize, "Feature terrain data")
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, nil, nil, nil, err
 	}
 
-	if _, err = readReportedStringList(reader, header.FeatureWonderDataSize, "Feature wonder data"); err != nil {
-		return nil, nil, nil, err
+	featureWonderList, err = readReportedStringList(reader, header.FeatureWonderDataSize, "Feature wonder data")
+	if err != nil {
+		return nil, nil, nil, nil, err
 	}
 
 	resourceList, err = readReportedStringList(reader, header.ResourceDataSize, "Resource data")
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, nil, nil, nil, err
 	}
 
-	return terrainList, featureTerrainList, resourceList, nil
+	return terrainList, featureTerrainList, featureWonderList, resourceList, nil
 }
 
-// skipMapMetadata reads (and logs) the mod data, map name, map description, and world size fields.
-// None of this data is retained in the parsed map, matching the file's historical behavior.
-func skipMapMetadata(reader *io.SectionReader, header *Civ5MapHeader, version int) error {
-	modDataBytes, err := readByteArray(reader, header.ModDataSize)
+// readMapMetadata reads (and logs) the mod data, map name, map description, and world size fields.
+// The mod data is kept byte for byte, while the text fields are trimmed at their null terminator.
+func readMapMetadata(reader *io.SectionReader, header *Civ5MapHeader, layout civ5MapLayout) (civ5MapMetadata, error) {
+	metadata := civ5MapMetadata{}
+	modDataBytes, err := readByteArray(reader, header.ModDataSize, "mod data")
 	if err != nil {
-		return err
+		return metadata, fmt.Errorf("failed to read mod data: %w", err)
 	}
-	fmt.Println("Mod data:", string(modDataBytes))
+	metadata.ModData = string(modDataBytes)
+	fmt.Println("Mod data:", metadata.ModData)
 
-	mapNameBytes, err := readByteArray(reader, header.MapNameLength)
+	mapNameBytes, err := readByteArray(reader, header.MapNameLength, "map name")
 	if err != nil {
-		return err
+		return metadata, fmt.Errorf("failed to read map name: %w", err)
 	}
-	fmt.Println("Map name: ", string(mapNameBytes))
+	metadata.MapName = nullTerminatedString(mapNameBytes)
+	fmt.Println("Map name: ", metadata.MapName)
 
-	mapDescriptionBytes, err := readByteArray(reader, header.MapDescriptionLength)
+	mapDescriptionBytes, err := readByteArray(reader, header.MapDescriptionLength, "map description")
 	if err != nil {
-		return err
+		return metadata, fmt.Errorf("failed to read map description: %w", err)
 	}
-	fmt.Println("Map description: ", string(mapDescriptionBytes))
+	metadata.MapDescription = nullTerminatedString(mapDescriptionBytes)
+	fmt.Println("Map description: ", metadata.MapDescription)
 
 	// Earlier versions don't have this field
-	if version >= MapVersion11 {
-		worldSizeStringLength, err := readUint32(reader)
+	if layout.HasWorldSize {
+		worldSizeStringLength, err := readUint32(reader, "world size length")
 		if err != nil {
-			return err
+			return metadata, fmt.Errorf("failed to read world size length: %w", err)
 		}
-		worldSize, err := readByteArray(reader, worldSizeStringLength)
+		worldSize, err := readByteArray(reader, worldSizeStringLength, "world size")
 		if err != nil {
-			return err
+			return metadata, fmt.Errorf("failed to read world size: %w", err)
 		}
-		fmt.Println("World size: ", string(worldSize))
+		metadata.WorldSize = nullTerminatedString(worldSize)
+		fmt.Println("World size: ", metadata.WorldSize)
 	}
 
-	return nil
+	return metadata, nil
 }
 
 // reportGameDescriptionHeader prints a human-readable summary of the game description header
@@ -862,92 +1105,101 @@ func reportGameDescriptionHeader(header *Civ5GameDescriptionHeader) {
 }
 
 // readGameDescriptionSection reads the game description header and the type/unit/city data
-// sections that follow it, returning the raw unit and city data for later parsing
-func readGameDescriptionSection(reader *io.SectionReader, version int) (Civ5GameDescriptionHeader, []byte, []byte, error) {
+// sections that follow it, keeping the type lists and the raw unit and city data for later parsing
+func readGameDescriptionSection(reader *io.SectionReader, layout civ5MapLayout) (*civ5GameDescription, error) {
 	fmt.Println("Reading game description header...")
-	gameDescriptionHeader := Civ5GameDescriptionHeader{}
-	if err := readStruct(reader, &gameDescriptionHeader); err != nil {
-		return gameDescriptionHeader, nil, nil, err
+	section := &civ5GameDescription{}
+	header := &section.Header
+	if err := readStruct(reader, header, "game description header"); err != nil {
+		return nil, err
 	}
-	reportGameDescriptionHeader(&gameDescriptionHeader)
+	reportGameDescriptionHeader(header)
 
 	victoryDataSize := uint32(0)
 	gameOptionDataSize := uint32(0)
-	if version >= MapVersion11 {
+	if layout.HasVictoryAndOptions {
 		var err error
-		victoryDataSize, err = readUint32(reader)
+		victoryDataSize, err = readUint32(reader, "victory data size")
 		if err != nil {
-			return gameDescriptionHeader, nil, nil, err
+			return nil, err
 		}
-		gameOptionDataSize, err = readUint32(reader)
+		gameOptionDataSize, err = readUint32(reader, "game option data size")
 		if err != nil {
-			return gameDescriptionHeader, nil, nil, err
+			return nil, err
 		}
 		fmt.Printf("Victory Data Size: %d bytes\n", victoryDataSize)
 		fmt.Printf("Game Option Data Size: %d bytes\n", gameOptionDataSize)
 	}
 
-	namedListSizes := []struct {
+	namedLists := []struct {
+		list *[]string
 		size uint32
 		name string
 	}{
-		{gameDescriptionHeader.ImprovementDataSize, "Improvement data"},
-		{gameDescriptionHeader.UnitTypeDataSize, "Unit type data"},
-		{gameDescriptionHeader.TechTypeDataSize, "Tech type data"},
-		{gameDescriptionHeader.PolicyTypeDataSize, "Policy type data"},
-		{gameDescriptionHeader.BuildingTypeDataSize, "Building type data"},
-		{gameDescriptionHeader.PromotionTypeDataSize, "Promotion type data"},
-	}
-	for _, list := range namedListSizes {
-		if _, err := readReportedStringList(reader, list.size, list.name); err != nil {
-			return gameDescriptionHeader, nil, nil, err
+		{&section.ImprovementList, header.ImprovementDataSize, "Improvement data"},
+		{&section.UnitTypeList, header.UnitTypeDataSize, "Unit type data"},
+		{&section.TechList, header.TechTypeDataSize, "Tech type data"},
+		{&section.PolicyList, header.PolicyTypeDataSize, "Policy type data"},
+		{&section.BuildingList, header.BuildingTypeDataSize, "Building type data"},
+		{&section.PromotionList, header.PromotionTypeDataSize, "Promotion type data"},
+	}
+	for _, named := range namedLists {
+		list, err := readReportedStringList(reader, named.size, named.name)
+		if err != nil {
+			return nil, err
 		}
+		*named.list = list
 	}
 
-	fmt.Println("Unit data size: ", gameDescriptionHeader.UnitDataSize)
-	unitDataBytes, err := readByteArray(reader, gameDescriptionHeader.UnitDataSize)
-	if err != nil {
-		return gameDescriptionHeader, nil, nil, err
+	var err error
+	fmt.Println("Unit data size: ", header.UnitDataSize)
+	if section.UnitData, err = readByteArray(reader, header.UnitDataSize, "unit data"); err != nil {
+		return nil, err
 	}
 
-	fmt.Println("Unit name data size: ", gameDescriptionHeader.UnitNameDataSize)
-	if _, err := readByteArray(reader, gameDescriptionHeader.UnitNameDataSize); err != nil {
-		return gameDescriptionHeader, nil, nil, err
+	fmt.Println("Unit name data size: ", header.UnitNameDataSize)
+	if section.UnitNameData, err = readByteArray(reader, header.UnitNameDataSize, "unit name data"); err != nil {
+		return nil, err
 	}
 
-	fmt.Println("City data size: ", gameDescriptionHeader.CityDataSize)
-	cityDataBytes, err := readByteArray(reader, gameDescriptionHeader.CityDataSize)
-	if err != nil {
-		return gameDescriptionHeader, nil, nil, err
+	fmt.Println("City data size: ", header.CityDataSize)
+	section.CityDataOffset = currentOffset(reader)
+	if section.CityData, err = readByteArray(reader, header.CityDataSize, "city data"); err != nil {
+		return nil, err
 	}
 
-	if version >= MapVersion11 {
-		if _, err := readReportedStringList(reader, victoryDataSize, "Victory data"); err != nil {
-			return gameDescriptionHeader, nil, nil, err
+	section.VictoryList = []string{}
+	section.GameOptionList = []string{}
+	if layout.HasVictoryAndOptions {
+		if section.VictoryList, err = readReportedStringList(reader, victoryDataSize, "Victory data"); err != nil {
+			return nil, err
 		}
-		if _, err := readReportedStringList(reader, gameOptionDataSize, "Game option data"); err != nil {
-			return gameDescriptionHeader, nil, nil, err
+		if section.GameOptionList, err = readReportedStringList(reader, gameOptionDataSize, "Game option data"); err != nil {
+			return nil, err
 		}
 	}
 
-	return gameDescriptionHeader, unitDataBytes, cityDataBytes, nil
+	return section, nil
 }
 
 // readFileTail reads a fixed-size section of a file, ending precedingBytes before the end of the file
-func readFileTail(inputFile *os.File, fileLength int64, size, precedingBytes int) ([]byte, error) {
-	data := make([]byte, size)
+func readFileTail(r io.ReaderAt, fileLength int64, size, precedingBytes int) ([]byte, error) {
 	offset := fileLength - int64(precedingBytes) - int64(size)
-	if _, err := inputFile.ReadAt(data, offset); err != nil {
-		return nil, err
+	if size < 0 || precedingBytes < 0 || offset < 0 {
+		return nil, &ErrTruncated{Section: "file tail", Offset: 0}
+	}
+	data := make([]byte, size)
+	if _, err := r.ReadAt(data, offset); err != nil {
+		return nil, newReadError("file tail", offset, err)
 	}
 	return data, nil
 }
 
 // readTailSections reads the map tile properties and player civilization data that are
 // stored at fixed-size offsets from the end of the file
-func readTailSections(inputFile *os.File, fileLength int64, mapHeader *Civ5MapHeader, gameDescriptionHeader *Civ5GameDescriptionHeader) ([][]*Civ5MapTileImprovement, []*Civ5PlayerData, error) {
-	mapTilePropertiesSize := int(mapHeader.Height) * int(mapHeader.Width) * binary.Size(Civ5MapTileHeader{})
-	mapTileProperties, err := readFileTail(inputFile, fileLength, mapTilePropertiesSize, 0)
+func readTailSections(r io.ReaderAt, fileLength int64, mapHeader *Civ5MapHeader, gameDescriptionHeader *Civ5GameDescriptionHeader, policyList []string) ([][]*Civ5MapTileImprovement, []*Civ5PlayerData, error) {
+	mapTilePropertiesSize := mapTilePropertiesSize(mapHeader)
+	mapTileProperties, err := readFileTail(r, fileLength, mapTilePropertiesSize, 0)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -957,13 +1209,12 @@ func readTailSections(inputFile *os.File, fileLength int64, mapHeader *Civ5MapHe
 		return nil, nil, err
 	}
 
-	playerCivDataSize := CivDataSize * (int(gameDescriptionHeader.PlayerCount) + int(gameDescriptionHeader.CityStateCount))
-	playerCivData, err := readFileTail(inputFile, fileLength, playerCivDataSize, mapTilePropertiesSize)
+	playerCivData, err := readFileTail(r, fileLength, playerCivDataSize(gameDescriptionHeader), mapTilePropertiesSize)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	allPlayerData, err := ParseCivData(playerCivData)
+	allPlayerData, err := ParseCivData(playerCivData, policyList)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -971,28 +1222,102 @@ func readTailSections(inputFile *os.File, fileLength int64, mapHeader *Civ5MapHe
 	return mapTileImprovementData, allPlayerData, nil
 }
 
+// mapTilePropertiesSize returns the size of the map tile properties at the end of the file
+func mapTilePropertiesSize(mapHeader *Civ5MapHeader) int {
+	return int(mapHeader.Height) * int(mapHeader.Width) * binary.Size(Civ5MapTileHeader{})
+}
+
+// playerCivDataSize returns the size of the player records that precede the map tile properties
+func playerCivDataSize(gameDescriptionHeader *Civ5GameDescriptionHeader) int {
+	return CivDataSize * (int(gameDescriptionHeader.PlayerCount) + int(gameDescriptionHeader.CityStateCount))
+}
+
+// readTeamSection reads the unknown block and the team names. The team names precede the player
+// records, and the unknown block fills the gap between the end of the game description (at
+// sectionStart) and the team names. If the sections overlap, the unknown block is left empty.
+func readTeamSection(r io.ReaderAt, fileLength, sectionStart int64, mapHeader *Civ5MapHeader, gameDescriptionHeader *Civ5GameDescriptionHeader) ([]byte, []string, error) {
+	teamDataSize := TeamNameSize * int(gameDescriptionHeader.TeamCount)
+	precedingBytes := mapTilePropertiesSize(mapHeader) + playerCivDataSize(gameDescriptionHeader)
+	teamData, err := readFileTail(r, fileLength, teamDataSize, precedingBytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read team data: %w", err)
+	}
+
+	teamNames := make([]string, int(gameDescriptionHeader.TeamCount))
+	for i := range teamNames {
+		teamNames[i] = nullTerminatedString(teamData[i*TeamNameSize : (i+1)*TeamNameSize])
+	}
+
+	unknownBlockSize := fileLength - int64(precedingBytes+teamDataSize) - sectionStart
+	if unknownBlockSize < 0 {
+		fmt.Println("Team data overlaps the game description by", -unknownBlockSize, "bytes, skipping unknown block")
+		return []byte{}, teamNames, nil
+	}
+	fmt.Println("Unknown block size: ", unknownBlockSize)
+	unknownBlock, err := readFileTail(r, fileLength, int(unknownBlockSize), precedingBytes+teamDataSize)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read unknown block: %w", err)
+	}
+	return unknownBlock, teamNames, nil
+}
+
+// buildTeams links each team name to the players whose Team field refers to it
+func buildTeams(teamNames []string, players []*Civ5PlayerData) []*Civ5TeamData {
+	teams := make([]*Civ5TeamData, len(teamNames))
+	for i, name := range teamNames {
+		teams[i] = &Civ5TeamData{Index: i, Name: name, PlayerIndices: []int{}}
+	}
+	for i, player := range players {
+		if player.Team >= 0 && player.Team < len(teams) {
+			teams[player.Team].PlayerIndices = append(teams[player.Team].PlayerIndices, i)
+		}
+	}
+	return teams
+}
+
+// reportTeams prints a human-readable summary of the teams and their players
+func reportTeams(teams []*Civ5TeamData) {
+	fmt.Printf("\n=== Teams (%d teams) ===\n", len(teams))
+	for _, team := range teams {
+		fmt.Printf("  %d. %s: players %v\n", team.Index+1, team.Name, team.PlayerIndices)
+	}
+}
+
+// ReadCiv5MapFile reads a .civ5map file from disk. See ParseCiv5Map.
 func ReadCiv5MapFile(filename string) (*Civ5MapData, error) {
-	inputFile, fileLength, streamReader, err := openMapFileReader(filename)
+	inputFile, fileLength, err := openInputFile(filename)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to load map: %w", err)
 	}
 	defer inputFile.Close()
+	return ParseCiv5Map(inputFile, fileLength)
+}
+
+// ParseCiv5Map parses the first size bytes of r as a .civ5map file. Nothing is written to disk,
+// so this also works on a map that is only held in memory, e.g. with a bytes.Reader.
+func ParseCiv5Map(r io.ReaderAt, size int64) (*Civ5MapData, error) {
+	streamReader := io.NewSectionReader(r, 0, size)
 
 	mapHeader := Civ5MapHeader{}
-	if err := readStruct(streamReader, &mapHeader); err != nil {
+	if err := readStruct(streamReader, &mapHeader, "map header"); err != nil {
 		return nil, err
 	}
 
 	version := mapVersion(mapHeader.ScenarioVersion)
 	scenario := mapScenario(mapHeader.ScenarioVersion)
 	reportMapHeaderInfo(&mapHeader, version, scenario)
+	layout, err := mapLayoutForVersion(version)
+	if err != nil {
+		return nil, err
+	}
 
-	terrainList, featureTerrainList, resourceList, err := readTerrainTypeLists(streamReader, &mapHeader)
+	terrainList, featureTerrainList, featureWonderList, resourceList, err := readTerrainTypeLists(streamReader, &mapHeader)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := skipMapMetadata(streamReader, &mapHeader, version); err != nil {
+	metadata, err := readMapMetadata(streamReader, &mapHeader, layout)
+	if err != nil {
 		return nil, err
 	}
 
@@ -1009,30 +1334,59 @@ func ReadCiv5MapFile(filename string) (*Civ5MapData, error) {
 		return nil, err
 	}
 	if atEndOfFile {
-		return createPhysicalMapData(&mapHeader, terrainList, featureTerrainList, resourceList, mapTiles), nil
+		mapData := createPhysicalMapData(&mapHeader, terrainList, featureTerrainList, resourceList, mapTiles)
+		setMapMetadata(mapData, metadata, featureWonderList)
+		return mapData, nil
 	}
 
-	gameDescriptionHeader, unitDataBytes, cityDataBytes, err := readGameDescriptionSection(streamReader, version)
+	gameDescription, err := readGameDescriptionSection(streamReader, layout)
 	if err != nil {
 		return nil, err
 	}
+	gameDescriptionHeader := gameDescription.Header
 
-	mapTileImprovementData, allPlayerData, err := readTailSections(inputFile, fileLength, &mapHeader, &gameDescriptionHeader)
+	gameDescriptionEnd, err := streamReader.Seek(0, io.SeekCurrent)
 	if err != nil {
 		return nil, err
 	}
 
-	maxCityId := findMaxCityId(mapTileImprovementData, int(mapHeader.Height), int(mapHeader.Width))
-	fmt.Println("Max city id is", maxCityId)
+	mapTileImprovementData, allPlayerData, err := readTailSections(r, size, &mapHeader, &gameDescriptionHeader, gameDescription.PolicyList)
+	if err != nil {
+		return nil, err
+	}
 
-	cityData, err := ParseCityData(cityDataBytes, version, maxCityId)
+	unknownBlock, teamNames, err := readTeamSection(r, size, gameDescriptionEnd, &mapHeader, &gameDescriptionHeader)
 	if err != nil {
 		return nil, err
 	}
+	teams := buildTeams(teamNames, allPlayerData)
+	reportTeams(teams)
+
+	maxCityId := findMaxCityId(mapTileImprovementData, int(mapHeader.Height), int(mapHeader.Width))
+	fmt.Println("Max city id is", maxCityId)
+
+	// The city section is read from the file itself so that errors give offsets in the file
+	var cityData []*Civ5CityData
+	if len(gameDescription.CityData) > 0 {
+		cityDataEnd := gameDescription.CityDataOffset + int64(len(gameDescription.CityData))
+		cityReader := io.NewSectionReader(r, 0, cityDataEnd)
+		if _, err := cityReader.Seek(gameDescription.CityDataOffset, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if cityData, err = readCities(cityReader, version, maxCityId); err != nil {
+			return nil, err
+		}
+	}
 
-	if _, err := ParseUnitData(unitDataBytes, version); err != nil {
+	units, err := ParseUnitData(gameDescription.UnitData, version)
+	if err != nil {
 		return nil, err
 	}
+	if units == nil {
+		units = []*Civ5UnitData{}
+	}
+	resolveUnits(units, byteArrayToStringArray(gameDescription.UnitNameData), gameDescription.UnitTypeList, mapTileImprovementData)
+	reportUnits(units)
 
 	if len(cityData) > 0 {
 		resolvedCities := resolveCityNames(mapTileImprovementData, cityData, mapHeader.Height, mapHeader.Width)
@@ -1042,6 +1396,12 @@ func ReadCiv5MapFile(filename string) (*Civ5MapData, error) {
 	cityOwnerMap, cityOwnerIndexMap := buildCityOwnerMaps(cityData, gameDescriptionHeader.PlayerCount, gameDescriptionHeader.CityStateCount)
 	reportCityOwnerMaps(cityOwnerMap, cityOwnerIndexMap)
 
-	return buildMapData(&mapHeader, terrainList, featureTerrainList, resourceList,
-		mapTiles, mapTileImprovementData, cityData, allPlayerData, cityOwnerIndexMap), nil
+	mapData := buildMapData(&mapHeader, &gameDescriptionHeader, terrainList, featureTerrainList, resourceList,
+		mapTiles, mapTileImprovementData, cityData, allPlayerData, cityOwnerIndexMap)
+	setMapMetadata(mapData, metadata, featureWonderList)
+	setGameDescriptionLists(mapData, gameDescription)
+	mapData.Units = units
+	mapData.UnknownBlock = unknownBlock
+	mapData.Teams = teams
+	return mapData, nil
 }
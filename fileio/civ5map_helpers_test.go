@@ -3,6 +3,7 @@ package fileio
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
 	"reflect"
 	"testing"
 )
@@ -85,6 +86,36 @@ func TestMapVersionAndScenario(t *testing.T) {
 	}
 }
 
+func TestMapHeaderFormatVersion(t *testing.T) {
+	// 0x8C is the scenario version byte of the maps in the maps folder
+	header := Civ5MapHeader{ScenarioVersion: 0x8C}
+	if got := header.FormatVersion(); got != MapVersion12 {
+		t.Errorf("FormatVersion() = %d, want %d", got, MapVersion12)
+	}
+}
+
+func TestMapLayoutForVersion(t *testing.T) {
+	for _, version := range []int{MapVersion11, MapVersion12} {
+		if _, err := mapLayoutForVersion(version); err != nil {
+			t.Errorf("mapLayoutForVersion(%d) returned error: %v", version, err)
+		}
+	}
+
+	// The versions before Gods & Kings haven't been checked against a real map
+	for _, version := range []int{7, 8, 9, 10} {
+		_, err := mapLayoutForVersion(version)
+		if want := fmt.Sprintf("unsupported map version %d", version); err == nil || err.Error() != want {
+			t.Errorf("mapLayoutForVersion(%d) error = %v, want %s", version, err, want)
+		}
+	}
+	if _, err := ParseCityData([]byte{0, 0, 0, 0}, 7, 0); err == nil {
+		t.Error("ParseCityData(version 7) = nil error, want an error")
+	}
+	if _, err := ParseUnitData([]byte{0, 0, 0, 0}, 7); err == nil {
+		t.Error("ParseUnitData(version 7) = nil error, want an error")
+	}
+}
+
 func TestGetSortedKeysInt(t *testing.T) {
 	m := map[int]string{3: "c", 1: "a", 2: "b"}
 	got := GetSortedKeys(m)
@@ -155,6 +186,44 @@ func TestParseUnitDataV12(t *testing.T) {
 	}
 }
 
+func TestParseUnitDataStatusFlags(t *testing.T) {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint32(1))
+	binary.Write(&buf, binary.LittleEndian, Civ5UnitHeaderV11{UnitType: 2, Status: 5})
+
+	units, err := ParseUnitData(buf.Bytes(), MapVersion11)
+	if err != nil {
+		t.Fatalf("ParseUnitData returned error: %v", err)
+	}
+	got := units[0]
+	if !got.IsFortified || got.IsEmbarked || !got.IsGarrisoned {
+		t.Errorf("status 5 decoded as fortified %t, embarked %t, garrisoned %t; want fortified and garrisoned",
+			got.IsFortified, got.IsEmbarked, got.IsGarrisoned)
+	}
+	if got.X != -1 || got.Y != -1 {
+		t.Errorf("unresolved unit position = (%d, %d), want (-1, -1)", got.X, got.Y)
+	}
+}
+
+func TestResolveUnits(t *testing.T) {
+	units := []*Civ5UnitData{
+		{X: -1, Y: -1, UnitType: 1, NameIndex: 0},
+		{X: -1, Y: -1, UnitType: 9, NameIndex: RawNoUnitNameIndex},
+	}
+	tiles := [][]*Civ5MapTileImprovement{
+		{{UnitId: InvalidUnitId}, {UnitId: 0}},
+		{{UnitId: 7}, {UnitId: InvalidUnitId}},
+	}
+	resolveUnits(units, []string{"Brutus"}, []string{"UNIT_SETTLER", "UNIT_WARRIOR"}, tiles)
+
+	if units[0].X != 1 || units[0].Y != 0 || units[0].UnitTypeName != "UNIT_WARRIOR" || units[0].Name != "Brutus" {
+		t.Errorf("units[0] = %+v, want UNIT_WARRIOR named Brutus at (1, 0)", units[0])
+	}
+	if units[1].Id != 1 || units[1].X != -1 || units[1].UnitTypeName != "" || units[1].Name != "" {
+		t.Errorf("units[1] = %+v, want unplaced unit 1 without type name or custom name", units[1])
+	}
+}
+
 func TestParseUnitDataEmpty(t *testing.T) {
 	units, err := ParseUnitData([]byte{}, MapVersion12)
 	if err != nil {
@@ -222,7 +291,7 @@ func TestParseCivData(t *testing.T) {
 	var buf bytes.Buffer
 	binary.Write(&buf, binary.LittleEndian, header)
 
-	players, err := ParseCivData(buf.Bytes())
+	players, err := ParseCivData(buf.Bytes(), nil)
 	if err != nil {
 		t.Fatalf("ParseCivData returned error: %v", err)
 	}
@@ -237,6 +306,55 @@ func TestParseCivData(t *testing.T) {
 	}
 }
 
+func TestParseCivDataFullRecord(t *testing.T) {
+	header := Civ5PlayerHeader{
+		Culture:        50,
+		Gold:           300,
+		StartPositionX: 12,
+		StartPositionY: 34,
+		Team:           2,
+		Playable:       1,
+	}
+	header.Policies[0] = 0b101 // first and third policy
+	header.Policies[1] = 0b1   // ninth policy, beyond the end of the policy list
+	copy(header.LeaderName[:], "Augustus")
+	copy(header.CivName[:], "Roman Republic")
+	copy(header.Era[:], "ERA_CLASSICAL")
+	copy(header.Handicap[:], "HANDICAP_PRINCE")
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, header)
+
+	policyList := []string{"POLICY_LIBERTY", "POLICY_TRADITION", "POLICY_HONOR"}
+	players, err := ParseCivData(buf.Bytes(), policyList)
+	if err != nil {
+		t.Fatalf("ParseCivData returned error: %v", err)
+	}
+	got := players[0]
+	if got.LeaderName != "Augustus" || got.CivName != "Roman Republic" || got.Era != "ERA_CLASSICAL" || got.Handicap != "HANDICAP_PRINCE" {
+		t.Errorf("ParseCivData() names = %q %q %q %q", got.LeaderName, got.CivName, got.Era, got.Handicap)
+	}
+	if got.Culture != 50 || got.Gold != 300 || got.StartPositionX != 12 || got.StartPositionY != 34 || got.Team != 2 || !got.Playable {
+		t.Errorf("ParseCivData() player = %+v, want matching header fields", got)
+	}
+	if want := []string{"POLICY_LIBERTY", "POLICY_HONOR"}; !reflect.DeepEqual(got.Policies, want) {
+		t.Errorf("ParseCivData() Policies = %v, want %v", got.Policies, want)
+	}
+}
+
+func TestBuildTeams(t *testing.T) {
+	players := []*Civ5PlayerData{{Team: 1}, {Team: 0}, {Team: 1}, {Team: 9}}
+	teams := buildTeams([]string{"Axis", "Allies"}, players)
+
+	want := []*Civ5TeamData{
+		{Index: 0, Name: "Axis", PlayerIndices: []int{1}},
+		{Index: 1, Name: "Allies", PlayerIndices: []int{0, 2}},
+	}
+	if !reflect.DeepEqual(teams, want) {
+		t.Errorf("buildTeams() = %+v, want %+v", teams, want)
+	}
+}
+
 func TestParseMapTileProperties(t *testing.T) {
 	var buf bytes.Buffer
 	// 2 tiles (1x2 map)
@@ -56,6 +56,10 @@ func GetPhysicalMapTileColor(terrainString string) color.RGBA {
 		return color.RGBA{95, 149, 149, 255}
 	case "TERRAIN_OCEAN":
 		return color.RGBA{47, 74, 93, 255}
+	case "TERRAIN_MOUNTAIN":
+		return color.RGBA{109, 97, 86, 255}
+	case "TERRAIN_HILL":
+		return color.RGBA{127, 121, 71, 255}
 	}
 
 	// default
@@ -77,6 +81,48 @@ func GetTerrainString(mapData *Civ5MapData, row int, column int) string {
 	return mapData.TerrainList[terrainType]
 }
 
+// tileListEntry looks up the tile at row, column in grid and returns the entry of list at the
+// index that field reads from it. It returns "" if the tile is off the grid or the index isn't in
+// the list, like the 0xFF that marks a tile without a feature, resource or improvement.
+func tileListEntry[T any](grid [][]*T, row int, column int, list []string, field func(*T) int) string {
+	// Check bounds to prevent panic
+	if row < 0 || row >= len(grid) {
+		return ""
+	}
+	if column < 0 || column >= len(grid[row]) || grid[row][column] == nil {
+		return ""
+	}
+	index := field(grid[row][column])
+	if index < 0 || index >= len(list) {
+		return ""
+	}
+	return list[index]
+}
+
+// GetFeatureString returns the feature on a tile, e.g. FEATURE_FOREST, or "" if it has none
+func GetFeatureString(mapData *Civ5MapData, row int, column int) string {
+	return tileListEntry(mapData.MapTiles, row, column, mapData.FeatureTerrainList,
+		func(tile *Civ5MapTilePhysical) int { return tile.FeatureTerrainType })
+}
+
+// GetFeatureWonderString returns the natural wonder on a tile, e.g. FEATURE_FUJI, or "" if it has none
+func GetFeatureWonderString(mapData *Civ5MapData, row int, column int) string {
+	return tileListEntry(mapData.MapTiles, row, column, mapData.FeatureWonderList,
+		func(tile *Civ5MapTilePhysical) int { return tile.FeatureWonderType })
+}
+
+// GetResourceString returns the resource on a tile, e.g. RESOURCE_IRON, or "" if it has none
+func GetResourceString(mapData *Civ5MapData, row int, column int) string {
+	return tileListEntry(mapData.MapTiles, row, column, mapData.ResourceList,
+		func(tile *Civ5MapTilePhysical) int { return tile.ResourceType })
+}
+
+// GetImprovementString returns the improvement on a tile, e.g. IMPROVEMENT_FARM, or "" if it has none
+func GetImprovementString(mapData *Civ5MapData, row int, column int) string {
+	return tileListEntry(mapData.MapTileImprovements, row, column, mapData.TileImprovementList,
+		func(tile *Civ5MapTileImprovement) int { return tile.Improvement })
+}
+
 func IsWaterTile(mapData *Civ5MapData, row int, column int) bool {
 	terrainString := GetTerrainString(mapData, row, column)
 	return terrainString == "TERRAIN_COAST" || terrainString == "TERRAIN_OCEAN"
@@ -101,7 +147,18 @@ func TileHasMountain(mapData *Civ5MapData, row int, column int) bool {
 	if column < 0 || column >= len(mapData.MapTiles[row]) {
 		return false
 	}
-	return mapData.MapTiles[row][column].Elevation == 2
+	return mapData.MapTiles[row][column].Elevation == ElevationMountain
+}
+
+func TileHasHills(mapData *Civ5MapData, row int, column int) bool {
+	// Check bounds to prevent panic
+	if row < 0 || row >= len(mapData.MapTiles) {
+		return false
+	}
+	if column < 0 || column >= len(mapData.MapTiles[row]) {
+		return false
+	}
+	return mapData.MapTiles[row][column].Elevation == ElevationHills
 }
 
 func IsInvalidTileOwner(value int) bool {
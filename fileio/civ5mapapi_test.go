@@ -101,6 +101,55 @@ func TestGetTerrainStringBounds(t *testing.T) {
 	}
 }
 
+func TestGetTileListStrings(t *testing.T) {
+	// Tile (0, 0) has the second entry of every list and tile (0, 1) has none
+	mapData := newTestMapData()
+	mapData.FeatureTerrainList = []string{"FEATURE_ICE", "FEATURE_FOREST"}
+	mapData.FeatureWonderList = DefaultFeatureWonderList
+	mapData.ResourceList = []string{"RESOURCE_IRON", "RESOURCE_WHEAT"}
+	mapData.TileImprovementList = []string{"IMPROVEMENT_FARM", "IMPROVEMENT_MINE"}
+	first, second := mapData.MapTiles[0][0], mapData.MapTiles[0][1]
+	first.FeatureTerrainType, first.FeatureWonderType, first.ResourceType = 1, 1, 1
+	second.FeatureTerrainType, second.FeatureWonderType, second.ResourceType = 0xFF, 0xFF, 0xFF
+	mapData.MapTileImprovements[0][0].Improvement = 1
+	mapData.MapTileImprovements[0][1].Improvement = 0xFF
+
+	tests := []struct {
+		name   string
+		lookup func(*Civ5MapData, int, int) string
+		want   string
+	}{
+		{"GetFeatureString", GetFeatureString, "FEATURE_FOREST"},
+		{"GetFeatureWonderString", GetFeatureWonderString, "FEATURE_FUJI"},
+		{"GetResourceString", GetResourceString, "RESOURCE_WHEAT"},
+		{"GetImprovementString", GetImprovementString, "IMPROVEMENT_MINE"},
+	}
+	for _, tt := range tests {
+		if got := tt.lookup(mapData, 0, 0); got != tt.want {
+			t.Errorf("%s(0,0) = %q, want %q", tt.name, got, tt.want)
+		}
+		// No entry and out of bounds should return ""
+		for _, position := range [][2]int{{0, 1}, {-1, 0}, {5, 0}, {0, 5}} {
+			if got := tt.lookup(mapData, position[0], position[1]); got != "" {
+				t.Errorf("%s(%d,%d) = %q, want \"\"", tt.name, position[0], position[1], got)
+			}
+		}
+	}
+}
+
+// TestGetImprovementStringFromMapFile checks that improvements in a .civ5map are named from the
+// improvement list of its game description
+func TestGetImprovementStringFromMapFile(t *testing.T) {
+	mapData := newWriterTestMapData(MapVersion12)
+	mapData.TileImprovementList = []string{"IMPROVEMENT_FARM", "IMPROVEMENT_MINE"}
+	mapData.MapTileImprovements[0][1].Improvement = 1
+
+	got := writeAndReadMapFile(t, mapData)
+	if name := GetImprovementString(got, 0, 1); name != "IMPROVEMENT_MINE" {
+		t.Errorf("GetImprovementString(0,1) = %q, want IMPROVEMENT_MINE", name)
+	}
+}
+
 func TestIsWaterTile(t *testing.T) {
 	mapData := newTestMapData()
 
@@ -144,6 +193,21 @@ func TestTileHasMountain(t *testing.T) {
 	}
 }
 
+func TestTileHasHills(t *testing.T) {
+	mapData := newTestMapData()
+	mapData.MapTiles[0][0].Elevation = ElevationHills
+
+	if !TileHasHills(mapData, 0, 0) {
+		t.Errorf("expected (0,0) elevation 1 to be hills")
+	}
+	if TileHasHills(mapData, 0, 1) {
+		t.Errorf("expected (0,1) elevation 2 to not be hills")
+	}
+	if TileHasHills(mapData, 99, 99) {
+		t.Errorf("expected out of bounds to not be hills")
+	}
+}
+
 func TestIsInvalidTileOwner(t *testing.T) {
 	tests := []struct {
 		value int
@@ -0,0 +1,517 @@
+package fileio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Default values written for records the parsed map data doesn't carry
+const (
+	DefaultCityPopulation = 1
+	DefaultCityHealth     = 100000 // 100% health
+	RawNoUnitNameIndex    = 65535  // Name index written for units without a custom name
+)
+
+// stringArrayToByteArray joins a list of strings into a null-separated byte buffer.
+// This is the inverse of byteArrayToStringArray.
+func stringArrayToByteArray(list []string) []byte {
+	var builder strings.Builder
+	for _, item := range list {
+		builder.WriteString(item)
+		builder.WriteByte(0)
+	}
+	return []byte(builder.String())
+}
+
+// nullTerminatedBytes encodes a string followed by a single null byte
+func nullTerminatedBytes(s string) []byte {
+	return append([]byte(s), 0)
+}
+
+// fixedSizeBytes copies a string into a zero-padded buffer of the given size, truncating if needed
+func fixedSizeBytes(s string, size int) []byte {
+	buffer := make([]byte, size)
+	copy(buffer, s)
+	return buffer
+}
+
+// writeUint32 writes a uint32 to the binary stream
+func writeUint32(writer io.Writer, value uint32) error {
+	return binary.Write(writer, binary.LittleEndian, value)
+}
+
+// writeStruct writes a struct to the binary stream
+func writeStruct(writer io.Writer, data interface{}) error {
+	return binary.Write(writer, binary.LittleEndian, data)
+}
+
+// writeByteArrays writes each byte array to the binary stream in order
+func writeByteArrays(writer io.Writer, arrays ...[]byte) error {
+	for _, array := range arrays {
+		if _, err := writer.Write(array); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mapDimensions returns the height and width of the physical tile grid, checking that every row
+// has the same width
+func mapDimensions(mapData *Civ5MapData) (int, int, error) {
+	height := len(mapData.MapTiles)
+	if height == 0 || len(mapData.MapTiles[0]) == 0 {
+		return 0, 0, fmt.Errorf("map has no tiles to write")
+	}
+	width := len(mapData.MapTiles[0])
+	for i, row := range mapData.MapTiles {
+		if len(row) != width {
+			return 0, 0, fmt.Errorf("map tile row %d has width %d, expected %d", i, len(row), width)
+		}
+	}
+	return height, width, nil
+}
+
+// playerAndCityStateCounts returns how many of the player records belong to major civs and how many
+// to city states. Maps read from a .civ5map file carry these counts in the game description header.
+// Maps imported from older json exports don't, so the counts are derived from the owner index map
+// instead, which has one entry per player slot with city states starting at CityStateOffset.
+func playerAndCityStateCounts(mapData *Civ5MapData) (int, int) {
+	header := mapData.GameDescriptionHeader
+	if int(header.PlayerCount)+int(header.CityStateCount) == len(mapData.Civ5PlayerData) {
+		return int(header.PlayerCount), int(header.CityStateCount)
+	}
+
+	playerCount, cityStateCount := 0, 0
+	for owner := range mapData.CityOwnerIndexMap {
+		if owner >= CityStateOffset {
+			cityStateCount++
+		} else {
+			playerCount++
+		}
+	}
+	if playerCount+cityStateCount == len(mapData.Civ5PlayerData) {
+		return playerCount, cityStateCount
+	}
+	return len(mapData.Civ5PlayerData), 0
+}
+
+// citiesForWrite returns the city records to write. Older json exports don't include CityData,
+// only the resolved city name and owner on each tile, so in that case a minimal city record is
+// rebuilt from the tiles so that the city ids stored in the tile properties stay valid.
+func citiesForWrite(mapData *Civ5MapData, height, width int) []*Civ5CityData {
+	if len(mapData.CityData) > 0 || len(mapData.MapTileImprovements) == 0 {
+		return mapData.CityData
+	}
+
+	maxCityId := InvalidCityId
+	for i := 0; i < height; i++ {
+		for j := 0; j < width; j++ {
+			if cityId := mapData.MapTileImprovements[i][j].CityId; cityId > maxCityId {
+				maxCityId = cityId
+			}
+		}
+	}
+	if maxCityId == InvalidCityId {
+		return nil
+	}
+
+	cities := make([]*Civ5CityData, maxCityId+1)
+	for i := range cities {
+		cities[i] = &Civ5CityData{Population: DefaultCityPopulation, Health: DefaultCityHealth}
+	}
+	for i := 0; i < height; i++ {
+		for j := 0; j < width; j++ {
+			tile := mapData.MapTileImprovements[i][j]
+			if tile.CityId == InvalidCityId || tile.CityId > maxCityId {
+				continue
+			}
+			cities[tile.CityId].Name = tile.CityName
+			cities[tile.CityId].Owner = tile.Owner
+			cities[tile.CityId].OwnerAdjusted = int(adjustedCityOwner(uint8(tile.Owner)))
+		}
+	}
+	return cities
+}
+
+// encodeCityData encodes the city section: a city count followed by each city record and its
+// building data
+func encodeCityData(cities []*Civ5CityData, version int) ([]byte, error) {
+	if len(cities) == 0 {
+		return []byte{}, nil
+	}
+
+	var buffer bytes.Buffer
+	if err := writeUint32(&buffer, uint32(len(cities))); err != nil {
+		return nil, err
+	}
+
+	buildingDataSize := buildingDataSizeForVersion(version)
+	for _, city := range cities {
+		header := Civ5CityHeader{
+			Owner:      uint8(city.Owner),
+			Population: uint16(city.Population),
+			Health:     uint32(city.Health),
+		}
+		copy(header.Name[:], city.Name)
+		if city.IsNameLocalized {
+			header.Flags |= 1
+		}
+		if city.IsPuppetState {
+			header.Flags |= 1 << IsPuppetStateFlag
+		}
+		if city.IsOccupied {
+			header.Flags |= 1 << IsOccupiedFlag
+		}
+		if err := writeStruct(&buffer, header); err != nil {
+			return nil, err
+		}
+
+		buildingInfo := make([]byte, buildingDataSize)
+		copy(buildingInfo, city.BuildingInfo)
+		buffer.Write(buildingInfo)
+	}
+	return buffer.Bytes(), nil
+}
+
+// encodeUnitData encodes the unit section, a unit count followed by each unit record, together
+// with the unit name section. Custom names are renumbered in unit order, so the name index of
+// each unit is recomputed rather than copied.
+func encodeUnitData(units []*Civ5UnitData, version int) ([]byte, []byte, error) {
+	if len(units) == 0 {
+		return []byte{}, []byte{}, nil
+	}
+
+	var buffer bytes.Buffer
+	if err := writeUint32(&buffer, uint32(len(units))); err != nil {
+		return nil, nil, err
+	}
+
+	unitNames := make([]string, 0)
+	for i, unit := range units {
+		if unit == nil {
+			return nil, nil, fmt.Errorf("unit %d is missing", i)
+		}
+		nameIndex := uint16(RawNoUnitNameIndex)
+		if unit.Name != "" {
+			nameIndex = uint16(len(unitNames))
+			unitNames = append(unitNames, unit.Name)
+		}
+
+		status := uint8(unit.Status) &^ (1<<UnitFortifiedFlag | 1<<UnitEmbarkedFlag | 1<<UnitGarrisonedFlag)
+		if unit.IsFortified {
+			status |= 1 << UnitFortifiedFlag
+		}
+		if unit.IsEmbarked {
+			status |= 1 << UnitEmbarkedFlag
+		}
+		if unit.IsGarrisoned {
+			status |= 1 << UnitGarrisonedFlag
+		}
+
+		var header interface{}
+		if civ5MapLayouts[version].UnitDataSize == UnitDataSizeV12 {
+			headerV12 := Civ5UnitHeaderV12{
+				NameIndex:       nameIndex,
+				Experience:      uint32(unit.Experience),
+				Health:          uint32(unit.Health),
+				UnitType:        uint32(unit.UnitType),
+				Owner:           uint8(unit.Owner),
+				FacingDirection: uint8(unit.FacingDirection),
+				Status:          status,
+			}
+			copy(headerV12.Promotion[:], unit.PromotionInfo)
+			header = headerV12
+		} else {
+			headerV11 := Civ5UnitHeaderV11{
+				NameIndex:       nameIndex,
+				Experience:      uint32(unit.Experience),
+				Health:          uint32(unit.Health),
+				UnitType:        uint8(unit.UnitType),
+				Owner:           uint8(unit.Owner),
+				FacingDirection: uint8(unit.FacingDirection),
+				Status:          status,
+			}
+			copy(headerV11.Promotion[:], unit.PromotionInfo)
+			header = headerV11
+		}
+		if err := writeStruct(&buffer, header); err != nil {
+			return nil, nil, err
+		}
+	}
+	return buffer.Bytes(), stringArrayToByteArray(unitNames), nil
+}
+
+// encodeTeamNames encodes one fixed-size team name record per team. Maps without team data
+// get the default team names, one team per player.
+func encodeTeamNames(teams []*Civ5TeamData, teamCount int) []byte {
+	data := make([]byte, 0, teamCount*TeamNameSize)
+	for i := 0; i < teamCount; i++ {
+		name := fmt.Sprintf("Team %d", i+1)
+		if i < len(teams) {
+			name = teams[i].Name
+		}
+		data = append(data, fixedSizeBytes(name, TeamNameSize)...)
+	}
+	return data
+}
+
+// teamCountForWrite returns the number of team records to write
+func teamCountForWrite(mapData *Civ5MapData, playerCount, cityStateCount int) int {
+	if len(mapData.Teams) > 0 {
+		return len(mapData.Teams)
+	}
+	return playerCount + cityStateCount
+}
+
+// encodePolicies sets the bit of every named policy found in the policy list.
+// Policies that aren't in the list can't be represented and are dropped.
+func encodePolicies(policies, policyList []string) [32]byte {
+	bitfield := [32]byte{}
+	policyIndex := make(map[string]int, len(policyList))
+	for i, name := range policyList {
+		if _, ok := policyIndex[name]; !ok {
+			policyIndex[name] = i
+		}
+	}
+	for _, name := range policies {
+		if i, ok := policyIndex[name]; ok && i < len(bitfield)*8 {
+			bitfield[i/8] |= 1 << (i % 8)
+		}
+	}
+	return bitfield
+}
+
+// playerDataToCivHeader maps the public player data model back to the raw civilization header
+func playerDataToCivHeader(player *Civ5PlayerData, policyList []string) Civ5PlayerHeader {
+	header := Civ5PlayerHeader{
+		Policies:       encodePolicies(player.Policies, policyList),
+		Culture:        uint32(player.Culture),
+		Gold:           uint32(player.Gold),
+		StartPositionX: uint32(player.StartPositionX),
+		StartPositionY: uint32(player.StartPositionY),
+		Team:           uint8(player.Team),
+	}
+	copy(header.LeaderName[:], player.LeaderName)
+	copy(header.CivName[:], player.CivName)
+	copy(header.CivType[:], player.CivType)
+	copy(header.TeamColor[:], player.TeamColor)
+	copy(header.Era[:], player.Era)
+	copy(header.Handicap[:], player.Handicap)
+	if player.Playable {
+		header.Playable = 1
+	}
+	return header
+}
+
+// mapTileImprovementToHeader maps a parsed tile property back to the raw tile header
+func mapTileImprovementToHeader(tile *Civ5MapTileImprovement, cityCount, unitCount int) Civ5MapTileHeader {
+	cityId := uint16(RawNoCityId)
+	if tile.CityId != InvalidCityId && tile.CityId < cityCount {
+		cityId = uint16(tile.CityId)
+	}
+	unitId := uint16(RawNoUnitId)
+	if tile.UnitId != InvalidUnitId && tile.UnitId < unitCount {
+		unitId = uint16(tile.UnitId)
+	}
+	return Civ5MapTileHeader{
+		CityId:      cityId,
+		UnitId:      unitId,
+		Owner:       uint8(tile.Owner),
+		Improvement: uint8(tile.Improvement),
+		RouteType:   uint8(tile.RouteType),
+		RouteOwner:  uint8(tile.RouteOwner),
+	}
+}
+
+// writeMapSection writes the map header, the geography string lists, the map metadata, and the
+// physical map tiles
+func writeMapSection(writer io.Writer, mapData *Civ5MapData, version, height, width int) error {
+	terrainData := stringArrayToByteArray(mapData.TerrainList)
+	featureTerrainData := stringArrayToByteArray(mapData.FeatureTerrainList)
+	featureWonderData := stringArrayToByteArray(mapData.FeatureWonderList)
+	resourceData := stringArrayToByteArray(mapData.ResourceList)
+	modData := []byte(mapData.ModData)
+	mapName := nullTerminatedBytes(mapData.MapName)
+	mapDescription := nullTerminatedBytes(mapData.MapDescription)
+
+	header := mapData.MapHeader
+	header.Width = uint32(width)
+	header.Height = uint32(height)
+	header.TerrainDataSize = uint32(len(terrainData))
+	header.FeatureTerrainDataSize = uint32(len(featureTerrainData))
+	header.FeatureWonderDataSize = uint32(len(featureWonderData))
+	header.ResourceDataSize = uint32(len(resourceData))
+	header.ModDataSize = uint32(len(modData))
+	header.MapNameLength = uint32(len(mapName))
+	header.MapDescriptionLength = uint32(len(mapDescription))
+	if err := writeStruct(writer, header); err != nil {
+		return fmt.Errorf("failed to write map header: %w", err)
+	}
+
+	if err := writeByteArrays(writer, terrainData, featureTerrainData, featureWonderData, resourceData,
+		modData, mapName, mapDescription); err != nil {
+		return fmt.Errorf("failed to write map string lists: %w", err)
+	}
+
+	// Earlier versions don't have the world size field
+	if civ5MapLayouts[version].HasWorldSize {
+		if err := writeUint32(writer, uint32(len(mapData.WorldSize))); err != nil {
+			return fmt.Errorf("failed to write world size length: %w", err)
+		}
+		if err := writeByteArrays(writer, []byte(mapData.WorldSize)); err != nil {
+			return fmt.Errorf("failed to write world size: %w", err)
+		}
+	}
+
+	for i := 0; i < height; i++ {
+		for j := 0; j < width; j++ {
+			tile := mapData.MapTiles[i][j]
+			if err := writeStruct(writer, Civ5MapTile{
+				TerrainType:        uint8(tile.TerrainType),
+				ResourceType:       uint8(tile.ResourceType),
+				FeatureTerrainType: uint8(tile.FeatureTerrainType),
+				RiverData:          uint8(tile.RiverData),
+				Elevation:          uint8(tile.Elevation),
+				Continent:          uint8(tile.Continent),
+				FeatureWonderType:  uint8(tile.FeatureWonderType),
+				ResourceAmount:     uint8(tile.ResourceAmount),
+			}); err != nil {
+				return fmt.Errorf("failed to write map tile at position (%d, %d): %w", i, j, err)
+			}
+		}
+	}
+	return nil
+}
+
+// writeGameDescriptionSection writes the game description header and the type lists, unit data,
+// city data, victory and game option lists that follow it
+func writeGameDescriptionSection(writer io.Writer, mapData *Civ5MapData, cities []*Civ5CityData, version, playerCount, cityStateCount int) error {
+	improvementData := stringArrayToByteArray(mapData.TileImprovementList)
+	unitTypeData := stringArrayToByteArray(mapData.UnitTypeList)
+	techTypeData := stringArrayToByteArray(mapData.TechList)
+	policyTypeData := stringArrayToByteArray(mapData.PolicyList)
+	buildingTypeData := stringArrayToByteArray(mapData.BuildingList)
+	promotionTypeData := stringArrayToByteArray(mapData.PromotionList)
+	victoryData := stringArrayToByteArray(mapData.VictoryList)
+	gameOptionData := stringArrayToByteArray(mapData.GameOptionList)
+
+	unitData, unitNameData, err := encodeUnitData(mapData.Units, version)
+	if err != nil {
+		return fmt.Errorf("failed to encode unit data: %w", err)
+	}
+
+	cityData, err := encodeCityData(cities, version)
+	if err != nil {
+		return fmt.Errorf("failed to encode city data: %w", err)
+	}
+
+	header := mapData.GameDescriptionHeader
+	header.PlayerCount = uint8(playerCount)
+	header.CityStateCount = uint8(cityStateCount)
+	header.TeamCount = uint8(teamCountForWrite(mapData, playerCount, cityStateCount))
+	header.ImprovementDataSize = uint32(len(improvementData))
+	header.UnitTypeDataSize = uint32(len(unitTypeData))
+	header.TechTypeDataSize = uint32(len(techTypeData))
+	header.PolicyTypeDataSize = uint32(len(policyTypeData))
+	header.BuildingTypeDataSize = uint32(len(buildingTypeData))
+	header.PromotionTypeDataSize = uint32(len(promotionTypeData))
+	header.UnitDataSize = uint32(len(unitData))
+	header.UnitNameDataSize = uint32(len(unitNameData))
+	header.CityDataSize = uint32(len(cityData))
+	if err := writeStruct(writer, header); err != nil {
+		return fmt.Errorf("failed to write game description header: %w", err)
+	}
+
+	if civ5MapLayouts[version].HasVictoryAndOptions {
+		if err := writeUint32(writer, uint32(len(victoryData))); err != nil {
+			return err
+		}
+		if err := writeUint32(writer, uint32(len(gameOptionData))); err != nil {
+			return err
+		}
+	}
+
+	if err := writeByteArrays(writer, improvementData, unitTypeData, techTypeData, policyTypeData,
+		buildingTypeData, promotionTypeData, unitData, unitNameData, cityData); err != nil {
+		return fmt.Errorf("failed to write game description data: %w", err)
+	}
+
+	if civ5MapLayouts[version].HasVictoryAndOptions {
+		if err := writeByteArrays(writer, victoryData, gameOptionData); err != nil {
+			return fmt.Errorf("failed to write victory and game option data: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeTailSections writes the unknown block, the team names, the player civilization data, and
+// the map tile properties, which the reader locates at fixed-size offsets from the end of the file
+func writeTailSections(writer io.Writer, mapData *Civ5MapData, cityCount, teamCount, height, width int) error {
+	if err := writeByteArrays(writer, mapData.UnknownBlock); err != nil {
+		return fmt.Errorf("failed to write unknown block: %w", err)
+	}
+	if err := writeByteArrays(writer, encodeTeamNames(mapData.Teams, teamCount)); err != nil {
+		return fmt.Errorf("failed to write team data: %w", err)
+	}
+
+	for i, player := range mapData.Civ5PlayerData {
+		if err := writeStruct(writer, playerDataToCivHeader(player, mapData.PolicyList)); err != nil {
+			return fmt.Errorf("failed to write player %d: %w", i, err)
+		}
+	}
+
+	for i := 0; i < height; i++ {
+		for j := 0; j < width; j++ {
+			if err := writeStruct(writer, mapTileImprovementToHeader(mapData.MapTileImprovements[i][j], cityCount, len(mapData.Units))); err != nil {
+				return fmt.Errorf("failed to write tile properties at position (%d, %d): %w", i, j, err)
+			}
+		}
+	}
+	return nil
+}
+
+// WriteCiv5MapFile writes map data in the binary .civ5map format, using the layout for the format
+// version stored in the map header. Section sizes in the map and game description headers are
+// recomputed from the data being written. A map without tile properties is written as a physical
+// map only, without any game description data.
+func WriteCiv5MapFile(mapData *Civ5MapData, w io.Writer) error {
+	if mapData == nil {
+		return fmt.Errorf("map data is nil")
+	}
+	height, width, err := mapDimensions(mapData)
+	if err != nil {
+		return err
+	}
+	version := mapVersion(mapData.MapHeader.ScenarioVersion)
+	if _, err := mapLayoutForVersion(version); err != nil {
+		return err
+	}
+
+	if err := writeMapSection(w, mapData, version, height, width); err != nil {
+		return err
+	}
+
+	if len(mapData.MapTileImprovements) == 0 {
+		return nil
+	}
+	if len(mapData.MapTileImprovements) != height {
+		return fmt.Errorf("map has %d rows of tile properties, expected %d", len(mapData.MapTileImprovements), height)
+	}
+	for i, row := range mapData.MapTileImprovements {
+		if len(row) != width {
+			return fmt.Errorf("tile property row %d has width %d, expected %d", i, len(row), width)
+		}
+	}
+
+	cities := citiesForWrite(mapData, height, width)
+	playerCount, cityStateCount := playerAndCityStateCounts(mapData)
+	if err := writeGameDescriptionSection(w, mapData, cities, version, playerCount, cityStateCount); err != nil {
+		return err
+	}
+
+	teamCount := teamCountForWrite(mapData, playerCount, cityStateCount)
+	return writeTailSections(w, mapData, len(cities), teamCount, height, width)
+}
@@ -0,0 +1,402 @@
+package fileio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// newWriterTestMapData builds a small 2x3 map with one city per civ, for round trip tests.
+func newWriterTestMapData(version int) *Civ5MapData {
+	height, width := 2, 3
+	mapTiles := make([][]*Civ5MapTilePhysical, height)
+	improvements := make([][]*Civ5MapTileImprovement, height)
+	for i := 0; i < height; i++ {
+		mapTiles[i] = make([]*Civ5MapTilePhysical, width)
+		improvements[i] = make([]*Civ5MapTileImprovement, width)
+		for j := 0; j < width; j++ {
+			mapTiles[i][j] = &Civ5MapTilePhysical{
+				X:                  j,
+				Y:                  i,
+				TerrainType:        (i + j) % 2,
+				ResourceType:       0xFF,
+				FeatureTerrainType: 0xFF,
+				RiverData:          j % 2,
+				Elevation:          i,
+				Continent:          1,
+				FeatureWonderType:  0xFF,
+				ResourceAmount:     0,
+			}
+			improvements[i][j] = &Civ5MapTileImprovement{
+				X:           j,
+				Y:           i,
+				CityId:      InvalidCityId,
+				UnitId:      InvalidUnitId,
+				Owner:       0xFF,
+				Improvement: 0xFF,
+				RouteType:   0xFF,
+				RouteOwner:  0xFF,
+			}
+		}
+	}
+	improvements[0][0].CityId = 0
+	improvements[0][0].CityName = "Rome"
+	improvements[0][0].Owner = 0
+	improvements[1][2].CityId = 1
+	improvements[1][2].CityName = "Monaco"
+	improvements[1][2].Owner = CityStateOffset
+	improvements[1][1].Owner = CityStateOffset
+	improvements[1][1].RouteType = 0
+	improvements[0][1].UnitId = 0
+	improvements[0][2].UnitId = 1
+
+	promotionSize := PromotionSizeV11
+	if version == MapVersion12 {
+		promotionSize = PromotionSizeV12
+	}
+	units := []*Civ5UnitData{
+		{Id: 0, X: 1, Y: 0, Name: "Brutus", NameIndex: 0, Experience: 5, Health: 100000, UnitType: 1, UnitTypeName: "UNIT_WARRIOR",
+			Owner: 0, FacingDirection: 2, Status: 1 << UnitFortifiedFlag, IsFortified: true, PromotionInfo: make([]byte, promotionSize)},
+		{Id: 1, X: 2, Y: 0, NameIndex: RawNoUnitNameIndex, Health: 100000, UnitType: 0, UnitTypeName: "UNIT_SETTLER",
+			Owner: 0, Status: 1<<UnitEmbarkedFlag | 1<<UnitGarrisonedFlag, IsEmbarked: true, IsGarrisoned: true, PromotionInfo: make([]byte, promotionSize)},
+	}
+	units[0].PromotionInfo[0] = 1
+
+	return &Civ5MapData{
+		MapHeader: Civ5MapHeader{ScenarioVersion: uint8(version), Players: 1},
+		GameDescriptionHeader: Civ5GameDescriptionHeader{
+			MaxTurns:       500,
+			StartYear:      -4000,
+			PlayerCount:    1,
+			CityStateCount: 1,
+		},
+		MapName:             "Test Map",
+		MapDescription:      "A small map for testing",
+		WorldSize:           "WORLDSIZE_DUEL",
+		ModData:             "",
+		TerrainList:         []string{"TERRAIN_GRASS", "TERRAIN_OCEAN"},
+		FeatureTerrainList:  []string{"FEATURE_ICE"},
+		FeatureWonderList:   []string{"FEATURE_FUJI"},
+		ResourceList:        []string{"RESOURCE_IRON"},
+		TileImprovementList: []string{"IMPROVEMENT_FARM"},
+		UnitTypeList:        []string{"UNIT_SETTLER", "UNIT_WARRIOR"},
+		TechList:            []string{"TECH_AGRICULTURE"},
+		PolicyList:          []string{"POLICY_TRADITION", "POLICY_LIBERTY"},
+		BuildingList:        []string{"BUILDING_PALACE"},
+		PromotionList:       []string{"PROMOTION_DRILL_1"},
+		VictoryList:         []string{"VICTORY_DOMINATION"},
+		GameOptionList:      []string{"GAMEOPTION_NO_BARBARIANS"},
+		MapTiles:            mapTiles,
+		MapTileImprovements: improvements,
+		Units:               units,
+		CityData: []*Civ5CityData{
+			{Name: "Rome", Owner: 0, OwnerAdjusted: 0, Population: 3, Health: 100000, BuildingInfo: make([]byte, buildingDataSizeForVersion(version))},
+			{Name: "Monaco", Owner: CityStateOffset, OwnerAdjusted: 0, IsOccupied: true, Population: 1, Health: 50000, BuildingInfo: make([]byte, buildingDataSizeForVersion(version))},
+		},
+		Civ5PlayerData: []*Civ5PlayerData{
+			{Index: 0, LeaderName: "Augustus", CivName: "Roman Republic", CivType: "CIVILIZATION_ROME", TeamColor: "PLAYERCOLOR_ROME",
+				Era: "ERA_ANCIENT", Handicap: "HANDICAP_PRINCE", Culture: 10, Gold: 100, StartPositionX: 0, StartPositionY: 0,
+				Team: 0, Playable: true, Policies: []string{"POLICY_LIBERTY"}},
+			{Index: 1, CivType: "MINOR_CIV_MONACO", TeamColor: "PLAYERCOLOR_MINOR_WHITE", Era: "ERA_ANCIENT",
+				StartPositionX: 2, StartPositionY: 1, Team: 1, Policies: []string{}},
+		},
+		UnknownBlock: []byte{1, 2, 3, 4, 5},
+		Teams: []*Civ5TeamData{
+			{Index: 0, Name: "Romans", PlayerIndices: []int{0}},
+			{Index: 1, Name: "Team 2", PlayerIndices: []int{1}},
+		},
+		CityOwnerIndexMap: map[int]int{0: 0, CityStateOffset: 1},
+		CivColorOverrides: []CivColorOverride{},
+	}
+}
+
+// writeAndReadMapFile writes map data to a temporary .civ5map file and reads it back.
+func writeAndReadMapFile(t *testing.T, mapData *Civ5MapData) *Civ5MapData {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := WriteCiv5MapFile(mapData, &buf); err != nil {
+		t.Fatalf("WriteCiv5MapFile returned error: %v", err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "roundtrip.civ5map")
+	if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+
+	got, err := ReadCiv5MapFile(filename)
+	if err != nil {
+		t.Fatalf("ReadCiv5MapFile returned error: %v", err)
+	}
+	return got
+}
+
+func TestStringArrayToByteArrayRoundTrip(t *testing.T) {
+	list := []string{"foo", "", "bar"}
+	got := byteArrayToStringArray(stringArrayToByteArray(list))
+	if !reflect.DeepEqual(got, list) {
+		t.Errorf("byteArrayToStringArray(stringArrayToByteArray(%v)) = %v", list, got)
+	}
+	if encoded := stringArrayToByteArray(nil); len(encoded) != 0 {
+		t.Errorf("stringArrayToByteArray(nil) = %v, want empty", encoded)
+	}
+}
+
+func TestWriteCiv5MapFileRoundTrip(t *testing.T) {
+	for _, version := range []int{MapVersion11, MapVersion12} {
+		want := newWriterTestMapData(version)
+		got := writeAndReadMapFile(t, want)
+
+		if !reflect.DeepEqual(got.TerrainList, want.TerrainList) ||
+			!reflect.DeepEqual(got.FeatureTerrainList, want.FeatureTerrainList) ||
+			!reflect.DeepEqual(got.ResourceList, want.ResourceList) {
+			t.Errorf("v%d: string lists = %v %v %v, want %v %v %v", version,
+				got.TerrainList, got.FeatureTerrainList, got.ResourceList,
+				want.TerrainList, want.FeatureTerrainList, want.ResourceList)
+		}
+		if !reflect.DeepEqual(got.MapTiles, want.MapTiles) {
+			t.Errorf("v%d: MapTiles did not round trip", version)
+		}
+		if !reflect.DeepEqual(got.MapTileImprovements, want.MapTileImprovements) {
+			t.Errorf("v%d: MapTileImprovements did not round trip", version)
+		}
+		if !reflect.DeepEqual(got.Units, want.Units) {
+			t.Errorf("v%d: Units = %+v, want %+v", version, got.Units, want.Units)
+		}
+		if !reflect.DeepEqual(got.CityData, want.CityData) {
+			t.Errorf("v%d: CityData = %+v, want %+v", version, got.CityData, want.CityData)
+		}
+		if !reflect.DeepEqual(got.Civ5PlayerData, want.Civ5PlayerData) {
+			t.Errorf("v%d: Civ5PlayerData = %+v, want %+v", version, got.Civ5PlayerData, want.Civ5PlayerData)
+		}
+		if !reflect.DeepEqual(got.Teams, want.Teams) {
+			t.Errorf("v%d: Teams = %+v, want %+v", version, got.Teams, want.Teams)
+		}
+		if !bytes.Equal(got.UnknownBlock, want.UnknownBlock) {
+			t.Errorf("v%d: UnknownBlock = %v, want %v", version, got.UnknownBlock, want.UnknownBlock)
+		}
+		if !reflect.DeepEqual(got.CityOwnerIndexMap, want.CityOwnerIndexMap) {
+			t.Errorf("v%d: CityOwnerIndexMap = %v, want %v", version, got.CityOwnerIndexMap, want.CityOwnerIndexMap)
+		}
+		if got.GameDescriptionHeader.MaxTurns != 500 || got.GameDescriptionHeader.StartYear != -4000 {
+			t.Errorf("v%d: GameDescriptionHeader = %+v, want MaxTurns 500 and StartYear -4000", version, got.GameDescriptionHeader)
+		}
+		if got.MapHeader.Width != 3 || got.MapHeader.Height != 2 || got.MapHeader.ScenarioVersion != uint8(version) {
+			t.Errorf("v%d: MapHeader = %+v, want 3x2 map with version %d", version, got.MapHeader, version)
+		}
+	}
+}
+
+func TestWriteCiv5MapFileKeepsMetadataAndLists(t *testing.T) {
+	want := newWriterTestMapData(MapVersion12)
+	want.ModData = "{mod-id}\x00"
+	got := writeAndReadMapFile(t, want)
+
+	if got.MapName != want.MapName || got.MapDescription != want.MapDescription ||
+		got.WorldSize != want.WorldSize || got.ModData != want.ModData {
+		t.Errorf("metadata = %q %q %q %q, want %q %q %q %q",
+			got.MapName, got.MapDescription, got.WorldSize, got.ModData,
+			want.MapName, want.MapDescription, want.WorldSize, want.ModData)
+	}
+
+	lists := []struct {
+		name      string
+		got, want []string
+	}{
+		{"FeatureWonderList", got.FeatureWonderList, want.FeatureWonderList},
+		{"TileImprovementList", got.TileImprovementList, want.TileImprovementList},
+		{"UnitTypeList", got.UnitTypeList, want.UnitTypeList},
+		{"TechList", got.TechList, want.TechList},
+		{"PolicyList", got.PolicyList, want.PolicyList},
+		{"BuildingList", got.BuildingList, want.BuildingList},
+		{"PromotionList", got.PromotionList, want.PromotionList},
+		{"VictoryList", got.VictoryList, want.VictoryList},
+		{"GameOptionList", got.GameOptionList, want.GameOptionList},
+	}
+	for _, list := range lists {
+		if !reflect.DeepEqual(list.got, list.want) {
+			t.Errorf("%s = %v, want %v", list.name, list.got, list.want)
+		}
+	}
+}
+
+func TestWriteCiv5MapFileDefaultTeams(t *testing.T) {
+	// Older json exports have no team data, so every player gets a default team.
+	mapData := newWriterTestMapData(MapVersion12)
+	mapData.Teams = nil
+	mapData.UnknownBlock = nil
+
+	got := writeAndReadMapFile(t, mapData)
+	if len(got.Teams) != 2 || got.Teams[0].Name != "Team 1" || got.Teams[1].Name != "Team 2" {
+		t.Fatalf("Teams = %+v, want default Team 1 and Team 2", got.Teams)
+	}
+	if len(got.UnknownBlock) != 0 {
+		t.Errorf("UnknownBlock = %v, want empty", got.UnknownBlock)
+	}
+}
+
+func TestWriteCiv5MapFileUnitStatusFlags(t *testing.T) {
+	// The status flags take precedence over the raw status bits when writing.
+	mapData := newWriterTestMapData(MapVersion12)
+	mapData.Units[0].IsFortified = false
+	mapData.Units[0].IsEmbarked = true
+
+	got := writeAndReadMapFile(t, mapData).Units[0]
+	if got.IsFortified || !got.IsEmbarked || got.IsGarrisoned || got.Status != 1<<UnitEmbarkedFlag {
+		t.Errorf("unit status = %d (fortified %t, embarked %t, garrisoned %t), want only embarked",
+			got.Status, got.IsFortified, got.IsEmbarked, got.IsGarrisoned)
+	}
+}
+
+func TestWriteCiv5MapFileIsStable(t *testing.T) {
+	// Writing the data that was read back must produce exactly the same bytes.
+	mapData := newWriterTestMapData(MapVersion12)
+	var first bytes.Buffer
+	if err := WriteCiv5MapFile(mapData, &first); err != nil {
+		t.Fatalf("WriteCiv5MapFile returned error: %v", err)
+	}
+
+	var second bytes.Buffer
+	if err := WriteCiv5MapFile(writeAndReadMapFile(t, mapData), &second); err != nil {
+		t.Fatalf("WriteCiv5MapFile returned error: %v", err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Errorf("second write produced %d bytes, want the same %d bytes as the first", second.Len(), first.Len())
+	}
+}
+
+func TestWriteCiv5MapFilePhysicalOnly(t *testing.T) {
+	want := newWriterTestMapData(MapVersion12)
+	want.MapTileImprovements = [][]*Civ5MapTileImprovement{}
+
+	got := writeAndReadMapFile(t, want)
+	if !reflect.DeepEqual(got.MapTiles, want.MapTiles) {
+		t.Errorf("MapTiles did not round trip")
+	}
+	if got.MapName != want.MapName || !reflect.DeepEqual(got.FeatureWonderList, want.FeatureWonderList) {
+		t.Errorf("MapName = %q, FeatureWonderList = %v, want %q and %v",
+			got.MapName, got.FeatureWonderList, want.MapName, want.FeatureWonderList)
+	}
+	if len(got.MapTileImprovements) != 0 || len(got.Civ5PlayerData) != 0 {
+		t.Errorf("physical-only map read back with game data: %d improvement rows, %d players",
+			len(got.MapTileImprovements), len(got.Civ5PlayerData))
+	}
+}
+
+func TestWriteCiv5MapFileRebuildsCitiesFromTiles(t *testing.T) {
+	// Older json exports carry city names on the tiles but have no CityData.
+	mapData := newWriterTestMapData(MapVersion12)
+	mapData.CityData = nil
+	mapData.GameDescriptionHeader = Civ5GameDescriptionHeader{}
+
+	got := writeAndReadMapFile(t, mapData)
+	if len(got.CityData) != 2 {
+		t.Fatalf("CityData has %d cities, want 2", len(got.CityData))
+	}
+	if got.CityData[0].Name != "Rome" || got.CityData[1].Name != "Monaco" || got.CityData[1].Owner != CityStateOffset {
+		t.Errorf("CityData = %+v %+v, want Rome and Monaco owned by the city state", got.CityData[0], got.CityData[1])
+	}
+	if got.GameDescriptionHeader.PlayerCount != 1 || got.GameDescriptionHeader.CityStateCount != 1 {
+		t.Errorf("player counts = %d/%d, want 1/1 derived from CityOwnerIndexMap",
+			got.GameDescriptionHeader.PlayerCount, got.GameDescriptionHeader.CityStateCount)
+	}
+	if got.MapTileImprovements[1][2].CityName != "Monaco" {
+		t.Errorf("tile city name = %q, want Monaco", got.MapTileImprovements[1][2].CityName)
+	}
+}
+
+func TestWriteCiv5MapFileErrors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCiv5MapFile(nil, &buf); err == nil {
+		t.Error("WriteCiv5MapFile(nil) = nil error, want an error")
+	}
+	if err := WriteCiv5MapFile(&Civ5MapData{}, &buf); err == nil {
+		t.Error("WriteCiv5MapFile(no tiles) = nil error, want an error")
+	}
+
+	mapData := newWriterTestMapData(MapVersion12)
+	mapData.MapTileImprovements = mapData.MapTileImprovements[:1]
+	if err := WriteCiv5MapFile(mapData, &buf); err == nil {
+		t.Error("WriteCiv5MapFile(mismatched tile properties) = nil error, want an error")
+	}
+}
+
+func TestParseCiv5MapFromMemory(t *testing.T) {
+	want := newWriterTestMapData(MapVersion12)
+	var buf bytes.Buffer
+	if err := WriteCiv5MapFile(want, &buf); err != nil {
+		t.Fatalf("WriteCiv5MapFile returned error: %v", err)
+	}
+
+	got, err := ParseCiv5Map(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("ParseCiv5Map returned error: %v", err)
+	}
+	if !reflect.DeepEqual(got, writeAndReadMapFile(t, want)) {
+		t.Errorf("ParseCiv5Map() doesn't match ReadCiv5MapFile() for the same bytes")
+	}
+}
+
+func TestParseCiv5MapCityCountErrorOffset(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCiv5MapFile(newWriterTestMapData(MapVersion12), &buf); err != nil {
+		t.Fatalf("WriteCiv5MapFile returned error: %v", err)
+	}
+	data := buf.Bytes()
+
+	// Find the city section by reading up to it
+	reader := io.NewSectionReader(bytes.NewReader(data), 0, int64(len(data)))
+	header := Civ5MapHeader{}
+	if err := readStruct(reader, &header, "map header"); err != nil {
+		t.Fatalf("failed to read map header: %v", err)
+	}
+	layout := civ5MapLayouts[MapVersion12]
+	if _, _, _, _, err := readTerrainTypeLists(reader, &header); err != nil {
+		t.Fatalf("failed to read terrain lists: %v", err)
+	}
+	if _, err := readMapMetadata(reader, &header, layout); err != nil {
+		t.Fatalf("failed to read map metadata: %v", err)
+	}
+	if _, err := parsePhysicalMapTiles(reader, &header); err != nil {
+		t.Fatalf("failed to read map tiles: %v", err)
+	}
+	gameDescription, err := readGameDescriptionSection(reader, layout)
+	if err != nil {
+		t.Fatalf("failed to read game description: %v", err)
+	}
+
+	countOffset := gameDescription.CityDataOffset
+	binary.LittleEndian.PutUint32(data[countOffset:], 1000)
+	_, err = ParseCiv5Map(bytes.NewReader(data), int64(len(data)))
+	var implausible *ErrImplausibleLength
+	if !errors.As(err, &implausible) || implausible.Section != "city count" || implausible.Offset != countOffset {
+		t.Errorf("ParseCiv5Map() error = %v, want an implausible city count at offset 0x%X", err, countOffset)
+	}
+}
+
+func TestParseCiv5MapUnsupportedVersion(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCiv5MapFile(newWriterTestMapData(MapVersion12), &buf); err != nil {
+		t.Fatalf("WriteCiv5MapFile returned error: %v", err)
+	}
+
+	for _, version := range []int{5, 10, 13} {
+		data := append([]byte{}, buf.Bytes()...)
+		data[0] = data[0]&^VersionMask | uint8(version)
+
+		_, err := ParseCiv5Map(bytes.NewReader(data), int64(len(data)))
+		var unsupported *ErrUnsupportedMapVersion
+		if !errors.As(err, &unsupported) || unsupported.Version != version {
+			t.Errorf("ParseCiv5Map(version %d) error = %v, want unsupported map version %d", version, err, version)
+		}
+
+		mapData := newWriterTestMapData(version)
+		if err := WriteCiv5MapFile(mapData, &bytes.Buffer{}); !errors.As(err, &unsupported) {
+			t.Errorf("WriteCiv5MapFile(version %d) error = %v, want unsupported map version", version, err)
+		}
+	}
+}
@@ -1,12 +1,58 @@
 package fileio
 
 import (
-	"encoding/binary"
 	"fmt"
 	"io"
-	"os"
+	"strings"
 )
 
+// Replay event type ids, as encoded in Civ5ReplayEvent.TypeId
+const (
+	ReplayEventCityFounded     = 1
+	ReplayEventTilesClaimed    = 2
+	ReplayEventCityTransferred = 3
+	ReplayEventTilesRazed      = 4
+)
+
+// Plot types stored in the replay tile array
+const (
+	ReplayPlotMountain = 0
+	ReplayPlotHills    = 1
+	ReplayPlotLand     = 2
+	ReplayPlotOcean    = 3
+)
+
+// ReplayTerrainList and ReplayFeatureList are the base game terrain and feature types, in the
+// order of their ids in the game database. Replay tiles store these ids instead of indices into
+// a per-file list like .civ5map files do.
+var (
+	ReplayTerrainList = []string{
+		"TERRAIN_GRASS", "TERRAIN_PLAINS", "TERRAIN_DESERT", "TERRAIN_TUNDRA", "TERRAIN_SNOW",
+		"TERRAIN_COAST", "TERRAIN_OCEAN", "TERRAIN_MOUNTAIN", "TERRAIN_HILL",
+	}
+	ReplayFeatureList = []string{
+		"FEATURE_ICE", "FEATURE_JUNGLE", "FEATURE_MARSH", "FEATURE_OASIS", "FEATURE_FLOOD_PLAINS",
+		"FEATURE_FOREST", "FEATURE_FALLOUT", "FEATURE_CRATER", "FEATURE_FUJI", "FEATURE_MESA",
+		"FEATURE_REEF", "FEATURE_VOLCANO", "FEATURE_GIBRALTAR", "FEATURE_GEYSER",
+		"FEATURE_FOUNTAIN_YOUTH", "FEATURE_POTOSI", "FEATURE_EL_DORADO", "FEATURE_SRI_PADA",
+		"FEATURE_MT_SINAI", "FEATURE_MT_KAILASH", "FEATURE_ULURU", "FEATURE_LAKE_VICTORIA",
+		"FEATURE_KILIMANJARO", "FEATURE_SOLOMONS_MINES",
+	}
+)
+
+// ReplayTileSize is the size of one entry in the replay tile array
+const ReplayTileSize = 12
+
+// Civ5ReplayTile is one entry of the replay tile array
+type Civ5ReplayTile struct {
+	Unknown1    uint32
+	Unknown2    uint32
+	PlotType    uint8
+	TerrainType uint8
+	FeatureType uint8 // 0xFF if none
+	Unknown3    uint8
+}
+
 type Civ5ReplayFileConfigEntry struct {
 	VariableType string
 	VariableName string
@@ -43,9 +89,46 @@ type Civ5ReplayDataEntry struct {
 	Value int
 }
 
+// Civ5DLC is a downloadable content package enabled for a game
+type Civ5DLC struct {
+	Id      string // Package GUID as 32 hex digits
+	Enabled bool
+	Name    string
+}
+
+// Civ5Mod is a mod enabled for a game
+type Civ5Mod struct {
+	Id      string
+	Version int
+	Name    string
+}
+
+// Civ5ReplayMetadata holds the game settings stored in the replay header
+type Civ5ReplayMetadata struct {
+	GameVersion string
+	GameBuild   string
+	CurrentTurn int
+	Difficulty  string
+	StartEra    string
+	EndEra      string
+	GameSpeed   string
+	WorldSize   string
+	MapFilename string
+	DLC         []Civ5DLC
+	Mods        []Civ5Mod
+	CivName     string
+	LeaderName  string
+	PlayerColor string
+	StartTurn   int
+	StartYear   int // Negative for BC
+	EndTurn     int
+	EndYear     string
+}
+
 type Civ5ReplayData struct {
 	PlayerCiv       string
 	IsReplayFile    bool
+	Metadata        Civ5ReplayMetadata
 	AllCivs         []Civ5ReplayCiv
 	AllReplayEvents []Civ5ReplayEvent
 	DatasetNames    []string
@@ -55,83 +138,89 @@ type Civ5ReplayData struct {
 	// converted from a .civ5save file, which doesn't carry this information).
 	MapWidth  int
 	MapHeight int
+	// MapTiles holds the physical map stored at the end of the .civ5replay file, with terrain
+	// and feature types indexing ReplayTerrainList and ReplayFeatureList. It is empty when the
+	// replay doesn't carry a tile for every plot.
+	MapTiles [][]*Civ5MapTilePhysical
 }
 
-func readCivs(reader *io.SectionReader) []Civ5ReplayCiv {
-	civsLength := unsafeReadUint32(reader)
+func readCivs(reader *io.SectionReader) ([]Civ5ReplayCiv, error) {
+	civsLength, err := readCount(reader, "civs")
+	if err != nil {
+		return nil, err
+	}
 	allCivs := make([]Civ5ReplayCiv, 0)
 
-	for i := 0; i < int(civsLength); i++ {
-		unknownVariable1 := unsafeReadUint32(reader)
-		unknownVariable2 := unsafeReadUint32(reader)
-		unknownVariable3 := unsafeReadUint32(reader)
-		unknownVariable4 := unsafeReadUint32(reader)
-		leader, err := readVarString(reader, "leader")
-		if err != nil {
-			panic(fmt.Sprintf("failed to read leader: %v", err))
-		}
-		longName, err := readVarString(reader, "longName")
-		if err != nil {
-			panic(fmt.Sprintf("failed to read longName: %v", err))
+	for i := 0; i < civsLength; i++ {
+		var unknownVariables [4]uint32
+		if err := readStruct(reader, &unknownVariables, "civ unknown variables"); err != nil {
+			return nil, err
 		}
-		name, err := readVarString(reader, "name")
-		if err != nil {
-			panic(fmt.Sprintf("failed to read name: %v", err))
-		}
-		demonym, err := readVarString(reader, "demonym")
-		if err != nil {
-			panic(fmt.Sprintf("failed to read demonym: %v", err))
-		}
-
 		civData := Civ5ReplayCiv{
-			UnknownVariables: [4]int{int(unknownVariable1), int(unknownVariable2), int(unknownVariable3), int(unknownVariable4)},
-			Leader:           leader,
-			LongName:         longName,
-			Name:             name,
-			Demonym:          demonym,
+			UnknownVariables: [4]int{int(unknownVariables[0]), int(unknownVariables[1]), int(unknownVariables[2]), int(unknownVariables[3])},
+		}
+		if err := readVarStrings(reader, []varStringField{
+			{&civData.Leader, "leader"},
+			{&civData.LongName, "longName"},
+			{&civData.Name, "name"},
+			{&civData.Demonym, "demonym"},
+		}); err != nil {
+			return nil, err
 		}
 		allCivs = append(allCivs, civData)
 	}
 
-	return allCivs
+	return allCivs, nil
 }
 
-func readEvents(reader *io.SectionReader) []Civ5ReplayEvent {
-	eventsLength := unsafeReadUint32(reader)
+func readEvents(reader *io.SectionReader) ([]Civ5ReplayEvent, error) {
+	eventsLength, err := readCount(reader, "events")
+	if err != nil {
+		return nil, err
+	}
 	allReplayEvents := make([]Civ5ReplayEvent, eventsLength)
 
-	for i := 0; i < int(eventsLength); i++ {
-		turn := unsafeReadUint32(reader)
-		typeId := unsafeReadUint32(reader)
+	for i := 0; i < eventsLength; i++ {
+		var eventHeader [2]uint32
+		if err := readStruct(reader, &eventHeader, "event turn and type"); err != nil {
+			return nil, err
+		}
 
-		numTiles := unsafeReadUint32(reader)
+		numTiles, err := readCount(reader, "event tiles")
+		if err != nil {
+			return nil, err
+		}
+		tiles := make([][2]uint16, numTiles)
+		if err := readStruct(reader, tiles, "event tiles"); err != nil {
+			return nil, err
+		}
 		tileData := make([]Civ5ReplayEventTile, numTiles)
-		for i := 0; i < int(numTiles); i++ {
-			tileX := unsafeReadUint16(reader)
-			tileY := unsafeReadUint16(reader)
-
-			tileData[i] = Civ5ReplayEventTile{
-				X: int(tileX),
-				Y: int(tileY),
+		for j, tile := range tiles {
+			tileData[j] = Civ5ReplayEventTile{
+				X: int(tile[0]),
+				Y: int(tile[1]),
 			}
 		}
 
-		civId := int32(unsafeReadUint32(reader))
+		civId, err := readInt32(reader, "event civ id")
+		if err != nil {
+			return nil, err
+		}
 		eventText, err := readVarString(reader, "eventText")
 		if err != nil {
-			panic(fmt.Sprintf("failed to read eventText: %v", err))
+			return nil, err
 		}
 
 		allReplayEvents[i] = Civ5ReplayEvent{
-			Turn:   int(turn),
-			TypeId: int(typeId),
+			Turn:   int(eventHeader[0]),
+			TypeId: int(eventHeader[1]),
 			Tiles:  tileData,
 			CivId:  int(civId),
 			Text:   eventText,
 		}
 	}
 
-	return allReplayEvents
+	return allReplayEvents, nil
 }
 
 func GroupEventsByTurn(replayEvents []Civ5ReplayEvent) map[int][]Civ5ReplayEvent {
@@ -148,37 +237,39 @@ func GroupEventsByTurn(replayEvents []Civ5ReplayEvent) map[int][]Civ5ReplayEvent
 	return replayTurns
 }
 
-func readDatasetNames(streamReader *io.SectionReader) []string {
-	datasetLength := unsafeReadUint32(streamReader)
-	datasetNames := make([]string, int(datasetLength))
-	for i := 0; i < int(datasetLength); i++ {
-		name, err := readVarString(streamReader, "datasetNames")
-		if err != nil {
-			panic(fmt.Sprintf("failed to read dataset name %d: %v", i, err))
-		}
-		datasetNames[i] = name
-	}
-	return datasetNames
+func readDatasetNames(streamReader *io.SectionReader) ([]string, error) {
+	return readVarStringArray(streamReader, "datasetNames")
 }
 
-func readDatasetValues(streamReader *io.SectionReader) [][][]Civ5ReplayDataEntry {
-	datasetValuesArray1Length := unsafeReadUint32(streamReader)
-	datasetByCiv := make([][][]Civ5ReplayDataEntry, int(datasetValuesArray1Length))
+func readDatasetValues(streamReader *io.SectionReader) ([][][]Civ5ReplayDataEntry, error) {
+	datasetValuesArray1Length, err := readCount(streamReader, "dataset civs")
+	if err != nil {
+		return nil, err
+	}
+	datasetByCiv := make([][][]Civ5ReplayDataEntry, datasetValuesArray1Length)
 
-	for i := 0; i < int(datasetValuesArray1Length); i++ {
-		datasetValuesArray2Length := unsafeReadUint32(streamReader)
-		datasetByCategory := make([][]Civ5ReplayDataEntry, int(datasetValuesArray2Length))
+	for i := 0; i < datasetValuesArray1Length; i++ {
+		datasetValuesArray2Length, err := readCount(streamReader, "dataset categories")
+		if err != nil {
+			return nil, err
+		}
+		datasetByCategory := make([][]Civ5ReplayDataEntry, datasetValuesArray2Length)
 
-		for j := 0; j < int(datasetValuesArray2Length); j++ {
-			numDatasetValues := unsafeReadUint32(streamReader)
+		for j := 0; j < datasetValuesArray2Length; j++ {
+			numDatasetValues, err := readCount(streamReader, "dataset values")
+			if err != nil {
+				return nil, err
+			}
 
+			values := make([][2]uint32, numDatasetValues)
+			if err := readStruct(streamReader, values, "dataset values"); err != nil {
+				return nil, err
+			}
 			datasetArray := make([]Civ5ReplayDataEntry, numDatasetValues)
-			for k := 0; k < int(numDatasetValues); k++ {
-				turn := unsafeReadUint32(streamReader)
-				value := unsafeReadUint32(streamReader)
+			for k, value := range values {
 				datasetArray[k] = Civ5ReplayDataEntry{
-					Turn:  int(turn),
-					Value: int(value),
+					Turn:  int(value[0]),
+					Value: int(value[1]),
 				}
 			}
 
@@ -186,16 +277,20 @@ func readDatasetValues(streamReader *io.SectionReader) [][][]Civ5ReplayDataEntry
 		}
 		datasetByCiv[i] = datasetByCategory
 	}
-	return datasetByCiv
+	return datasetByCiv, nil
 }
 
-func buildCivDatasetValues(streamReader *io.SectionReader, datasetNames []string) []Civ5ReplayCivDataset {
-	datasetValues := readDatasetValues(streamReader)
+func buildCivDatasetValues(streamReader *io.SectionReader, datasetNames []string) ([]Civ5ReplayCivDataset, error) {
+	datasetValues, err := readDatasetValues(streamReader)
+	if err != nil {
+		return nil, err
+	}
 
 	allCivDatasetValues := make([]Civ5ReplayCivDataset, len(datasetValues))
 	for civIndex := 0; civIndex < len(datasetValues); civIndex++ {
 		dataMap := make(map[string][]Civ5ReplayDataEntry, 0)
-		for datasetNameIndex := 0; datasetNameIndex < len(datasetNames); datasetNameIndex++ {
+		// Names without values for this civ are left out
+		for datasetNameIndex := 0; datasetNameIndex < len(datasetNames) && datasetNameIndex < len(datasetValues[civIndex]); datasetNameIndex++ {
 			datasetName := datasetNames[datasetNameIndex]
 			dataMap[datasetName] = datasetValues[civIndex][datasetNameIndex]
 		}
@@ -205,249 +300,468 @@ func buildCivDatasetValues(streamReader *io.SectionReader, datasetNames []string
 			DatasetValues: dataMap,
 		}
 	}
-	return allCivDatasetValues
+	return allCivDatasetValues, nil
 }
 
-func ReadCiv5ReplayFile(filename string) (*Civ5ReplayData, error) {
-	inputFile, err := os.Open(filename)
+// varStringField names the destination of a variable-length string read by readVarStrings
+type varStringField struct {
+	dest *string
+	name string
+}
+
+// readVarStrings reads consecutive variable-length strings into the given destinations
+func readVarStrings(reader *io.SectionReader, fields []varStringField) error {
+	for _, field := range fields {
+		value, err := readVarString(reader, field.name)
+		if err != nil {
+			return err
+		}
+		*field.dest = value
+	}
+	return nil
+}
+
+// readDLCList reads the list of DLC packages enabled for the game
+func readDLCList(reader *io.SectionReader) ([]Civ5DLC, error) {
+	count, err := readCount(reader, "dlc")
 	if err != nil {
-		return nil, fmt.Errorf("failed to load replay file %q: %w", filename, err)
+		return nil, err
 	}
-	defer inputFile.Close()
 
-	fi, err := inputFile.Stat()
+	dlcList := make([]Civ5DLC, count)
+	for i := range dlcList {
+		id, err := readByteArray(reader, 16, "dlcId")
+		if err != nil {
+			return nil, err
+		}
+		enabled, err := readUint32(reader, "dlcEnabled")
+		if err != nil {
+			return nil, err
+		}
+		name, err := readVarString(reader, "dlcName")
+		if err != nil {
+			return nil, err
+		}
+		dlcList[i] = Civ5DLC{Id: fmt.Sprintf("%x", id), Enabled: enabled != 0, Name: name}
+	}
+	return dlcList, nil
+}
+
+// readModList reads the list of mods enabled for the game
+func readModList(reader *io.SectionReader) ([]Civ5Mod, error) {
+	count, err := readCount(reader, "mods")
 	if err != nil {
-		return nil, fmt.Errorf("failed to get file info for %q: %w", filename, err)
+		return nil, err
 	}
-	fileLength := fi.Size()
-	streamReader := io.NewSectionReader(inputFile, int64(0), fileLength)
-	fmt.Println("Loading Civ5Replay...")
 
-	_, err = readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
-		{
-			VariableType: "bytearray:4",
-			VariableName: "gameName",
-		},
-		{
-			VariableType: "uint32",
-			VariableName: "unknownBlock1",
-		},
-		{
-			VariableType: "varstring",
-			VariableName: "gameVersion",
-		},
-		{
-			VariableType: "varstring",
-			VariableName: "gameBuild",
-		},
-		{
-			VariableType: "uint32",
-			VariableName: "currentTurnNumber",
-		},
-		{
-			VariableType: "bytearray:1",
-			VariableName: "unknownBlock2",
-		},
-	})
+	modList := make([]Civ5Mod, count)
+	for i := range modList {
+		id, err := readVarString(reader, "modId")
+		if err != nil {
+			return nil, err
+		}
+		version, err := readUint32(reader, "modVersion")
+		if err != nil {
+			return nil, err
+		}
+		name, err := readVarString(reader, "modName")
+		if err != nil {
+			return nil, err
+		}
+		modList[i] = Civ5Mod{Id: id, Version: int(version), Name: name}
+	}
+	return modList, nil
+}
+
+// readReplayHeader reads the replay header up to the unknown block, returning the game settings
+// and the player civ
+func readReplayHeader(reader *io.SectionReader) (Civ5ReplayMetadata, string, error) {
+	metadata := Civ5ReplayMetadata{}
+
+	// Skip the game name and unknownBlock1
+	if _, err := readByteArray(reader, 8, "gameName"); err != nil {
+		return metadata, "", fmt.Errorf("failed to read game name: %w", err)
+	}
+	if err := readVarStrings(reader, []varStringField{
+		{&metadata.GameVersion, "gameVersion"},
+		{&metadata.GameBuild, "gameBuild"},
+	}); err != nil {
+		return metadata, "", err
+	}
+
+	currentTurn, err := readUint32(reader, "currentTurnNumber")
 	if err != nil {
-		return nil, fmt.Errorf("failed to read initial file config: %w", err)
+		return metadata, "", fmt.Errorf("failed to read current turn: %w", err)
 	}
+	metadata.CurrentTurn = int(currentTurn)
 
-	playerCiv, err := readVarString(streamReader, "playerCiv")
+	// Skip unknownBlock2
+	if _, err := readByteArray(reader, 1, "unknownBlock2"); err != nil {
+		return metadata, "", fmt.Errorf("failed to read unknown block: %w", err)
+	}
+
+	playerCiv, err := readVarString(reader, "playerCiv")
 	if err != nil {
-		return nil, fmt.Errorf("failed to read player civ: %w", err)
-	}
-
-	_, err = readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
-		{
-			VariableType: "varstring",
-			VariableName: "difficulty",
-		},
-		{
-			VariableType: "varstring",
-			VariableName: "eraStart",
-		},
-		{
-			VariableType: "varstring",
-			VariableName: "eraEnd",
-		},
-		{
-			VariableType: "varstring",
-			VariableName: "gameSpeed",
-		},
-		{
-			VariableType: "varstring",
-			VariableName: "worldSize",
-		},
-		{
-			VariableType: "varstring",
-			VariableName: "mapFilename",
-		},
-	})
-
-	readArray(streamReader, "dlc", []Civ5ReplayFileConfigEntry{
-		{
-			VariableType: "bytearray:16",
-			VariableName: "dlcId",
-		},
-		{
-			VariableType: "bytearray:4",
-			VariableName: "dlcEnabled",
-		},
-		{
-			VariableType: "varstring",
-			VariableName: "dlcName",
-		},
-	})
-
-	readArray(streamReader, "mods", []Civ5ReplayFileConfigEntry{
-		{
-			VariableType: "varstring",
-			VariableName: "modId",
-		},
-		{
-			VariableType: "bytearray:4",
-			VariableName: "modVersion",
-		},
-		{
-			VariableType: "varstring",
-			VariableName: "modName",
-		},
-	})
-
-	_, err = readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
-		{
-			VariableType: "varstring",
-			VariableName: "civName",
-		},
-		{
-			VariableType: "varstring",
-			VariableName: "leaderName",
-		},
-		{
-			VariableType: "varstring",
-			VariableName: "playerColor",
-		},
-		{
-			VariableType: "bytearray:8",
-			VariableName: "unknownBlock5",
-		},
-		{
-			VariableType: "varstring",
-			VariableName: "mapFilename2",
-		},
-	})
-
-	// This block doesn't seem to have a pattern
-	unknownVersion := unsafeReadUint32(streamReader)
-	unknownArr := make([]int, 0)
-	fmt.Println("Unknown version:", unknownVersion)
-	unknownArr = append(unknownArr, int(unknownVersion))
-	for i := 0; i < 4; i++ {
-		value := unsafeReadUint32(streamReader)
-		unknownArr = append(unknownArr, int(value))
+		return metadata, "", fmt.Errorf("failed to read player civ: %w", err)
+	}
+
+	if err := readVarStrings(reader, []varStringField{
+		{&metadata.Difficulty, "difficulty"},
+		{&metadata.StartEra, "eraStart"},
+		{&metadata.EndEra, "eraEnd"},
+		{&metadata.GameSpeed, "gameSpeed"},
+		{&metadata.WorldSize, "worldSize"},
+		{&metadata.MapFilename, "mapFilename"},
+	}); err != nil {
+		return metadata, "", err
 	}
 
-	unknownCount := unsafeReadUint32(streamReader)
-	unknownArr = append(unknownArr, int(unknownCount))
+	if metadata.DLC, err = readDLCList(reader); err != nil {
+		return metadata, "", err
+	}
+	if metadata.Mods, err = readModList(reader); err != nil {
+		return metadata, "", err
+	}
 
-	for i := 0; i < int(unknownCount); i++ {
-		value := unsafeReadUint32(streamReader)
+	if err := readVarStrings(reader, []varStringField{
+		{&metadata.CivName, "civName"},
+		{&metadata.LeaderName, "leaderName"},
+		{&metadata.PlayerColor, "playerColor"},
+	}); err != nil {
+		return metadata, "", err
+	}
+
+	// Skip unknownBlock5 and the second copy of the map filename
+	if _, err := readByteArray(reader, 8, "unknownBlock5"); err != nil {
+		return metadata, "", fmt.Errorf("failed to read unknown block: %w", err)
+	}
+	if _, err := readVarString(reader, "mapFilename2"); err != nil {
+		return metadata, "", err
+	}
+
+	return metadata, playerCiv, nil
+}
+
+// readReplayTurnRange reads the start and end turn and year that follow the unknown block
+func readReplayTurnRange(reader *io.SectionReader, metadata *Civ5ReplayMetadata) error {
+	startTurn, err := readUint32(reader, "startTurn")
+	if err != nil {
+		return fmt.Errorf("failed to read start turn: %w", err)
+	}
+	// startYear can be negative, e.g. 4000 BC
+	startYear := int32(0)
+	if err := readStruct(reader, &startYear, "startYear"); err != nil {
+		return fmt.Errorf("failed to read start year: %w", err)
+	}
+	endTurn, err := readUint32(reader, "endTurn")
+	if err != nil {
+		return fmt.Errorf("failed to read end turn: %w", err)
+	}
+	endYear, err := readVarString(reader, "endYear")
+	if err != nil {
+		return err
+	}
+	// Skip zeroStartYear and zeroEndYear
+	if _, err := readByteArray(reader, 8, "zeroYears"); err != nil {
+		return fmt.Errorf("failed to read zero years: %w", err)
+	}
+
+	metadata.StartTurn = int(startTurn)
+	metadata.StartYear = int(startYear)
+	metadata.EndTurn = int(endTurn)
+	metadata.EndYear = endYear
+	return nil
+}
+
+// reportReplayMetadata prints a human-readable summary of the replay settings
+func reportReplayMetadata(metadata *Civ5ReplayMetadata) {
+	fmt.Println("\n=== Replay Metadata ===")
+	fmt.Printf("Game Version: %s (build %s)\n", metadata.GameVersion, metadata.GameBuild)
+	fmt.Printf("Leader: %s, Civ: %s, Color: %s\n", metadata.LeaderName, metadata.CivName, metadata.PlayerColor)
+	fmt.Printf("Difficulty: %s, Speed: %s, World Size: %s\n", metadata.Difficulty, metadata.GameSpeed, metadata.WorldSize)
+	fmt.Printf("Map: %s\n", metadata.MapFilename)
+	fmt.Printf("Eras: %s to %s\n", metadata.StartEra, metadata.EndEra)
+	fmt.Printf("Turns: %d to %d (current %d), start year %d, end year %s\n",
+		metadata.StartTurn, metadata.EndTurn, metadata.CurrentTurn, metadata.StartYear, metadata.EndYear)
+	fmt.Printf("DLC: %d, Mods: %d\n", len(metadata.DLC), len(metadata.Mods))
+}
+
+// plotTypeToElevation converts a replay plot type to the elevation used by map tiles
+func plotTypeToElevation(plotType uint8) int {
+	switch plotType {
+	case ReplayPlotMountain:
+		return ElevationMountain
+	case ReplayPlotHills:
+		return ElevationHills
+	default:
+		return ElevationFlat
+	}
+}
+
+// readReplayTiles reads the replay tile array into physical map tiles. The tiles are stored row
+// by row, so an array with exactly one tile per plot is returned as a height x width grid.
+// Otherwise the array is skipped and no tiles are returned.
+func readReplayTiles(reader *io.SectionReader, width, height int) ([][]*Civ5MapTilePhysical, error) {
+	count, err := readCount(reader, "tiles")
+	if err != nil {
+		return nil, err
+	}
+
+	tiles := make([]Civ5ReplayTile, count)
+	if err := readStruct(reader, tiles, "tiles"); err != nil {
+		return nil, err
+	}
+	if count != width*height || count == 0 {
+		fmt.Println("Replay has", count, "tiles, which doesn't match the", width, "x", height, "map, skipping tiles")
+		return [][]*Civ5MapTilePhysical{}, nil
+	}
+
+	mapTiles := make([][]*Civ5MapTilePhysical, height)
+	for i := 0; i < height; i++ {
+		mapTiles[i] = make([]*Civ5MapTilePhysical, width)
+		for j := 0; j < width; j++ {
+			tile := tiles[i*width+j]
+			mapTiles[i][j] = &Civ5MapTilePhysical{
+				X:                  j,
+				Y:                  i,
+				TerrainType:        int(tile.TerrainType),
+				ResourceType:       0xFF,
+				FeatureTerrainType: int(tile.FeatureType),
+				Elevation:          plotTypeToElevation(tile.PlotType),
+				FeatureWonderType:  0xFF,
+			}
+		}
+	}
+	return mapTiles, nil
+}
+
+// ApplyReplayEvent applies a single replay event's effect to the map tile improvements,
+// mutating mapData in place. nextCityId is the city id to assign if this event founds a
+// new city; the (possibly incremented) next available city id is returned so the caller
+// can thread it into the next call.
+func ApplyReplayEvent(mapData *Civ5MapData, event Civ5ReplayEvent, nextCityId int) int {
+	switch event.TypeId {
+	case ReplayEventCityFounded:
+		// Set city id
+		for _, tile := range event.Tiles {
+			mapData.MapTileImprovements[tile.Y][tile.X].CityId = nextCityId
+			mapData.MapTileImprovements[tile.Y][tile.X].CityName = strings.TrimSuffix(event.Text, " is founded.")
+			nextCityId += 1
+		}
+	case ReplayEventTilesClaimed, ReplayEventCityTransferred:
+		// Change owner to new civ id
+		for _, tile := range event.Tiles {
+			mapData.MapTileImprovements[tile.Y][tile.X].Owner = event.CivId
+		}
+	case ReplayEventTilesRazed:
+		for _, tile := range event.Tiles {
+			// Remove city from map
+			mapData.MapTileImprovements[tile.Y][tile.X].Owner = -1
+			mapData.MapTileImprovements[tile.Y][tile.X].CityId = -1
+			mapData.MapTileImprovements[tile.Y][tile.X].CityName = ""
+			// Set razed city tile to road
+			mapData.MapTileImprovements[tile.Y][tile.X].RouteType = 2
+		}
+	}
+	return nextCityId
+}
+
+// BuildPlayerDataFromReplayCivs builds one player per replay civ, in the same order so that
+// replay event civ ids index the result. Replays store civ type names, while the civ names
+// taken from save files are converted to the matching CIVILIZATION_ and PLAYERCOLOR_ types.
+func BuildPlayerDataFromReplayCivs(civs []Civ5ReplayCiv) []*Civ5PlayerData {
+	playerData := make([]*Civ5PlayerData, 0)
+	for i := 0; i < len(civs); i++ {
+		civName := civs[i].Name
+
+		if strings.Contains(civName, "CIVILIZATION") || strings.Contains(civName, "MINOR_CIV") {
+			playerData = append(playerData, &Civ5PlayerData{
+				Index:     i,
+				CivType:   civName,
+				TeamColor: civs[i].LongName,
+			})
+		} else {
+			civName = strings.ReplaceAll(civName, " ", "")
+			playerData = append(playerData, &Civ5PlayerData{
+				Index:     i,
+				CivType:   fmt.Sprintf("CIVILIZATION_%s", strings.ToUpper(civName)),
+				TeamColor: fmt.Sprintf("PLAYERCOLOR_%s", strings.ToUpper(civName)),
+			})
+		}
+	}
+	return playerData
+}
+
+// newEmptyTileImprovements returns a height x width grid of unowned tiles without a city,
+// unit, improvement or route
+func newEmptyTileImprovements(width, height int) [][]*Civ5MapTileImprovement {
+	improvements := make([][]*Civ5MapTileImprovement, height)
+	for i := 0; i < height; i++ {
+		improvements[i] = make([]*Civ5MapTileImprovement, width)
+		for j := 0; j < width; j++ {
+			improvements[i][j] = &Civ5MapTileImprovement{
+				X:           j,
+				Y:           i,
+				CityId:      InvalidCityId,
+				UnitId:      InvalidUnitId,
+				Owner:       -1,
+				Improvement: 0xFF,
+				RouteType:   0xFF,
+				RouteOwner:  0xFF,
+			}
+		}
+	}
+	return improvements
+}
+
+// BuildMapDataFromReplay builds map data from the tiles embedded in a replay, for drawing a
+// replay without its .civ5map. Every tile starts out unowned and without a city or route, and
+// the replay events fill in ownership as they are applied.
+func BuildMapDataFromReplay(replayData *Civ5ReplayData) (*Civ5MapData, error) {
+	if replayData == nil {
+		return nil, fmt.Errorf("replay data is nil")
+	}
+	if len(replayData.MapTiles) == 0 || len(replayData.MapTiles[0]) == 0 {
+		return nil, fmt.Errorf("replay has no tile data to build a map from")
+	}
+
+	height := len(replayData.MapTiles)
+	width := len(replayData.MapTiles[0])
+	improvements := newEmptyTileImprovements(width, height)
+
+	header := &Civ5MapHeader{ScenarioVersion: MapVersion12, Width: uint32(width), Height: uint32(height)}
+	mapData := buildMapData(header, &Civ5GameDescriptionHeader{}, ReplayTerrainList, ReplayFeatureList, []string{},
+		replayData.MapTiles, improvements, []*Civ5CityData{}, []*Civ5PlayerData{}, map[int]int{})
+	mapData.WorldSize = replayData.Metadata.WorldSize
+	return mapData, nil
+}
+
+// readReplayUnknownBlock reads the block between the header and the turn range. It doesn't
+// seem to have a pattern beyond its two counted arrays, so the values are only printed.
+func readReplayUnknownBlock(reader *io.SectionReader) ([]int, error) {
+	unknownArr := make([]int, 0)
+
+	var unknownVersion [5]uint32
+	if err := readStruct(reader, &unknownVersion, "unknown version"); err != nil {
+		return nil, err
+	}
+	fmt.Println("Unknown version:", unknownVersion[0])
+	for _, value := range unknownVersion {
 		unknownArr = append(unknownArr, int(value))
 	}
 
-	unknownCount2 := unsafeReadUint32(streamReader)
-	unknownArr = append(unknownArr, int(unknownCount2))
+	count1, err := readCount(reader, "unknown array 1")
+	if err != nil {
+		return nil, err
+	}
+	values1 := make([]uint32, count1)
+	if err := readStruct(reader, values1, "unknown array 1"); err != nil {
+		return nil, err
+	}
+
+	// The second array has one more value than its count
+	count2, err := readCount(reader, "unknown array 2")
+	if err != nil {
+		return nil, err
+	}
+	values2 := make([]uint32, count2+1)
+	if err := readStruct(reader, values2, "unknown array 2"); err != nil {
+		return nil, err
+	}
 
-	for i := 0; i < int(unknownCount2)+1; i++ {
-		value := unsafeReadUint32(streamReader)
+	unknownArr = append(unknownArr, count1)
+	for _, value := range values1 {
+		unknownArr = append(unknownArr, int(value))
+	}
+	unknownArr = append(unknownArr, count2)
+	for _, value := range values2 {
 		unknownArr = append(unknownArr, int(value))
 	}
 	fmt.Println("Unknown array:", unknownArr)
 
 	// Read one byte of padding
-	unknownBlock2 := [1]byte{}
-	if err := binary.Read(streamReader, binary.LittleEndian, &unknownBlock2); err != nil {
-		return nil, fmt.Errorf("failed to read padding block: %w", err)
-	}
-
-	_, err = readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
-		{
-			VariableType: "uint32",
-			VariableName: "startTurn",
-		},
-		{
-			VariableType: "int32", // startYear can be negative, e.g. 4000 BC
-			VariableName: "startYear",
-		},
-		{
-			VariableType: "uint32",
-			VariableName: "endTurn",
-		},
-		{
-			VariableType: "varstring",
-			VariableName: "endYear",
-		},
-		{
-			VariableType: "uint32",
-			VariableName: "zeroStartYear",
-		},
-		{
-			VariableType: "uint32",
-			VariableName: "zeroEndYear",
-		},
-	})
-
-	allCivs := readCivs(streamReader)
-
-	datasetNames := readDatasetNames(streamReader)
-	datasetValues := buildCivDatasetValues(streamReader, datasetNames)
+	if _, err := readByteArray(reader, 1, "padding block"); err != nil {
+		return nil, err
+	}
+	return unknownArr, nil
+}
+
+// ReadCiv5ReplayFile reads a .civ5replay file from disk. See ParseCiv5Replay.
+func ReadCiv5ReplayFile(filename string) (*Civ5ReplayData, error) {
+	inputFile, fileLength, err := openInputFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load replay file %q: %w", filename, err)
+	}
+	defer inputFile.Close()
+	return ParseCiv5Replay(inputFile, fileLength)
+}
+
+// ParseCiv5Replay parses the first size bytes of r as a .civ5replay file, entirely in memory
+func ParseCiv5Replay(r io.ReaderAt, size int64) (*Civ5ReplayData, error) {
+	streamReader := io.NewSectionReader(r, 0, size)
+	fmt.Println("Loading Civ5Replay...")
+
+	metadata, playerCiv, err := readReplayHeader(streamReader)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := readReplayUnknownBlock(streamReader); err != nil {
+		return nil, err
+	}
+
+	if err := readReplayTurnRange(streamReader, &metadata); err != nil {
+		return nil, err
+	}
+	reportReplayMetadata(&metadata)
+
+	allCivs, err := readCivs(streamReader)
+	if err != nil {
+		return nil, err
+	}
+
+	datasetNames, err := readDatasetNames(streamReader)
+	if err != nil {
+		return nil, err
+	}
+	datasetValues, err := buildCivDatasetValues(streamReader, datasetNames)
+	if err != nil {
+		return nil, err
+	}
 
 	// Read unknown value
-	_ = unsafeReadUint32(streamReader)
+	if _, err := readUint32(streamReader, "unknown value before events"); err != nil {
+		return nil, err
+	}
 
-	allReplayEvents := readEvents(streamReader)
+	allReplayEvents, err := readEvents(streamReader)
+	if err != nil {
+		return nil, err
+	}
 
-	mapWidth := unsafeReadUint32(streamReader)
-	mapHeight := unsafeReadUint32(streamReader)
+	var mapSize [2]uint32
+	if err := readStruct(streamReader, &mapSize, "map size"); err != nil {
+		return nil, err
+	}
+	mapWidth, mapHeight := mapSize[0], mapSize[1]
 	fmt.Println("Map width:", mapWidth, ", height:", mapHeight)
 
-	readArray(streamReader, "tiles", []Civ5ReplayFileConfigEntry{
-		{
-			VariableType: "uint32",
-			VariableName: "unknownVariable1",
-		},
-		{
-			VariableType: "uint32",
-			VariableName: "unknownVariable2",
-		},
-		{
-			VariableType: "uint8",
-			VariableName: "elevationId",
-		},
-		{
-			VariableType: "uint8",
-			VariableName: "typeId",
-		},
-		{
-			VariableType: "uint8",
-			VariableName: "featureId",
-		},
-		{
-			VariableType: "uint8",
-			VariableName: "unknownVariable3",
-		},
-	})
+	mapTiles, err := readReplayTiles(streamReader, int(mapWidth), int(mapHeight))
+	if err != nil {
+		return nil, err
+	}
 
 	replayData := Civ5ReplayData{
 		PlayerCiv:       playerCiv,
 		IsReplayFile:    true,
+		Metadata:        metadata,
 		AllCivs:         allCivs,
 		AllReplayEvents: allReplayEvents,
 		DatasetNames:    datasetNames,
 		DatasetValues:   datasetValues,
 		MapWidth:        int(mapWidth),
 		MapHeight:       int(mapHeight),
+		MapTiles:        mapTiles,
 	}
 
 	return &replayData, nil
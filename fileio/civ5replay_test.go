@@ -55,7 +55,10 @@ func TestReadCivs(t *testing.T) {
 	writeVarString(&buf, "Rome")
 	writeVarString(&buf, "Romans")
 
-	civs := readCivs(newSectionReader(buf.Bytes()))
+	civs, err := readCivs(newSectionReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("readCivs returned error: %v", err)
+	}
 
 	if len(civs) != 1 {
 		t.Fatalf("readCivs() returned %d civs, want 1", len(civs))
@@ -77,7 +80,10 @@ func TestReadCivsEmpty(t *testing.T) {
 	var buf bytes.Buffer
 	binary.Write(&buf, binary.LittleEndian, uint32(0))
 
-	civs := readCivs(newSectionReader(buf.Bytes()))
+	civs, err := readCivs(newSectionReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("readCivs returned error: %v", err)
+	}
 	if len(civs) != 0 {
 		t.Errorf("readCivs() returned %d civs, want 0", len(civs))
 	}
@@ -96,7 +102,10 @@ func TestReadEvents(t *testing.T) {
 	binary.Write(&buf, binary.LittleEndian, uint32(2)) // civId
 	writeVarString(&buf, "Rome is founded.")
 
-	events := readEvents(newSectionReader(buf.Bytes()))
+	events, err := readEvents(newSectionReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("readEvents returned error: %v", err)
+	}
 
 	if len(events) != 1 {
 		t.Fatalf("readEvents() returned %d events, want 1", len(events))
@@ -126,7 +135,10 @@ func TestReadEventsNegativeCivId(t *testing.T) {
 	binary.Write(&buf, binary.LittleEndian, int32(-1)) // civId, stored as signed
 	writeVarString(&buf, "")
 
-	events := readEvents(newSectionReader(buf.Bytes()))
+	events, err := readEvents(newSectionReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("readEvents returned error: %v", err)
+	}
 
 	if events[0].CivId != -1 {
 		t.Errorf("readEvents()[0].CivId = %d, want -1", events[0].CivId)
@@ -142,7 +154,10 @@ func TestReadDatasetNames(t *testing.T) {
 	writeVarString(&buf, "Score")
 	writeVarString(&buf, "Gold")
 
-	names := readDatasetNames(newSectionReader(buf.Bytes()))
+	names, err := readDatasetNames(newSectionReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("readDatasetNames returned error: %v", err)
+	}
 	want := []string{"Score", "Gold"}
 	if !reflect.DeepEqual(names, want) {
 		t.Errorf("readDatasetNames() = %v, want %v", names, want)
@@ -159,7 +174,10 @@ func TestReadDatasetValues(t *testing.T) {
 	binary.Write(&buf, binary.LittleEndian, uint32(2))   // turn
 	binary.Write(&buf, binary.LittleEndian, uint32(150)) // value
 
-	values := readDatasetValues(newSectionReader(buf.Bytes()))
+	values, err := readDatasetValues(newSectionReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("readDatasetValues returned error: %v", err)
+	}
 
 	if len(values) != 1 || len(values[0]) != 1 || len(values[0][0]) != 2 {
 		t.Fatalf("readDatasetValues() shape = %v, want [1][1][2]", values)
@@ -182,7 +200,10 @@ func TestBuildCivDatasetValues(t *testing.T) {
 	binary.Write(&buf, binary.LittleEndian, uint32(99)) // value
 
 	datasetNames := []string{"Score", "Gold"}
-	civDatasets := buildCivDatasetValues(newSectionReader(buf.Bytes()), datasetNames)
+	civDatasets, err := buildCivDatasetValues(newSectionReader(buf.Bytes()), datasetNames)
+	if err != nil {
+		t.Fatalf("buildCivDatasetValues returned error: %v", err)
+	}
 
 	if len(civDatasets) != 1 {
 		t.Fatalf("buildCivDatasetValues() returned %d civs, want 1", len(civDatasets))
@@ -199,3 +220,352 @@ func TestBuildCivDatasetValues(t *testing.T) {
 		t.Errorf("DatasetValues[Gold] = %v, want [{Turn:1 Value:99}]", goldValues)
 	}
 }
+
+func TestReadReplayHeaderAndTurnRange(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("CIV5")
+	binary.Write(&buf, binary.LittleEndian, uint32(0)) // unknownBlock1
+	writeVarString(&buf, "1.0.3.279")
+	writeVarString(&buf, "403694")
+	binary.Write(&buf, binary.LittleEndian, uint32(250)) // current turn
+	buf.WriteByte(0)                                     // unknownBlock2
+	writeVarString(&buf, "CIVILIZATION_ROME")
+	for _, s := range []string{"HANDICAP_PRINCE", "ERA_ANCIENT", "ERA_MODERN", "GAMESPEED_STANDARD", "WORLDSIZE_SMALL", "Assets\\Maps\\Continents.lua"} {
+		writeVarString(&buf, s)
+	}
+	binary.Write(&buf, binary.LittleEndian, uint32(1)) // dlc count
+	buf.Write([]byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xAB, 0xCD, 0xEF, 0, 0, 0, 0, 0, 0, 0, 0x10})
+	binary.Write(&buf, binary.LittleEndian, uint32(1))
+	writeVarString(&buf, "Expansion2")
+	binary.Write(&buf, binary.LittleEndian, uint32(1)) // mod count
+	writeVarString(&buf, "mod-guid")
+	binary.Write(&buf, binary.LittleEndian, uint32(3))
+	writeVarString(&buf, "Better Maps")
+	writeVarString(&buf, "Rome")
+	writeVarString(&buf, "Augustus")
+	writeVarString(&buf, "PLAYERCOLOR_ROME")
+	buf.Write(make([]byte, 8)) // unknownBlock5
+	writeVarString(&buf, "Assets\\Maps\\Continents.lua")
+	// Turn range, which follows the unknown block in the file
+	binary.Write(&buf, binary.LittleEndian, uint32(0))
+	binary.Write(&buf, binary.LittleEndian, int32(-4000))
+	binary.Write(&buf, binary.LittleEndian, uint32(500))
+	writeVarString(&buf, "2050 AD")
+	buf.Write(make([]byte, 8))
+
+	reader := newSectionReader(buf.Bytes())
+	metadata, playerCiv, err := readReplayHeader(reader)
+	if err != nil {
+		t.Fatalf("readReplayHeader returned error: %v", err)
+	}
+	if err := readReplayTurnRange(reader, &metadata); err != nil {
+		t.Fatalf("readReplayTurnRange returned error: %v", err)
+	}
+
+	want := Civ5ReplayMetadata{
+		GameVersion: "1.0.3.279",
+		GameBuild:   "403694",
+		CurrentTurn: 250,
+		Difficulty:  "HANDICAP_PRINCE",
+		StartEra:    "ERA_ANCIENT",
+		EndEra:      "ERA_MODERN",
+		GameSpeed:   "GAMESPEED_STANDARD",
+		WorldSize:   "WORLDSIZE_SMALL",
+		MapFilename: "Assets\\Maps\\Continents.lua",
+		DLC:         []Civ5DLC{{Id: "0123456789abcdef0000000000000010", Enabled: true, Name: "Expansion2"}},
+		Mods:        []Civ5Mod{{Id: "mod-guid", Version: 3, Name: "Better Maps"}},
+		CivName:     "Rome",
+		LeaderName:  "Augustus",
+		PlayerColor: "PLAYERCOLOR_ROME",
+		StartTurn:   0,
+		StartYear:   -4000,
+		EndTurn:     500,
+		EndYear:     "2050 AD",
+	}
+	if playerCiv != "CIVILIZATION_ROME" {
+		t.Errorf("readReplayHeader() playerCiv = %q, want CIVILIZATION_ROME", playerCiv)
+	}
+	if !reflect.DeepEqual(metadata, want) {
+		t.Errorf("replay metadata = %+v, want %+v", metadata, want)
+	}
+}
+
+func TestReadReplayHeaderTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("CIV5")
+	binary.Write(&buf, binary.LittleEndian, uint32(0))
+	writeVarString(&buf, "1.0.3.279")
+
+	if _, _, err := readReplayHeader(newSectionReader(buf.Bytes())); err == nil {
+		t.Error("readReplayHeader(truncated) = nil error, want an error")
+	}
+}
+
+func writeReplayTiles(buf *bytes.Buffer, tiles []Civ5ReplayTile) {
+	binary.Write(buf, binary.LittleEndian, uint32(len(tiles)))
+	binary.Write(buf, binary.LittleEndian, tiles)
+}
+
+func TestReadReplayTiles(t *testing.T) {
+	var buf bytes.Buffer
+	writeReplayTiles(&buf, []Civ5ReplayTile{
+		{PlotType: ReplayPlotOcean, TerrainType: 6, FeatureType: 0xFF},
+		{PlotType: ReplayPlotLand, TerrainType: 0, FeatureType: 5},
+		{PlotType: ReplayPlotHills, TerrainType: 1, FeatureType: 0xFF},
+		{PlotType: ReplayPlotMountain, TerrainType: 7, FeatureType: 0xFF},
+		{PlotType: ReplayPlotLand, TerrainType: 2, FeatureType: 3},
+		{PlotType: ReplayPlotOcean, TerrainType: 5, FeatureType: 0xFF},
+	})
+
+	tiles, err := readReplayTiles(newSectionReader(buf.Bytes()), 3, 2)
+	if err != nil {
+		t.Fatalf("readReplayTiles returned error: %v", err)
+	}
+	if len(tiles) != 2 || len(tiles[0]) != 3 {
+		t.Fatalf("readReplayTiles() returned %d rows, want 2 rows of 3", len(tiles))
+	}
+	forest := tiles[0][1]
+	if forest.X != 1 || forest.Y != 0 || forest.TerrainType != 0 || forest.FeatureTerrainType != 5 || forest.Elevation != ElevationFlat {
+		t.Errorf("tile (1, 0) = %+v, want grassland forest", forest)
+	}
+	if tiles[0][2].Elevation != ElevationHills {
+		t.Errorf("tile (2, 0) elevation = %d, want hills", tiles[0][2].Elevation)
+	}
+	if tiles[1][0].Elevation != ElevationMountain || tiles[1][0].X != 0 || tiles[1][0].Y != 1 {
+		t.Errorf("tile (0, 1) = %+v, want a mountain", tiles[1][0])
+	}
+	if tiles[1][1].FeatureTerrainType != 3 {
+		t.Errorf("tile (1, 1) feature = %d, want 3", tiles[1][1].FeatureTerrainType)
+	}
+}
+
+func TestReadReplayTilesSizeMismatch(t *testing.T) {
+	var buf bytes.Buffer
+	writeReplayTiles(&buf, []Civ5ReplayTile{{}, {}})
+	buf.WriteString("next")
+
+	reader := newSectionReader(buf.Bytes())
+	tiles, err := readReplayTiles(reader, 3, 2)
+	if err != nil {
+		t.Fatalf("readReplayTiles returned error: %v", err)
+	}
+	if len(tiles) != 0 {
+		t.Errorf("readReplayTiles() returned %d rows, want none", len(tiles))
+	}
+	rest := make([]byte, 4)
+	if _, err := reader.Read(rest); err != nil || string(rest) != "next" {
+		t.Errorf("readReplayTiles() didn't consume exactly the tile array, next bytes = %q", rest)
+	}
+}
+
+func TestReadReplayTilesTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint32(4))
+	buf.Write(make([]byte, ReplayTileSize))
+
+	if _, err := readReplayTiles(newSectionReader(buf.Bytes()), 2, 2); err == nil {
+		t.Error("readReplayTiles(truncated) = nil error, want an error")
+	}
+}
+
+func TestBuildMapDataFromReplay(t *testing.T) {
+	replayData := &Civ5ReplayData{
+		MapWidth:  2,
+		MapHeight: 1,
+		MapTiles: [][]*Civ5MapTilePhysical{{
+			{X: 0, Y: 0, TerrainType: 6, FeatureTerrainType: 0xFF},
+			{X: 1, Y: 0, TerrainType: 0, FeatureTerrainType: 5},
+		}},
+		Metadata: Civ5ReplayMetadata{WorldSize: "WORLDSIZE_SMALL"},
+	}
+
+	mapData, err := BuildMapDataFromReplay(replayData)
+	if err != nil {
+		t.Fatalf("BuildMapDataFromReplay returned error: %v", err)
+	}
+	if mapData.MapHeader.Width != 2 || mapData.MapHeader.Height != 1 {
+		t.Errorf("map size = %dx%d, want 2x1", mapData.MapHeader.Width, mapData.MapHeader.Height)
+	}
+	if GetTerrainString(mapData, 0, 0) != "TERRAIN_OCEAN" || GetTerrainString(mapData, 0, 1) != "TERRAIN_GRASS" {
+		t.Errorf("terrain = %q, %q, want TERRAIN_OCEAN, TERRAIN_GRASS",
+			GetTerrainString(mapData, 0, 0), GetTerrainString(mapData, 0, 1))
+	}
+	if mapData.FeatureTerrainList[5] != "FEATURE_FOREST" {
+		t.Errorf("feature 5 = %q, want FEATURE_FOREST", mapData.FeatureTerrainList[5])
+	}
+	if mapData.WorldSize != "WORLDSIZE_SMALL" {
+		t.Errorf("WorldSize = %q, want WORLDSIZE_SMALL", mapData.WorldSize)
+	}
+	if len(mapData.MapTileImprovements) != 1 || len(mapData.MapTileImprovements[0]) != 2 {
+		t.Fatalf("MapTileImprovements has wrong size")
+	}
+	for _, tile := range mapData.MapTileImprovements[0] {
+		if tile.Owner != -1 || tile.CityId != InvalidCityId || tile.UnitId != InvalidUnitId {
+			t.Errorf("improvement tile = %+v, want unowned without a city or unit", tile)
+		}
+	}
+	if mapData.CityOwnerIndexMap == nil {
+		t.Error("CityOwnerIndexMap is nil, want an empty map")
+	}
+}
+
+func TestBuildMapDataFromReplayNoTiles(t *testing.T) {
+	if _, err := BuildMapDataFromReplay(&Civ5ReplayData{}); err == nil {
+		t.Error("BuildMapDataFromReplay(no tiles) = nil error, want an error")
+	}
+	if _, err := BuildMapDataFromReplay(nil); err == nil {
+		t.Error("BuildMapDataFromReplay(nil) = nil error, want an error")
+	}
+}
+
+// newTestReplayMapData builds a 1x2 map data grid for ApplyReplayEvent tests.
+func newTestReplayMapData() *Civ5MapData {
+	return &Civ5MapData{
+		MapTileImprovements: [][]*Civ5MapTileImprovement{
+			{
+				{X: 0, Y: 0, CityId: -1, Owner: -1},
+				{X: 1, Y: 0, CityId: -1, Owner: -1},
+			},
+		},
+	}
+}
+
+func TestApplyReplayEventCityFounded(t *testing.T) {
+	mapData := newTestReplayMapData()
+	event := Civ5ReplayEvent{
+		TypeId: ReplayEventCityFounded,
+		Text:   "Rome is founded.",
+		Tiles:  []Civ5ReplayEventTile{{X: 0, Y: 0}},
+	}
+
+	nextCityId := ApplyReplayEvent(mapData, event, 0)
+
+	tile := mapData.MapTileImprovements[0][0]
+	if tile.CityId != 0 {
+		t.Errorf("CityId = %d, want 0", tile.CityId)
+	}
+	if tile.CityName != "Rome" {
+		t.Errorf("CityName = %q, want Rome", tile.CityName)
+	}
+	if nextCityId != 1 {
+		t.Errorf("nextCityId = %d, want 1", nextCityId)
+	}
+}
+
+func TestApplyReplayEventCityFoundedMultipleTiles(t *testing.T) {
+	mapData := &Civ5MapData{
+		MapTileImprovements: [][]*Civ5MapTileImprovement{
+			{
+				{X: 0, Y: 0, CityId: -1},
+				{X: 1, Y: 0, CityId: -1},
+			},
+		},
+	}
+	event := Civ5ReplayEvent{
+		TypeId: ReplayEventCityFounded,
+		Text:   "Paris is founded.",
+		Tiles: []Civ5ReplayEventTile{
+			{X: 0, Y: 0},
+			{X: 1, Y: 0},
+		},
+	}
+
+	nextCityId := ApplyReplayEvent(mapData, event, 5)
+
+	// Each tile in the event gets its own incrementing city id.
+	if mapData.MapTileImprovements[0][0].CityId != 5 {
+		t.Errorf("tile 0 CityId = %d, want 5", mapData.MapTileImprovements[0][0].CityId)
+	}
+	if mapData.MapTileImprovements[0][1].CityId != 6 {
+		t.Errorf("tile 1 CityId = %d, want 6", mapData.MapTileImprovements[0][1].CityId)
+	}
+	if nextCityId != 7 {
+		t.Errorf("nextCityId = %d, want 7", nextCityId)
+	}
+}
+
+func TestApplyReplayEventTilesClaimed(t *testing.T) {
+	mapData := newTestReplayMapData()
+	event := Civ5ReplayEvent{
+		TypeId: ReplayEventTilesClaimed,
+		CivId:  3,
+		Tiles:  []Civ5ReplayEventTile{{X: 1, Y: 0}},
+	}
+
+	nextCityId := ApplyReplayEvent(mapData, event, 2)
+
+	if mapData.MapTileImprovements[0][1].Owner != 3 {
+		t.Errorf("Owner = %d, want 3", mapData.MapTileImprovements[0][1].Owner)
+	}
+	// Non-founding events must not advance the city id counter.
+	if nextCityId != 2 {
+		t.Errorf("nextCityId = %d, want unchanged 2", nextCityId)
+	}
+}
+
+func TestApplyReplayEventCityTransferred(t *testing.T) {
+	mapData := newTestReplayMapData()
+	event := Civ5ReplayEvent{
+		TypeId: ReplayEventCityTransferred,
+		CivId:  7,
+		Tiles:  []Civ5ReplayEventTile{{X: 0, Y: 0}},
+	}
+
+	ApplyReplayEvent(mapData, event, 0)
+
+	if mapData.MapTileImprovements[0][0].Owner != 7 {
+		t.Errorf("Owner = %d, want 7", mapData.MapTileImprovements[0][0].Owner)
+	}
+}
+
+func TestApplyReplayEventTilesRazed(t *testing.T) {
+	mapData := &Civ5MapData{
+		MapTileImprovements: [][]*Civ5MapTileImprovement{
+			{
+				{X: 0, Y: 0, CityId: 4, CityName: "Carthage", Owner: 2, RouteType: 0},
+			},
+		},
+	}
+	event := Civ5ReplayEvent{
+		TypeId: ReplayEventTilesRazed,
+		Tiles:  []Civ5ReplayEventTile{{X: 0, Y: 0}},
+	}
+
+	nextCityId := ApplyReplayEvent(mapData, event, 5)
+
+	tile := mapData.MapTileImprovements[0][0]
+	if tile.Owner != -1 {
+		t.Errorf("Owner = %d, want -1", tile.Owner)
+	}
+	if tile.CityId != -1 {
+		t.Errorf("CityId = %d, want -1", tile.CityId)
+	}
+	if tile.CityName != "" {
+		t.Errorf("CityName = %q, want empty", tile.CityName)
+	}
+	if tile.RouteType != 2 {
+		t.Errorf("RouteType = %d, want 2 (road)", tile.RouteType)
+	}
+	// Razing does not found a city, so the counter is unaffected.
+	if nextCityId != 5 {
+		t.Errorf("nextCityId = %d, want unchanged 5", nextCityId)
+	}
+}
+
+func TestApplyReplayEventUnknownTypeIsNoOp(t *testing.T) {
+	mapData := newTestReplayMapData()
+	event := Civ5ReplayEvent{
+		TypeId: 99,
+		Tiles:  []Civ5ReplayEventTile{{X: 0, Y: 0}},
+	}
+
+	nextCityId := ApplyReplayEvent(mapData, event, 3)
+
+	tile := mapData.MapTileImprovements[0][0]
+	if tile.CityId != -1 || tile.Owner != -1 {
+		t.Errorf("unknown event type mutated tile: %+v", tile)
+	}
+	if nextCityId != 3 {
+		t.Errorf("nextCityId = %d, want unchanged 3", nextCityId)
+	}
+}
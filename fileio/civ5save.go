@@ -5,7 +5,6 @@ import (
 	"compress/zlib"
 	"fmt"
 	"io"
-	"os"
 	"strings"
 )
 
@@ -18,33 +17,61 @@ const (
 	ArrayLengthCorrectionThreshold = 150
 )
 
+// Civ5SaveHeader holds the game settings stored in the uncompressed part of a .civ5save file
+type Civ5SaveHeader struct {
+	GameVersion string
+	GameBuild   string
+	GameName    string
+	Turn        int
+	Difficulty  string
+	StartEra    string
+	EndEra      string
+	GameSpeed   string
+	WorldSize   string
+	MapWidth    int // Grid size of the world size, which scripted maps are generated with
+	MapHeight   int
+	MapFilename string
+	Climate     string
+	SeaLevel    string
+	GameOptions []string // Only the options that are enabled
+	DLC         []Civ5DLC
+	Mods        []Civ5Mod
+}
+
 type Civ5SaveData struct {
 	PlayerCiv       string
 	IsReplayFile    bool
+	Header          Civ5SaveHeader
 	AllCivs         []Civ5ReplayCiv
 	AllReplayEvents []Civ5ReplayEvent
 }
 
-func readClimateName(streamReader *io.SectionReader) {
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+// readClimateName reads the climate section and returns the climate type
+func readClimateName(streamReader *io.SectionReader) (string, error) {
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "varstring",
 			VariableName: "climateName1",
 		},
-	})
+	}); err != nil {
+		return "", err
+	}
 
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "bytearray:12",
 			VariableName: "paddingAfterClimateName1",
 		},
-	})
+	}); err != nil {
+		return "", err
+	}
 
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
-		{
-			VariableType: "varstring",
-			VariableName: "climateNameType",
-		},
+	climateType, err := readVarString(streamReader, "climateNameType")
+	if err != nil {
+		return "", fmt.Errorf("failed to read climate type: %w", err)
+	}
+
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "varstring",
 			VariableName: "climateNameDescription",
@@ -53,9 +80,11 @@ func readClimateName(streamReader *io.SectionReader) {
 			VariableType: "varstring",
 			VariableName: "climateName2",
 		},
-	})
+	}); err != nil {
+		return "", err
+	}
 
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "int32",
 			VariableName: "desertPercentChange",
@@ -100,29 +129,38 @@ func readClimateName(streamReader *io.SectionReader) {
 			VariableType: "float32",
 			VariableName: "randIceLatitude",
 		},
-	})
+	}); err != nil {
+		return "", err
+	}
+	return climateType, nil
 }
 
-func readSeaLevel(streamReader *io.SectionReader) {
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+// readSeaLevel reads the sea level section and returns the sea level type
+func readSeaLevel(streamReader *io.SectionReader) (string, error) {
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "varstring",
 			VariableName: "seaLevelName1",
 		},
-	})
+	}); err != nil {
+		return "", err
+	}
 
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "bytearray:12",
 			VariableName: "paddingAfterSeaLevel1",
 		},
-	})
+	}); err != nil {
+		return "", err
+	}
 
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
-		{
-			VariableType: "varstring",
-			VariableName: "seaLevelNameType",
-		},
+	seaLevelType, err := readVarString(streamReader, "seaLevelNameType")
+	if err != nil {
+		return "", fmt.Errorf("failed to read sea level type: %w", err)
+	}
+
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "varstring",
 			VariableName: "seaLevelNameDescription",
@@ -131,18 +169,23 @@ func readSeaLevel(streamReader *io.SectionReader) {
 			VariableType: "varstring",
 			VariableName: "seaLevelName2",
 		},
-	})
+	}); err != nil {
+		return "", err
+	}
 
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "bytearray:5",
 			VariableName: "paddingAfterSeaLevel2",
 		},
-	})
+	}); err != nil {
+		return "", err
+	}
+	return seaLevelType, nil
 }
 
-func readTurnSpeedData(streamReader *io.SectionReader) {
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+func readTurnSpeedData(streamReader *io.SectionReader) error {
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "uint32",
 			VariableName: "turnTimerId1",
@@ -195,36 +238,49 @@ func readTurnSpeedData(streamReader *io.SectionReader) {
 			VariableType: "uint8",
 			VariableName: "turnTimerUnknown2",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	readArray(streamReader, "turnTimerVictoryFlags", []Civ5ReplayFileConfigEntry{
+	if err := readArray(streamReader, "turnTimerVictoryFlags", []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "uint8", // length is usually 5
 			VariableName: "victoryFlag",
 		},
-	})
+	}); err != nil {
+		return err
+	}
+	return nil
 }
 
-func readWorldSizeData(streamReader *io.SectionReader) {
-	numberBeforeWorldSize := unsafeReadUint32(streamReader)
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+// readWorldSizeData reads the world size section and sets the map grid size in the header
+func readWorldSizeData(streamReader *io.SectionReader, header *Civ5SaveHeader) error {
+	numberBeforeWorldSize, err := readUint32(streamReader, "numberBeforeWorldSize")
+	if err != nil {
+		return err
+	}
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "uint32",
 			VariableName: "portraitIndex1",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
 	// Should be related to map version
 	if numberBeforeWorldSize == 2 {
-		readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+		if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 			{
 				VariableType: "uint32",
 				VariableName: "numBeforeWorldSize",
 			},
-		})
+		}); err != nil {
+			return err
+		}
 	}
 
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "varstring",
 			VariableName: "worldSize1",
@@ -249,9 +305,11 @@ func readWorldSizeData(streamReader *io.SectionReader) {
 			VariableType: "varstring",
 			VariableName: "worldSize2",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "uint32",
 			VariableName: "defaultPlayers",
@@ -288,26 +346,36 @@ func readWorldSizeData(streamReader *io.SectionReader) {
 			VariableType: "int32",
 			VariableName: "maxConscriptModifier",
 		},
-		{
-			VariableType: "uint32",
-			VariableName: "gridWidth",
-		},
-		{
-			VariableType: "uint32",
-			VariableName: "gridHeight",
-		},
-	})
+	}); err != nil {
+		return err
+	}
+
+	gridWidth, err := readUint32(streamReader, "gridWidth")
+	if err != nil {
+		return err
+	}
+	gridHeight, err := readUint32(streamReader, "gridHeight")
+	if err != nil {
+		return err
+	}
+	if tileCount := uint64(gridWidth) * uint64(gridHeight); tileCount > MaxArrayLength {
+		return &ErrImplausibleLength{Section: "grid size", Offset: currentOffset(streamReader) - 8, Length: int64(tileCount)}
+	}
+	header.MapWidth = int(gridWidth)
+	header.MapHeight = int(gridHeight)
 
 	if numberBeforeWorldSize == 2 {
-		readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+		if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 			{
 				VariableType: "uint32",
 				VariableName: "maxActiveReligions",
 			},
-		})
+		}); err != nil {
+			return err
+		}
 	}
 
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "int32",
 			VariableName: "terrainGrainChange",
@@ -336,32 +404,50 @@ func readWorldSizeData(streamReader *io.SectionReader) {
 			VariableType: "uint32",
 			VariableName: "numCitiesTechCostMod",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
 	if numberBeforeWorldSize == 2 {
-		readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+		if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 			{
 				VariableType: "uint32",
 				VariableName: "portraitIndex2",
 			},
-		})
+		}); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-func readGameOptions(streamReader *io.SectionReader) {
-	readArray(streamReader, "gameOptionArr", []Civ5ReplayFileConfigEntry{
-		{
-			VariableType: "varstring",
-			VariableName: "gameOption",
-		},
-		{
-			VariableType: "uint32",
-			VariableName: "gameOptionEnabled",
-		},
-	})
+// readGameOptions reads the game option list and returns the options that are enabled
+func readGameOptions(streamReader *io.SectionReader) ([]string, error) {
+	count, err := readCount(streamReader, "gameOptionArr")
+	if err != nil {
+		return nil, err
+	}
+
+	enabledOptions := make([]string, 0)
+	for i := 0; i < count; i++ {
+		option, err := readVarString(streamReader, "gameOption")
+		if err != nil {
+			return nil, err
+		}
+		enabled, err := readUint32(streamReader, "gameOptionEnabled")
+		if err != nil {
+			return nil, err
+		}
+		if enabled != 0 {
+			enabledOptions = append(enabledOptions, option)
+		}
+	}
+	return enabledOptions, nil
 }
 
-func buildReaderForDecompressedFile(compressedStreamReader *io.SectionReader, outputFilename string) (*bytes.Reader, int, error) {
+// buildReaderForDecompressedFile inflates the compressed block in memory. If the block is damaged,
+// whatever could be inflated is still used.
+func buildReaderForDecompressedFile(compressedStreamReader *io.SectionReader) (*bytes.Reader, int, error) {
 	decompressedFileReader, err := zlib.NewReader(compressedStreamReader)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to create zlib new reader: %w", err)
@@ -377,88 +463,52 @@ func buildReaderForDecompressedFile(compressedStreamReader *io.SectionReader, ou
 		}
 
 		fmt.Println("Decompressed contents size:", len(decompressedContents))
-		err = os.WriteFile(outputFilename, decompressedContents, 0644)
-		if err != nil {
-			return nil, 0, fmt.Errorf("error writing to %q: %w", outputFilename, err)
-		}
 	}
 
 	return bytes.NewReader(decompressedContents), len(decompressedContents), nil
 }
 
-// readVarStringArrayOrPanic reads a count-prefixed array of variable-length strings.
-// Matches the file's existing convention of treating a malformed stream at this point
-// as unrecoverable, rather than threading an error back through every caller.
-func readVarStringArrayOrPanic(reader *io.SectionReader, count uint32, varStringLabel, panicPhrase string) []string {
-	values := make([]string, count)
-	for i := 0; i < int(count); i++ {
-		value, err := readVarString(reader, varStringLabel)
-		if err != nil {
-			panic(fmt.Sprintf("failed to read %s: %v", panicPhrase, err))
-		}
-		values[i] = value
-	}
-	return values
-}
-
 // readDynamicPaddingBlock reads a size-prefixed padding block whose length is derived from a
 // marker value read just before it, matching the file's "(marker+1) groups of 4 bytes" pattern.
 // A marker of 0 means the block is absent.
-func readDynamicPaddingBlock(reader *io.SectionReader, marker uint32, blockName string) {
+func readDynamicPaddingBlock(reader *io.SectionReader, marker uint32, blockName string) error {
 	if marker == 0 {
-		return
+		return nil
 	}
-	readFileConfig(reader, []Civ5ReplayFileConfigEntry{
+	if _, err := readFileConfig(reader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: fmt.Sprintf("bytearray:%d", (marker+1)*4),
 			VariableName: blockName,
 		},
-	})
+	}); err != nil {
+		return err
+	}
+	return nil
 }
 
-// openSaveFileReader opens a save file and returns a section reader spanning its entire contents
-func openSaveFileReader(filename string) (*os.File, int64, *io.SectionReader, error) {
-	inputFile, err := os.Open(filename)
-	if err != nil {
-		return nil, 0, nil, fmt.Errorf("failed to open file %q: %w", filename, err)
+// readSaveHeader reads the game version/build/turn number header and the active player's civ
+func readSaveHeader(streamReader *io.SectionReader, header *Civ5SaveHeader) (string, error) {
+	// Skip the game name and unknownBlock1
+	if _, err := readByteArray(streamReader, 8, "gameName"); err != nil {
+		return "", fmt.Errorf("failed to read game name: %w", err)
 	}
-	fi, err := inputFile.Stat()
+	if err := readVarStrings(streamReader, []varStringField{
+		{&header.GameVersion, "gameVersion"},
+		{&header.GameBuild, "gameBuild"},
+	}); err != nil {
+		return "", err
+	}
+
+	currentTurn, err := readUint32(streamReader, "currentTurnNumber")
 	if err != nil {
-		inputFile.Close()
-		return nil, 0, nil, fmt.Errorf("failed to get file info for %q: %w", filename, err)
+		return "", fmt.Errorf("failed to read current turn: %w", err)
 	}
-	saveFileLength := fi.Size()
-	return inputFile, saveFileLength, io.NewSectionReader(inputFile, int64(0), saveFileLength), nil
-}
+	header.Turn = int(currentTurn)
 
-// readSaveHeader reads the game name/version/build/turn number header and the active player's civ
-func readSaveHeader(streamReader *io.SectionReader) (string, error) {
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
-		{
-			VariableType: "bytearray:4",
-			VariableName: "gameName",
-		},
-		{
-			VariableType: "bytearray:4",
-			VariableName: "unknownBlock1",
-		},
-		{
-			VariableType: "varstring",
-			VariableName: "gameVersion",
-		},
-		{
-			VariableType: "varstring",
-			VariableName: "gameBuild",
-		},
-		{
-			VariableType: "uint32",
-			VariableName: "currentTurnNumber",
-		},
-		{
-			VariableType: "bytearray:1",
-			VariableName: "unknownBlock2",
-		},
-	})
+	// Skip unknownBlock2
+	if _, err := readByteArray(streamReader, 1, "unknownBlock2"); err != nil {
+		return "", fmt.Errorf("failed to read unknown block: %w", err)
+	}
 
 	playerCiv, err := readVarString(streamReader, "playerCiv")
 	if err != nil {
@@ -470,69 +520,32 @@ func readSaveHeader(streamReader *io.SectionReader) (string, error) {
 }
 
 // readGameSettingsAndContent reads the difficulty/era/speed/world-size block and the DLC and mod lists
-func readGameSettingsAndContent(streamReader *io.SectionReader) {
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
-		{
-			VariableType: "varstring",
-			VariableName: "difficulty",
-		},
-		{
-			VariableType: "varstring",
-			VariableName: "eraStart",
-		},
-		{
-			VariableType: "varstring",
-			VariableName: "eraEnd",
-		},
-		{
-			VariableType: "varstring",
-			VariableName: "gameSpeed",
-		},
-		{
-			VariableType: "varstring",
-			VariableName: "worldSize",
-		},
-		{
-			VariableType: "varstring",
-			VariableName: "mapFilename1",
-		},
-	})
-
-	readArray(streamReader, "dlc", []Civ5ReplayFileConfigEntry{
-		{
-			VariableType: "bytearray:16",
-			VariableName: "dlcId",
-		},
-		{
-			VariableType: "bytearray:4",
-			VariableName: "dlcEnabled",
-		},
-		{
-			VariableType: "varstring",
-			VariableName: "dlcName",
-		},
-	})
+func readGameSettingsAndContent(streamReader *io.SectionReader, header *Civ5SaveHeader) error {
+	if err := readVarStrings(streamReader, []varStringField{
+		{&header.Difficulty, "difficulty"},
+		{&header.StartEra, "eraStart"},
+		{&header.EndEra, "eraEnd"},
+		{&header.GameSpeed, "gameSpeed"},
+		{&header.WorldSize, "worldSize"},
+		{&header.MapFilename, "mapFilename1"},
+	}); err != nil {
+		return err
+	}
 
-	readArray(streamReader, "mods", []Civ5ReplayFileConfigEntry{
-		{
-			VariableType: "varstring",
-			VariableName: "modId",
-		},
-		{
-			VariableType: "bytearray:4",
-			VariableName: "modVersion",
-		},
-		{
-			VariableType: "varstring",
-			VariableName: "modName",
-		},
-	})
+	var err error
+	if header.DLC, err = readDLCList(streamReader); err != nil {
+		return err
+	}
+	if header.Mods, err = readModList(streamReader); err != nil {
+		return err
+	}
+	return nil
 }
 
 // readPlayerAndMapInfo reads the player civ block, the player name array, and a handful of
 // arrays of unknown purpose that follow it
-func readPlayerAndMapInfo(streamReader *io.SectionReader) {
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+func readPlayerAndMapInfo(streamReader *io.SectionReader) error {
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "varstring",
 			VariableName: "playerCivName",
@@ -577,38 +590,49 @@ func readPlayerAndMapInfo(streamReader *io.SectionReader) {
 			VariableType: "varstring",
 			VariableName: "mapFilename2",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	readArray(streamReader, "unknownBlock3", []Civ5ReplayFileConfigEntry{
+	if err := readArray(streamReader, "unknownBlock3", []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "int32",
 			VariableName: "unknownBlock3Var",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	readArray(streamReader, "playerNameArr", []Civ5ReplayFileConfigEntry{
+	if err := readArray(streamReader, "playerNameArr", []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "varstring",
 			VariableName: "playerName",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
 	// 4 arrays, but value is unknown
 	for i := 0; i < 4; i++ {
-		readArray(streamReader, fmt.Sprintf("unknownArrayBlock1-%d", i), []Civ5ReplayFileConfigEntry{
+		if err := readArray(streamReader, fmt.Sprintf("unknownArrayBlock1-%d", i), []Civ5ReplayFileConfigEntry{
 			{
 				VariableType: "uint32",
 				VariableName: fmt.Sprintf("unknownArrayBlock1-%d", i),
 			},
-		})
+		}); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 // readCivRoster reads the list of civilization names and builds the initial civ roster
-func readCivRoster(streamReader *io.SectionReader) []Civ5ReplayCiv {
-	civNamesLength := unsafeReadUint32(streamReader)
-	fmt.Println("CivNamesLength:", civNamesLength)
-	civNameArr := readVarStringArrayOrPanic(streamReader, civNamesLength, "civName", "civ name")
+func readCivRoster(streamReader *io.SectionReader) ([]Civ5ReplayCiv, error) {
+	civNameArr, err := readVarStringArray(streamReader, "civName")
+	if err != nil {
+		return nil, err
+	}
+	fmt.Println("CivNamesLength:", len(civNameArr))
 	fmt.Println("CivNames:", civNameArr)
 
 	allCivs := make([]Civ5ReplayCiv, 0, len(civNameArr))
@@ -621,136 +645,180 @@ func readCivRoster(streamReader *io.SectionReader) []Civ5ReplayCiv {
 			Demonym:          "",
 		})
 	}
-	return allCivs
+	return allCivs, nil
 }
 
 // readLeadersAndCivArrays reads the leader name array and several more arrays of unknown purpose
-func readLeadersAndCivArrays(streamReader *io.SectionReader) {
-	readArray(streamReader, "leaderArray1", []Civ5ReplayFileConfigEntry{
+func readLeadersAndCivArrays(streamReader *io.SectionReader) error {
+	if err := readArray(streamReader, "leaderArray1", []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "varstring",
 			VariableName: "arrLeaderName",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	unknownBlock5Number := unsafeReadUint32(streamReader)
+	unknownBlock5Number, err := readUint32(streamReader, "unknownBlock5Number")
+	if err != nil {
+		return err
+	}
 	if unknownBlock5Number != 0 {
-		readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+		if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 			{
 				VariableType: "bytearray:12",
 				VariableName: "unknownBlock5",
 			},
-		})
+		}); err != nil {
+			return err
+		}
 	}
 
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "varstring",
 			VariableName: "computerUsername1",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	readArray(streamReader, "unknownBlock6-1", []Civ5ReplayFileConfigEntry{
+	if err := readArray(streamReader, "unknownBlock6-1", []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "int32",
 			VariableName: "unknownBlock6-1",
 		},
-	})
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	}); err != nil {
+		return err
+	}
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "bytearray:53",
 			VariableName: "unknownBlock6-2",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	readArray(streamReader, "unknownArray1", []Civ5ReplayFileConfigEntry{
+	if err := readArray(streamReader, "unknownArray1", []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "uint32",
 			VariableName: "unknownArray1Var",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	readArray(streamReader, "civArray1", []Civ5ReplayFileConfigEntry{
+	if err := readArray(streamReader, "civArray1", []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "varstring",
 			VariableName: "civName",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	readArray(streamReader, "unknownArray2", []Civ5ReplayFileConfigEntry{
+	if err := readArray(streamReader, "unknownArray2", []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "uint32",
 			VariableName: "unknownArray2Var",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	readArray(streamReader, "civArray2", []Civ5ReplayFileConfigEntry{
+	if err := readArray(streamReader, "civArray2", []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "varstring",
 			VariableName: "civArray2String",
 		},
-	})
+	}); err != nil {
+		return err
+	}
+	return nil
 }
 
 // readClimateSection reads a variable-length unknown block followed by the climate name section
-func readClimateSection(streamReader *io.SectionReader) {
-	unknownBlock7Number := unsafeReadUint32(streamReader)
-	readDynamicPaddingBlock(streamReader, unknownBlock7Number, "unknownBlock7-1")
+func readClimateSection(streamReader *io.SectionReader, header *Civ5SaveHeader) error {
+	unknownBlock7Number, err := readUint32(streamReader, "unknownBlock7Number")
+	if err != nil {
+		return err
+	}
+	if err := readDynamicPaddingBlock(streamReader, unknownBlock7Number, "unknownBlock7-1"); err != nil {
+		return err
+	}
 
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "bytearray:8",
 			VariableName: "unknownBlock7-2",
 		},
-	})
-	readClimateName(streamReader)
+	}); err != nil {
+		return err
+	}
+	climate, err := readClimateName(streamReader)
+	if err != nil {
+		return err
+	}
+	header.Climate = climate
+	return nil
 }
 
 // readGameNameAndTurnInfo reads the save's game name, current turn number, and a trailing
 // array whose presence depends on a peeked-ahead marker value
-func readGameNameAndTurnInfo(streamReader *io.SectionReader) {
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+func readGameNameAndTurnInfo(streamReader *io.SectionReader) (string, error) {
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "uint32",
 			VariableName: "unknownUint32",
 		},
-	})
+	}); err != nil {
+		return "", err
+	}
 
-	readArray(streamReader, "unknownBlock8-1", []Civ5ReplayFileConfigEntry{
+	if err := readArray(streamReader, "unknownBlock8-1", []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "uint32",
 			VariableName: "unknownBlock8-1",
 		},
-	})
+	}); err != nil {
+		return "", err
+	}
 
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "uint32",
 			VariableName: "unknownUint32",
 		},
-	})
+	}); err != nil {
+		return "", err
+	}
 
-	readArray(streamReader, "unknownBlock8-2", []Civ5ReplayFileConfigEntry{
+	if err := readArray(streamReader, "unknownBlock8-2", []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "uint32",
 			VariableName: "unknownBlock8-2",
 		},
-	})
+	}); err != nil {
+		return "", err
+	}
 
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "bytearray:15",
 			VariableName: "unknownBlock8-3",
 		},
-	})
+	}); err != nil {
+		return "", err
+	}
 
 	gameName, err := readVarString(streamReader, "gameName")
 	if err != nil {
-		panic(fmt.Sprintf("failed to read game name: %v", err))
+		return "", fmt.Errorf("failed to read game name: %w", err)
 	}
 	fmt.Println("Game name:", gameName)
 
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "uint32",
 			VariableName: "unknownUint32", // usually equal to 2
@@ -771,47 +839,68 @@ func readGameNameAndTurnInfo(streamReader *io.SectionReader) {
 			VariableType: "uint32",
 			VariableName: "unknownUint32",
 		},
-	})
+	}); err != nil {
+		return "", err
+	}
 
-	readArray(streamReader, "unknownArray3", []Civ5ReplayFileConfigEntry{
+	if err := readArray(streamReader, "unknownArray3", []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "uint32",
 			VariableName: "unknownArray3Var",
 		},
-	})
+	}); err != nil {
+		return "", err
+	}
 
 	// Some save files missing extra array
-	nextByte := unsafeReadUint16(streamReader)
+	nextByte, err := readUint16(streamReader, "unknownArray4 marker")
+	if err != nil {
+		return "", err
+	}
 	if nextByte != 0 {
-		streamReader.Seek(-2, io.SeekCurrent)
-		readArray(streamReader, "unknownArray4", []Civ5ReplayFileConfigEntry{
+		if _, err := streamReader.Seek(-2, io.SeekCurrent); err != nil {
+			return "", err
+		}
+		if err := readArray(streamReader, "unknownArray4", []Civ5ReplayFileConfigEntry{
 			{
 				VariableType: "int32", // a lot of negative values
 				VariableName: "unknownArray4Var",
 			},
-		})
-		readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+		}); err != nil {
+			return "", err
+		}
+		if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 			{
 				VariableType: "bytearray:2",
 				VariableName: "unknownBlock10",
 			},
-		})
+		}); err != nil {
+			return "", err
+		}
 	}
+	return gameName, nil
 }
 
 // readLeaderArray2AndPlayerSetup reads the second leader name array and the computer username/map block
-func readLeaderArray2AndPlayerSetup(streamReader *io.SectionReader) {
-	readArray(streamReader, "leaderArray2", []Civ5ReplayFileConfigEntry{
+func readLeaderArray2AndPlayerSetup(streamReader *io.SectionReader) error {
+	if err := readArray(streamReader, "leaderArray2", []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "varstring",
 			VariableName: "leaderArrName",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	unknownBlock11Number := unsafeReadUint32(streamReader)
-	readDynamicPaddingBlock(streamReader, unknownBlock11Number, "unknownBlock11")
+	unknownBlock11Number, err := readUint32(streamReader, "unknownBlock11Number")
+	if err != nil {
+		return err
+	}
+	if err := readDynamicPaddingBlock(streamReader, unknownBlock11Number, "unknownBlock11"); err != nil {
+		return err
+	}
 
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "varstring",
 			VariableName: "computerUsername2",
@@ -836,180 +925,242 @@ func readLeaderArray2AndPlayerSetup(streamReader *io.SectionReader) {
 			VariableType: "uint32",
 			VariableName: "unknownBlock12-3",
 		},
-	})
+	}); err != nil {
+		return err
+	}
+	return nil
 }
 
 // readMinorCivNames reads the minor civ (city-state) names and patches matching entries in the civ roster
-func readMinorCivNames(streamReader *io.SectionReader, allCivs []Civ5ReplayCiv) {
-	minorCivNamesLength := unsafeReadUint32(streamReader)
-	minorCivNameArr := readVarStringArrayOrPanic(streamReader, minorCivNamesLength, "minorCivName", "minor civ name")
+func readMinorCivNames(streamReader *io.SectionReader, allCivs []Civ5ReplayCiv) error {
+	minorCivNameArr, err := readVarStringArray(streamReader, "minorCivName")
+	if err != nil {
+		return err
+	}
 	for i, minorCivName := range minorCivNameArr {
-		if strings.Contains(minorCivName, "MINOR_CIV") {
+		if strings.Contains(minorCivName, "MINOR_CIV") && i < len(allCivs) {
 			allCivs[i].Name = minorCivName
 		}
 	}
 	fmt.Println("minorCivArray:", minorCivNameArr)
 
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "bytearray:77",
 			VariableName: "unknownBlock13",
 		},
-	})
+	}); err != nil {
+		return err
+	}
+	return nil
 }
 
 // readPlayerArraysAndColors reads several player-related arrays and patches the civ roster with player colors
-func readPlayerArraysAndColors(streamReader *io.SectionReader, allCivs []Civ5ReplayCiv) {
-	readArray(streamReader, "unknownArray5", []Civ5ReplayFileConfigEntry{
+func readPlayerArraysAndColors(streamReader *io.SectionReader, allCivs []Civ5ReplayCiv) error {
+	if err := readArray(streamReader, "unknownArray5", []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "int32", // a lot of negative values
 			VariableName: "unknownArray5Var",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	readArray(streamReader, "playerArr", []Civ5ReplayFileConfigEntry{
+	if err := readArray(streamReader, "playerArr", []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "varstring",
 			VariableName: "playerArrName",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "bytearray:8",
 			VariableName: "unknownBlock14",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	readArray(streamReader, "unknownArray6", []Civ5ReplayFileConfigEntry{
+	if err := readArray(streamReader, "unknownArray6", []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "uint8",
 			VariableName: "unknownArray6Var",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	playerColorLength := unsafeReadUint32(streamReader)
-	playerColorArr := readVarStringArrayOrPanic(streamReader, playerColorLength, "playerColorName", "player color name")
+	playerColorArr, err := readVarStringArray(streamReader, "playerColorName")
+	if err != nil {
+		return err
+	}
 	for i, playerColorName := range playerColorArr {
-		allCivs[i].LongName = playerColorName
+		if i < len(allCivs) {
+			allCivs[i].LongName = playerColorName
+		}
 	}
 	fmt.Println("playerColorArr:", playerColorArr)
 
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "bytearray:10",
 			VariableName: "unknownBlock15",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	readArray(streamReader, "unknownArray7", []Civ5ReplayFileConfigEntry{
+	if err := readArray(streamReader, "unknownArray7", []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "uint8",
 			VariableName: "unknownArray7Var",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "bytearray:12",
 			VariableName: "unknownBlock16",
 		},
-	})
+	}); err != nil {
+		return err
+	}
+	return nil
 }
 
 // readSeaLevelAndWorldSettings reads the sea level, turn speed, world size, and game option sections
-func readSeaLevelAndWorldSettings(streamReader *io.SectionReader) {
-	readSeaLevel(streamReader)
+func readSeaLevelAndWorldSettings(streamReader *io.SectionReader, header *Civ5SaveHeader) error {
+	seaLevel, err := readSeaLevel(streamReader)
+	if err != nil {
+		return err
+	}
+	header.SeaLevel = seaLevel
 
-	readArray(streamReader, "unknownArray8", []Civ5ReplayFileConfigEntry{
+	if err := readArray(streamReader, "unknownArray8", []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "uint32",
 			VariableName: "unknownArray8Var",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	readArray(streamReader, "unknownArray9", []Civ5ReplayFileConfigEntry{
+	if err := readArray(streamReader, "unknownArray9", []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "uint32",
 			VariableName: "unknownArray9Var",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "bytearray:12",
 			VariableName: "unknownBlock17",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	readArray(streamReader, "unknownArray10", []Civ5ReplayFileConfigEntry{
+	if err := readArray(streamReader, "unknownArray10", []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "uint32",
 			VariableName: "unknownArray10Var",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "bytearray:1",
 			VariableName: "unknownBlock18",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	readTurnSpeedData(streamReader)
-	readArray(streamReader, "unknownArrayAfterTurnSpeed", []Civ5ReplayFileConfigEntry{
+	if err := readTurnSpeedData(streamReader); err != nil {
+		return err
+	}
+	if err := readArray(streamReader, "unknownArrayAfterTurnSpeed", []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "uint8",
 			VariableName: "unknownArrayAfterTurnSpeedVar",
 		},
-	})
-	readWorldSizeData(streamReader)
-	readGameOptions(streamReader)
+	}); err != nil {
+		return err
+	}
+	if err := readWorldSizeData(streamReader, header); err != nil {
+		return err
+	}
+	if header.GameOptions, err = readGameOptions(streamReader); err != nil {
+		return err
+	}
 
-	readArray(streamReader, "unknownArrayAfterGameOptions", []Civ5ReplayFileConfigEntry{
+	if err := readArray(streamReader, "unknownArrayAfterGameOptions", []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "bytearray:9",
 			VariableName: "valueAfterGameOptions",
 		},
-	})
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	}); err != nil {
+		return err
+	}
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "varstring",
 			VariableName: "gameVersion2",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	readArray(streamReader, "unknownArray12", []Civ5ReplayFileConfigEntry{
+	if err := readArray(streamReader, "unknownArray12", []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "uint8",
 			VariableName: "unknownArray12Var",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	readArray(streamReader, "unknownArray13", []Civ5ReplayFileConfigEntry{
+	if err := readArray(streamReader, "unknownArray13", []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "uint8",
 			VariableName: "unknownArray13Var",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	readArray(streamReader, "unknownArray14", []Civ5ReplayFileConfigEntry{
+	if err := readArray(streamReader, "unknownArray14", []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "uint32",
 			VariableName: "unknownArray14Var",
 		},
-	})
+	}); err != nil {
+		return err
+	}
+	return nil
 }
 
 // locateCompressedBlock skips the padding before the compressed block and returns a reader
 // positioned at its start
-func locateCompressedBlock(streamReader *io.SectionReader, inputFile *os.File, saveFileLength int64) (*io.SectionReader, error) {
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+func locateCompressedBlock(streamReader *io.SectionReader, r io.ReaderAt, saveFileLength int64) (*io.SectionReader, error) {
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "bytearray:8", // value is always [2 0 0 0 0 0 1 0]
 			VariableName: "paddingBeforeCompressedBlock",
 		},
-	})
+	}); err != nil {
+		return nil, err
+	}
 
 	// Header of compressed block should begin with 0x789C
 	offsetToCompressedBlock, err := streamReader.Seek(0, io.SeekCurrent)
@@ -1018,58 +1169,107 @@ func locateCompressedBlock(streamReader *io.SectionReader, inputFile *os.File, s
 	}
 	fmt.Println("Offset to compressed data:", offsetToCompressedBlock)
 
-	return io.NewSectionReader(inputFile, offsetToCompressedBlock, saveFileLength-offsetToCompressedBlock), nil
+	return io.NewSectionReader(r, offsetToCompressedBlock, saveFileLength-offsetToCompressedBlock), nil
 }
 
-func ReadCiv5SaveFile(filename string, outputFilename string) (*Civ5SaveData, error) {
-	inputFile, saveFileLength, streamReader, err := openSaveFileReader(filename)
+// reportSaveHeader prints a summary of the save file settings
+func reportSaveHeader(header *Civ5SaveHeader) {
+	fmt.Println("\n=== Save Header ===")
+	fmt.Printf("Game: %s, Version: %s (build %s), Turn: %d\n", header.GameName, header.GameVersion, header.GameBuild, header.Turn)
+	fmt.Printf("Difficulty: %s, Speed: %s, World Size: %s (%dx%d)\n", header.Difficulty, header.GameSpeed, header.WorldSize, header.MapWidth, header.MapHeight)
+	fmt.Printf("Map: %s, Climate: %s, Sea Level: %s\n", header.MapFilename, header.Climate, header.SeaLevel)
+	fmt.Printf("Eras: %s to %s\n", header.StartEra, header.EndEra)
+	fmt.Printf("Game Options: %v\n", header.GameOptions)
+	fmt.Printf("DLC: %d, Mods: %d\n", len(header.DLC), len(header.Mods))
+}
+
+// ReadCiv5SaveFile reads a .civ5save file from disk. See ParseCiv5Save.
+func ReadCiv5SaveFile(filename string) (*Civ5SaveData, error) {
+	inputFile, saveFileLength, err := openInputFile(filename)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to open file %q: %w", filename, err)
 	}
 	defer inputFile.Close()
+	return ParseCiv5Save(inputFile, saveFileLength)
+}
+
+// ParseCiv5Save parses the first size bytes of r as a .civ5save file. The compressed block is
+// inflated in memory and nothing is written to disk.
+func ParseCiv5Save(r io.ReaderAt, saveFileLength int64) (*Civ5SaveData, error) {
+	streamReader := io.NewSectionReader(r, 0, saveFileLength)
 	fmt.Println("Loading Civ5Save...")
 
-	playerCiv, err := readSaveHeader(streamReader)
+	header := Civ5SaveHeader{}
+	playerCiv, err := readSaveHeader(streamReader, &header)
 	if err != nil {
 		return nil, err
 	}
 
-	readGameSettingsAndContent(streamReader)
-	readPlayerAndMapInfo(streamReader)
+	if err := readGameSettingsAndContent(streamReader, &header); err != nil {
+		return nil, fmt.Errorf("failed to read game settings: %w", err)
+	}
+	if err := readPlayerAndMapInfo(streamReader); err != nil {
+		return nil, fmt.Errorf("failed to read player info: %w", err)
+	}
 
-	allCivs := readCivRoster(streamReader)
+	allCivs, err := readCivRoster(streamReader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read civ names: %w", err)
+	}
 
-	readLeadersAndCivArrays(streamReader)
-	readClimateSection(streamReader)
-	readGameNameAndTurnInfo(streamReader)
-	readLeaderArray2AndPlayerSetup(streamReader)
-	readMinorCivNames(streamReader, allCivs)
-	readPlayerArraysAndColors(streamReader, allCivs)
-	readSeaLevelAndWorldSettings(streamReader)
+	if err := readLeadersAndCivArrays(streamReader); err != nil {
+		return nil, fmt.Errorf("failed to read leaders: %w", err)
+	}
+	if err := readClimateSection(streamReader, &header); err != nil {
+		return nil, fmt.Errorf("failed to read climate: %w", err)
+	}
+	if header.GameName, err = readGameNameAndTurnInfo(streamReader); err != nil {
+		return nil, err
+	}
+	if err := readLeaderArray2AndPlayerSetup(streamReader); err != nil {
+		return nil, fmt.Errorf("failed to read player setup: %w", err)
+	}
+	if err := readMinorCivNames(streamReader, allCivs); err != nil {
+		return nil, fmt.Errorf("failed to read minor civ names: %w", err)
+	}
+	if err := readPlayerArraysAndColors(streamReader, allCivs); err != nil {
+		return nil, fmt.Errorf("failed to read player colors: %w", err)
+	}
+	if err := readSeaLevelAndWorldSettings(streamReader, &header); err != nil {
+		return nil, fmt.Errorf("failed to read world settings: %w", err)
+	}
+	reportSaveHeader(&header)
 
-	compressedStreamReader, err := locateCompressedBlock(streamReader, inputFile, saveFileLength)
+	compressedStreamReader, err := locateCompressedBlock(streamReader, r, saveFileLength)
 	if err != nil {
 		return nil, err
 	}
 
-	decompressedStreamReader, decompressedContentsSize, err := buildReaderForDecompressedFile(compressedStreamReader, outputFilename)
+	decompressedStreamReader, decompressedContentsSize, err := buildReaderForDecompressedFile(compressedStreamReader)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decompress file: %w", err)
 	}
-	allReplayEvents := readDecompressed(decompressedStreamReader, decompressedContentsSize)
+	allReplayEvents, err := readDecompressed(decompressedStreamReader, decompressedContentsSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read compressed block: %w", err)
+	}
 
 	return &Civ5SaveData{
 		PlayerCiv:       playerCiv,
 		IsReplayFile:    false,
+		Header:          header,
 		AllCivs:         allCivs,
 		AllReplayEvents: allReplayEvents,
 	}, nil
 }
 
 // readDecompressedHeader reads the decompressed block's version/turn header and two leading unknown sections
-func readDecompressedHeader(streamReader *io.SectionReader) uint32 {
-	saveFileVersion := unsafeReadUint32(streamReader)
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+func readDecompressedHeader(streamReader *io.SectionReader) (uint32, error) {
+	saveFileVersion, err := readUint32(streamReader, "saveFileVersion")
+	if err != nil {
+		return 0, err
+	}
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "uint32", // value is usually 0
 			VariableName: "unknown2",
@@ -1090,51 +1290,62 @@ func readDecompressedHeader(streamReader *io.SectionReader) uint32 {
 			VariableType: "int32",
 			VariableName: "startYear",
 		},
-	})
+	}); err != nil {
+		return 0, err
+	}
 
 	for i := 0; i < 24; i++ {
-		readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+		if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 			{
 				VariableType: "int32",
 				VariableName: fmt.Sprintf("unknownSection1-%d", i),
 			},
-		})
+		}); err != nil {
+			return 0, err
+		}
 	}
 
 	// Seems to be a list of flags
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "bytearray:10",
 			VariableName: "unknownSection2",
 		},
-	})
+	}); err != nil {
+		return 0, err
+	}
 
-	return saveFileVersion
+	return saveFileVersion, nil
 }
 
 // readOptionsAndPadding reads the game options array and a large fixed-size padding block
-func readOptionsAndPadding(streamReader *io.SectionReader) {
-	readArray(streamReader, "optionsArr", []Civ5ReplayFileConfigEntry{
+func readOptionsAndPadding(streamReader *io.SectionReader) error {
+	if err := readArray(streamReader, "optionsArr", []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "varstring",
 			VariableName: "optionsArrName",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "bytearray:1844", // consistent between files
 			VariableName: "unknownSection3",
 		},
-	})
+	}); err != nil {
+		return err
+	}
+	return nil
 }
 
 // readVersionDependentUnitData reads the section whose layout differs between save file versions:
 // newer saves (SaveVersionWithUnitClassData) store named unit/unit-class/building-class arrays,
 // while older saves store a set of fixed-width unknown arrays and blocks instead
-func readVersionDependentUnitData(streamReader *io.SectionReader, saveFileVersion uint32) {
+func readVersionDependentUnitData(streamReader *io.SectionReader, saveFileVersion uint32) error {
 	if saveFileVersion == SaveVersionWithUnitClassData {
-		readArray(streamReader, "unitNameArr", []Civ5ReplayFileConfigEntry{
+		if err := readArray(streamReader, "unitNameArr", []Civ5ReplayFileConfigEntry{
 			{
 				VariableType: "varstring",
 				VariableName: "unitName",
@@ -1143,8 +1354,10 @@ func readVersionDependentUnitData(streamReader *io.SectionReader, saveFileVersio
 				VariableType: "uint32",
 				VariableName: "unknownValue",
 			},
-		})
-		readArray(streamReader, "unitClassArr", []Civ5ReplayFileConfigEntry{
+		}); err != nil {
+			return err
+		}
+		if err := readArray(streamReader, "unitClassArr", []Civ5ReplayFileConfigEntry{
 			{
 				VariableType: "varstring",
 				VariableName: "unitClass",
@@ -1153,8 +1366,10 @@ func readVersionDependentUnitData(streamReader *io.SectionReader, saveFileVersio
 				VariableType: "uint32",
 				VariableName: "unknownValue",
 			},
-		})
-		readArray(streamReader, "buildingClassArr", []Civ5ReplayFileConfigEntry{
+		}); err != nil {
+			return err
+		}
+		if err := readArray(streamReader, "buildingClassArr", []Civ5ReplayFileConfigEntry{
 			{
 				VariableType: "varstring",
 				VariableName: "buildingClass",
@@ -1163,16 +1378,20 @@ func readVersionDependentUnitData(streamReader *io.SectionReader, saveFileVersio
 				VariableType: "uint32",
 				VariableName: "unknownValue",
 			},
-		})
+		}); err != nil {
+			return err
+		}
 
 		// TODO: find padding
-		readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+		if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 			{
 				VariableType: "bytearray:2366", // for RED WW2 save files, but different for other mods
 				VariableName: "unknownPadding",
 			},
-		})
-		return
+		}); err != nil {
+			return err
+		}
+		return nil
 	}
 
 	// Three unknown arrays
@@ -1181,69 +1400,90 @@ func readVersionDependentUnitData(streamReader *io.SectionReader, saveFileVersio
 	// Each array element is 8 bytes. The first 4 bytes are usually consistent between different save files. The last 4 bytes can vary.
 
 	// Array 1 length: Usually 128 or 132, but some files have other values like [127, 154, 157]
-	arrayLength := unsafeReadUint32(streamReader)
+	arrayLength, err := readCount(streamReader, "unknownSection4-1")
+	if err != nil {
+		return err
+	}
 	// Can be one less for some save files
 	if arrayLength >= ArrayLengthCorrectionThreshold {
 		arrayLength = arrayLength - 1
 	}
-	for i := 0; i < int(arrayLength); i++ {
-		readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	for i := 0; i < arrayLength; i++ {
+		if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 			{
 				VariableType: "bytearray:8",
 				VariableName: "unknownSection4-1",
 			},
-		})
+		}); err != nil {
+			return err
+		}
 	}
 
 	// Array 2 length: Usually 83, but can be 85 in a save file when array 1 length is greater than 150
-	readArray(streamReader, "unknownSection4-2", []Civ5ReplayFileConfigEntry{
+	if err := readArray(streamReader, "unknownSection4-2", []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "bytearray:8",
 			VariableName: "unknownSection4-2",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
 	// Array 3 length: Usually 128 or 132, but some files have other values like [127, 153, 156]
-	arrayLength3 := unsafeReadUint32(streamReader)
-	for i := 0; i < int(arrayLength3)-1; i++ {
-		readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	arrayLength3, err := readCount(streamReader, "unknownSection4-3")
+	if err != nil {
+		return err
+	}
+	for i := 0; i < arrayLength3-1; i++ {
+		if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 			{
 				VariableType: "bytearray:8",
 				VariableName: "unknownSection4-3",
 			},
-		})
+		}); err != nil {
+			return err
+		}
 	}
 
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "bytearray:128",
 			VariableName: "unknownSection5-1",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "bytearray:756",
 			VariableName: "unknownSection5-2",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "bytearray:128",
 			VariableName: "unknownSection5-3",
 		},
-	})
+	}); err != nil {
+		return err
+	}
+	return nil
 }
 
 // readGreatPersonAndTrailingBlocks reads the great person array and the fixed-size blocks that follow it
-func readGreatPersonAndTrailingBlocks(streamReader *io.SectionReader) {
-	readArray(streamReader, "greatPersonArr", []Civ5ReplayFileConfigEntry{
+func readGreatPersonAndTrailingBlocks(streamReader *io.SectionReader) error {
+	if err := readArray(streamReader, "greatPersonArr", []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "varstring",
 			VariableName: "greatPersonName",
 		},
-	})
+	}); err != nil {
+		return err
+	}
 
 	for i := 0; i < 2; i++ {
 		// Constant block
@@ -1251,32 +1491,49 @@ func readGreatPersonAndTrailingBlocks(streamReader *io.SectionReader) {
 		// 0 32 0 0 255 255 255 255 255 255 255 255 255 255 255 255
 		// 255 255 255 255 255 255 255 255 255 255 255 255 255 255 255 255
 		// 255 255 255 255 0 0 0 0]
-		readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+		if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 			{
 				VariableType: "bytearray:56",
 				VariableName: "constantBlock",
 			},
-		})
+		}); err != nil {
+			return err
+		}
 	}
 
-	readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
+	if _, err := readFileConfig(streamReader, []Civ5ReplayFileConfigEntry{
 		{
 			VariableType: "bytearray:38",
 			VariableName: "unknownSectionAfterGreatPerson",
 		},
-	})
+	}); err != nil {
+		return err
+	}
+	return nil
 }
 
-func readDecompressed(reader *bytes.Reader, decompressedFileLength int) []Civ5ReplayEvent {
+func readDecompressed(reader *bytes.Reader, decompressedFileLength int) ([]Civ5ReplayEvent, error) {
 	streamReader := io.NewSectionReader(reader, int64(0), int64(decompressedFileLength))
 
-	saveFileVersion := readDecompressedHeader(streamReader)
-	readOptionsAndPadding(streamReader)
-	readVersionDependentUnitData(streamReader, saveFileVersion)
-	readGreatPersonAndTrailingBlocks(streamReader)
+	saveFileVersion, err := readDecompressedHeader(streamReader)
+	if err != nil {
+		return nil, err
+	}
+	if err := readOptionsAndPadding(streamReader); err != nil {
+		return nil, err
+	}
+	if err := readVersionDependentUnitData(streamReader, saveFileVersion); err != nil {
+		return nil, err
+	}
+	if err := readGreatPersonAndTrailingBlocks(streamReader); err != nil {
+		return nil, err
+	}
 
-	allReplayEvents := readEvents(streamReader)
+	allReplayEvents, err := readEvents(streamReader)
+	if err != nil {
+		return nil, err
+	}
 	fmt.Printf("Read %d replay events\n", len(allReplayEvents))
 
-	return allReplayEvents
+	return allReplayEvents, nil
 }
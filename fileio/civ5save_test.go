@@ -0,0 +1,130 @@
+package fileio
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestReadSaveHeaderAndSettings(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("CIV5")
+	binary.Write(&buf, binary.LittleEndian, uint32(8))
+	writeVarString(&buf, "1.0.3.279")
+	writeVarString(&buf, "403694")
+	binary.Write(&buf, binary.LittleEndian, uint32(121))
+	buf.WriteByte(0)
+	writeVarString(&buf, "CIVILIZATION_ROME")
+	for _, s := range []string{"HANDICAP_KING", "ERA_ANCIENT", "ERA_FUTURE", "GAMESPEED_QUICK", "WORLDSIZE_STANDARD", "Assets\\Maps\\Pangaea.lua"} {
+		writeVarString(&buf, s)
+	}
+	binary.Write(&buf, binary.LittleEndian, uint32(1))
+	buf.Write([]byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef, 0, 0, 0, 0, 0, 0, 0, 0x10})
+	binary.Write(&buf, binary.LittleEndian, uint32(0))
+	writeVarString(&buf, "Expansion2")
+	binary.Write(&buf, binary.LittleEndian, uint32(0))
+
+	reader := newSectionReader(buf.Bytes())
+	header := Civ5SaveHeader{}
+	playerCiv, err := readSaveHeader(reader, &header)
+	if err != nil {
+		t.Fatalf("readSaveHeader returned error: %v", err)
+	}
+	if err := readGameSettingsAndContent(reader, &header); err != nil {
+		t.Fatalf("readGameSettingsAndContent returned error: %v", err)
+	}
+
+	want := Civ5SaveHeader{
+		GameVersion: "1.0.3.279",
+		GameBuild:   "403694",
+		Turn:        121,
+		Difficulty:  "HANDICAP_KING",
+		StartEra:    "ERA_ANCIENT",
+		EndEra:      "ERA_FUTURE",
+		GameSpeed:   "GAMESPEED_QUICK",
+		WorldSize:   "WORLDSIZE_STANDARD",
+		MapFilename: "Assets\\Maps\\Pangaea.lua",
+		DLC:         []Civ5DLC{{Id: "0123456789abcdef0000000000000010", Enabled: false, Name: "Expansion2"}},
+		Mods:        []Civ5Mod{},
+	}
+	if playerCiv != "CIVILIZATION_ROME" {
+		t.Errorf("readSaveHeader() playerCiv = %q, want CIVILIZATION_ROME", playerCiv)
+	}
+	if !reflect.DeepEqual(header, want) {
+		t.Errorf("save header = %+v, want %+v", header, want)
+	}
+}
+
+func TestReadGameOptions(t *testing.T) {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint32(3))
+	writeVarString(&buf, "GAMEOPTION_NO_BARBARIANS")
+	binary.Write(&buf, binary.LittleEndian, uint32(1))
+	writeVarString(&buf, "GAMEOPTION_RAGING_BARBARIANS")
+	binary.Write(&buf, binary.LittleEndian, uint32(0))
+	writeVarString(&buf, "GAMEOPTION_QUICK_COMBAT")
+	binary.Write(&buf, binary.LittleEndian, uint32(1))
+
+	options, err := readGameOptions(newSectionReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("readGameOptions returned error: %v", err)
+	}
+	want := []string{"GAMEOPTION_NO_BARBARIANS", "GAMEOPTION_QUICK_COMBAT"}
+	if !reflect.DeepEqual(options, want) {
+		t.Errorf("readGameOptions() = %v, want %v", options, want)
+	}
+}
+
+func TestReadGameOptionsTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint32(2))
+	writeVarString(&buf, "GAMEOPTION_NO_BARBARIANS")
+
+	if _, err := readGameOptions(newSectionReader(buf.Bytes())); err == nil {
+		t.Error("readGameOptions(truncated) = nil error, want an error")
+	}
+}
+
+func TestReadSeaLevel(t *testing.T) {
+	var buf bytes.Buffer
+	writeVarString(&buf, "Low")
+	buf.Write(make([]byte, 12))
+	writeVarString(&buf, "SEALEVEL_LOW")
+	writeVarString(&buf, "TXT_KEY_SEALEVEL_LOW")
+	writeVarString(&buf, "Low")
+	buf.Write(make([]byte, 5))
+
+	seaLevel, err := readSeaLevel(newSectionReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("readSeaLevel returned error: %v", err)
+	}
+	if seaLevel != "SEALEVEL_LOW" {
+		t.Errorf("readSeaLevel() = %q, want SEALEVEL_LOW", seaLevel)
+	}
+}
+
+func TestBuildReaderForDecompressedFileInMemory(t *testing.T) {
+	var compressed bytes.Buffer
+	writer := zlib.NewWriter(&compressed)
+	writer.Write(bytes.Repeat([]byte("civ5"), 64))
+	writer.Close()
+
+	// Drop the checksum so the block is damaged, which is the case that used to write a side file
+	damaged := compressed.Bytes()[:compressed.Len()-4]
+	workDir := t.TempDir()
+	t.Chdir(workDir)
+
+	reader, size, err := buildReaderForDecompressedFile(newSectionReader(damaged))
+	if err != nil {
+		t.Fatalf("buildReaderForDecompressedFile returned error: %v", err)
+	}
+	if size != 256 || reader.Len() != 256 {
+		t.Errorf("decompressed size = %d, want 256", size)
+	}
+	if entries, _ := os.ReadDir(workDir); len(entries) != 0 {
+		t.Errorf("buildReaderForDecompressedFile wrote %d files, want none", len(entries))
+	}
+}
@@ -0,0 +1,336 @@
+package fileio
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Civ5CompactMapJson is a smaller form of Civ5MapJson for maps kept in git. Instead of one object
+// per tile, each tile field is stored as a grid with one run-length encoded string per map row,
+// so a change to a few tiles only changes the rows they are on. MapData holds everything else
+// and has no MapTiles or MapTileImprovements.
+type Civ5CompactMapJson struct {
+	GameName      string
+	FileFormat    string
+	SchemaVersion int
+	Width         int
+	Height        int
+	MapData       *Civ5MapData
+	Tiles         map[string][]string // Civ5MapTilePhysical field name -> encoded rows
+	Improvements  map[string][]string // Civ5MapTileImprovement field name -> encoded rows, empty if the map has none
+	CityNames     []CompactCityName
+}
+
+// CompactCityName is the CityName of the tile at X, Y, which are the only strings on a tile
+type CompactCityName struct {
+	X    int
+	Y    int
+	Name string
+}
+
+// The tile fields stored in a compact map. X and Y aren't stored since they are the position of
+// the tile in the grid.
+var (
+	compactTileFields = []string{
+		"TerrainType", "ResourceType", "FeatureTerrainType", "RiverData",
+		"Elevation", "Continent", "FeatureWonderType", "ResourceAmount",
+	}
+	compactImprovementFields = []string{
+		"CityId", "UnitId", "Owner", "Improvement", "RouteType", "RouteOwner",
+	}
+)
+
+// encodeCompactRow run-length encodes a row of values as comma-separated entries, where a run of
+// the same value is written as value*count, e.g. 3*4,0,1*2 for 3 3 3 3 0 1 1
+func encodeCompactRow(values []int) string {
+	var builder strings.Builder
+	for start := 0; start < len(values); {
+		end := start + 1
+		for end < len(values) && values[end] == values[start] {
+			end++
+		}
+		if start > 0 {
+			builder.WriteByte(',')
+		}
+		builder.WriteString(strconv.Itoa(values[start]))
+		if end-start > 1 {
+			builder.WriteByte('*')
+			builder.WriteString(strconv.Itoa(end - start))
+		}
+		start = end
+	}
+	return builder.String()
+}
+
+// decodeCompactRow expands a row written by encodeCompactRow, which must hold width values
+func decodeCompactRow(row string, width int) ([]int, error) {
+	values := make([]int, 0, width)
+	if row == "" {
+		if width != 0 {
+			return nil, fmt.Errorf("row is empty, expected %d values", width)
+		}
+		return values, nil
+	}
+
+	for _, entry := range strings.Split(row, ",") {
+		valueText, countText, isRun := strings.Cut(entry, "*")
+		value, err := strconv.Atoi(valueText)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q", entry)
+		}
+		count := 1
+		if isRun {
+			count, err = strconv.Atoi(countText)
+			if err != nil || count < 1 {
+				return nil, fmt.Errorf("invalid run length %q", entry)
+			}
+		}
+		if count > width-len(values) {
+			return nil, fmt.Errorf("row has more than %d values", width)
+		}
+		for i := 0; i < count; i++ {
+			values = append(values, value)
+		}
+	}
+	if len(values) != width {
+		return nil, fmt.Errorf("row has %d values, expected %d", len(values), width)
+	}
+	return values, nil
+}
+
+// tileGridField returns the named int field of every tile in grid, which is a
+// [][]*Civ5MapTilePhysical or [][]*Civ5MapTileImprovement
+func tileGridField(grid interface{}, fieldName string) ([][]int, error) {
+	gridValue := reflect.ValueOf(grid)
+	values := make([][]int, gridValue.Len())
+	for i := range values {
+		row := gridValue.Index(i)
+		values[i] = make([]int, row.Len())
+		for j := range values[i] {
+			tile := row.Index(j)
+			if tile.IsNil() {
+				return nil, fmt.Errorf("tile at (%d, %d) is missing", j, i)
+			}
+			values[i][j] = int(tile.Elem().FieldByName(fieldName).Int())
+		}
+	}
+	return values, nil
+}
+
+// setTileGridField sets the named int field of every tile in grid. values must have the same
+// size as grid.
+func setTileGridField(grid interface{}, fieldName string, values [][]int) {
+	gridValue := reflect.ValueOf(grid)
+	for i, rowValues := range values {
+		row := gridValue.Index(i)
+		for j, value := range rowValues {
+			row.Index(j).Elem().FieldByName(fieldName).SetInt(int64(value))
+		}
+	}
+}
+
+// newPhysicalTileGrid returns a grid of empty tiles with their X and Y set
+func newPhysicalTileGrid(width, height int) [][]*Civ5MapTilePhysical {
+	mapTiles := make([][]*Civ5MapTilePhysical, height)
+	for i := range mapTiles {
+		mapTiles[i] = make([]*Civ5MapTilePhysical, width)
+		for j := range mapTiles[i] {
+			mapTiles[i][j] = &Civ5MapTilePhysical{X: j, Y: i}
+		}
+	}
+	return mapTiles
+}
+
+// newImprovementTileGrid returns a grid of empty tiles with their X and Y set
+func newImprovementTileGrid(width, height int) [][]*Civ5MapTileImprovement {
+	mapTiles := make([][]*Civ5MapTileImprovement, height)
+	for i := range mapTiles {
+		mapTiles[i] = make([]*Civ5MapTileImprovement, width)
+		for j := range mapTiles[i] {
+			mapTiles[i][j] = &Civ5MapTileImprovement{X: j, Y: i}
+		}
+	}
+	return mapTiles
+}
+
+// checkTileGrids checks that every row of the tile grids of mapData has the same width and that
+// the improvement grid is either empty or the same size as the physical grid. It returns the
+// width and height of the map.
+func checkTileGrids(mapData *Civ5MapData) (int, int, error) {
+	height := len(mapData.MapTiles)
+	width := 0
+	if height > 0 {
+		width = len(mapData.MapTiles[0])
+	}
+	for i, row := range mapData.MapTiles {
+		if len(row) != width {
+			return 0, 0, fmt.Errorf("map tile row %d has %d tiles, expected %d", i, len(row), width)
+		}
+	}
+	if len(mapData.MapTileImprovements) == 0 {
+		return width, height, nil
+	}
+	if len(mapData.MapTileImprovements) != height {
+		return 0, 0, fmt.Errorf("map has %d improvement rows, expected %d", len(mapData.MapTileImprovements), height)
+	}
+	for i, row := range mapData.MapTileImprovements {
+		if len(row) != width {
+			return 0, 0, fmt.Errorf("improvement row %d has %d tiles, expected %d", i, len(row), width)
+		}
+	}
+	return width, height, nil
+}
+
+// encodeCompactGrid encodes the named int field of every tile in grid as one row string per row
+func encodeCompactGrid(grid interface{}, fieldName string) ([]string, error) {
+	values, err := tileGridField(grid, fieldName)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]string, len(values))
+	for i, rowValues := range values {
+		rows[i] = encodeCompactRow(rowValues)
+	}
+	return rows, nil
+}
+
+// decodeCompactGrid sets the named int field of every tile in a width x height grid from the
+// encoded rows
+func decodeCompactGrid(grid interface{}, fieldName string, rows []string, width, height int) error {
+	if len(rows) != height {
+		return fmt.Errorf("%s has %d rows, expected %d", fieldName, len(rows), height)
+	}
+	values := make([][]int, height)
+	for i, rowText := range rows {
+		rowValues, err := decodeCompactRow(rowText, width)
+		if err != nil {
+			return fmt.Errorf("%s row %d: %w", fieldName, i, err)
+		}
+		values[i] = rowValues
+	}
+	setTileGridField(grid, fieldName, values)
+	return nil
+}
+
+// NewCiv5CompactMapJson converts mapData to the compact document. mapData isn't modified.
+func NewCiv5CompactMapJson(mapData *Civ5MapData) (*Civ5CompactMapJson, error) {
+	width, height, err := checkTileGrids(mapData)
+	if err != nil {
+		return nil, err
+	}
+
+	otherData := *mapData
+	otherData.MapTiles = nil
+	otherData.MapTileImprovements = nil
+	compactJson := &Civ5CompactMapJson{
+		GameName:      "Civilization 5",
+		FileFormat:    ".Civ5MapCompact",
+		SchemaVersion: CurrentSchemaVersion,
+		Width:         width,
+		Height:        height,
+		MapData:       &otherData,
+		Tiles:         map[string][]string{},
+		CityNames:     []CompactCityName{},
+	}
+
+	for _, fieldName := range compactTileFields {
+		rows, err := encodeCompactGrid(mapData.MapTiles, fieldName)
+		if err != nil {
+			return nil, err
+		}
+		compactJson.Tiles[fieldName] = rows
+	}
+	if len(mapData.MapTileImprovements) == 0 {
+		return compactJson, nil
+	}
+
+	compactJson.Improvements = map[string][]string{}
+	for _, fieldName := range compactImprovementFields {
+		rows, err := encodeCompactGrid(mapData.MapTileImprovements, fieldName)
+		if err != nil {
+			return nil, err
+		}
+		compactJson.Improvements[fieldName] = rows
+	}
+	for i, row := range mapData.MapTileImprovements {
+		for j, tile := range row {
+			if tile.CityName != "" {
+				compactJson.CityNames = append(compactJson.CityNames, CompactCityName{X: j, Y: i, Name: tile.CityName})
+			}
+		}
+	}
+	return compactJson, nil
+}
+
+// ToMapData expands the compact document back into the Civ5MapData it was made from
+func (compactJson *Civ5CompactMapJson) ToMapData() (*Civ5MapData, error) {
+	if compactJson.MapData == nil {
+		return nil, fmt.Errorf("compact map has no MapData")
+	}
+	// Check each dimension before multiplying so that the area can't overflow
+	for _, dimension := range []int{compactJson.Width, compactJson.Height} {
+		if dimension < 0 || dimension > MaxArrayLength {
+			return nil, &ErrImplausibleLength{Section: "compact map size", Length: int64(dimension)}
+		}
+	}
+	if compactJson.Width*compactJson.Height > MaxArrayLength {
+		return nil, &ErrImplausibleLength{Section: "compact map size", Length: int64(compactJson.Width) * int64(compactJson.Height)}
+	}
+	mapData := *compactJson.MapData
+
+	mapData.MapTiles = newPhysicalTileGrid(compactJson.Width, compactJson.Height)
+	for _, fieldName := range compactTileFields {
+		rows, found := compactJson.Tiles[fieldName]
+		if !found {
+			return nil, fmt.Errorf("compact map is missing tile field %s", fieldName)
+		}
+		if err := decodeCompactGrid(mapData.MapTiles, fieldName, rows, compactJson.Width, compactJson.Height); err != nil {
+			return nil, err
+		}
+	}
+	if len(compactJson.Improvements) == 0 {
+		return &mapData, nil
+	}
+
+	mapData.MapTileImprovements = newImprovementTileGrid(compactJson.Width, compactJson.Height)
+	for _, fieldName := range compactImprovementFields {
+		rows, found := compactJson.Improvements[fieldName]
+		if !found {
+			return nil, fmt.Errorf("compact map is missing improvement field %s", fieldName)
+		}
+		if err := decodeCompactGrid(mapData.MapTileImprovements, fieldName, rows, compactJson.Width, compactJson.Height); err != nil {
+			return nil, err
+		}
+	}
+	for _, cityName := range compactJson.CityNames {
+		if cityName.X < 0 || cityName.X >= compactJson.Width || cityName.Y < 0 || cityName.Y >= compactJson.Height {
+			return nil, fmt.Errorf("city name %q at (%d, %d) is outside the map", cityName.Name, cityName.X, cityName.Y)
+		}
+		mapData.MapTileImprovements[cityName.Y][cityName.X].CityName = cityName.Name
+	}
+	return &mapData, nil
+}
+
+// ExportCiv5MapFileCompact writes mapData to outputFilename as a Civ5CompactMapJson document
+func ExportCiv5MapFileCompact(mapData *Civ5MapData, outputFilename string) error {
+	compactJson, err := NewCiv5CompactMapJson(mapData)
+	if err != nil {
+		return fmt.Errorf("failed to build compact map: %w", err)
+	}
+
+	file, err := json.MarshalIndent(compactJson, "", " ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal map data: %w", err)
+	}
+
+	err = os.WriteFile(outputFilename, file, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write to %q: %w", outputFilename, err)
+	}
+
+	return nil
+}
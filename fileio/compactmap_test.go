@@ -0,0 +1,139 @@
+package fileio
+
+import (
+	"errors"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCompactRowRoundTrip(t *testing.T) {
+	tests := []struct {
+		values []int
+		want   string
+	}{
+		{[]int{3, 3, 3, 3, 0, 1, 1}, "3*4,0,1*2"},
+		{[]int{-1, -1, 5}, "-1*2,5"},
+		{[]int{7}, "7"},
+		{[]int{}, ""},
+	}
+	for _, tt := range tests {
+		got := encodeCompactRow(tt.values)
+		if got != tt.want {
+			t.Errorf("encodeCompactRow(%v) = %q, want %q", tt.values, got, tt.want)
+		}
+		decoded, err := decodeCompactRow(got, len(tt.values))
+		if err != nil {
+			t.Fatalf("decodeCompactRow(%q) returned error: %v", got, err)
+		}
+		if !reflect.DeepEqual(decoded, tt.values) {
+			t.Errorf("decodeCompactRow(%q) = %v, want %v", got, decoded, tt.values)
+		}
+	}
+}
+
+func TestDecodeCompactRowErrors(t *testing.T) {
+	tests := []struct {
+		row   string
+		width int
+	}{
+		{"1*3", 2},
+		{"1,2", 3},
+		{"x", 1},
+		{"1*0", 0},
+		{"1*-2", 2},
+		{"", 1},
+	}
+	for _, tt := range tests {
+		if _, err := decodeCompactRow(tt.row, tt.width); err == nil {
+			t.Errorf("decodeCompactRow(%q, %d) = nil error, want an error", tt.row, tt.width)
+		}
+	}
+}
+
+func TestCompactMapRoundTrip(t *testing.T) {
+	mapData := newWriterTestMapData(12)
+	mapData.MapTileImprovements[0][1].CityName = "Roma"
+
+	filename := filepath.Join(t.TempDir(), "compact.json")
+	if err := ExportCiv5MapFileCompact(mapData, filename); err != nil {
+		t.Fatalf("ExportCiv5MapFileCompact returned error: %v", err)
+	}
+	imported, err := ImportCiv5MapFileFromJson(filename)
+	if err != nil {
+		t.Fatalf("ImportCiv5MapFileFromJson returned error: %v", err)
+	}
+	if !reflect.DeepEqual(imported, mapData) {
+		t.Errorf("compact map round trip = %+v, want %+v", imported, mapData)
+	}
+}
+
+func TestCompactMapWithoutImprovements(t *testing.T) {
+	mapData := newWriterTestMapData(12)
+	mapData.MapTileImprovements = nil
+
+	compactJson, err := NewCiv5CompactMapJson(mapData)
+	if err != nil {
+		t.Fatalf("NewCiv5CompactMapJson returned error: %v", err)
+	}
+	decoded, err := compactJson.ToMapData()
+	if err != nil {
+		t.Fatalf("ToMapData returned error: %v", err)
+	}
+	if decoded.MapTileImprovements != nil {
+		t.Errorf("MapTileImprovements = %v, want nil", decoded.MapTileImprovements)
+	}
+	if !reflect.DeepEqual(decoded.MapTiles, mapData.MapTiles) {
+		t.Errorf("MapTiles = %v, want %v", decoded.MapTiles, mapData.MapTiles)
+	}
+}
+
+func TestCompactMapRejectsBadGrids(t *testing.T) {
+	compactJson, err := NewCiv5CompactMapJson(newWriterTestMapData(12))
+	if err != nil {
+		t.Fatalf("NewCiv5CompactMapJson returned error: %v", err)
+	}
+	compactJson.Tiles["TerrainType"] = compactJson.Tiles["TerrainType"][1:]
+	if _, err := compactJson.ToMapData(); err == nil {
+		t.Error("ToMapData(missing row) = nil error, want an error")
+	}
+
+	delete(compactJson.Tiles, "TerrainType")
+	if _, err := compactJson.ToMapData(); err == nil {
+		t.Error("ToMapData(missing field) = nil error, want an error")
+	}
+
+	// The area of this size overflows int and would wrap around to a negative number
+	compactJson.Width, compactJson.Height = math.MaxInt/2+1, 2
+	var lengthErr *ErrImplausibleLength
+	if _, err := compactJson.ToMapData(); !errors.As(err, &lengthErr) {
+		t.Errorf("ToMapData(%dx%d) error = %v, want an ErrImplausibleLength", compactJson.Width, compactJson.Height, err)
+	}
+}
+
+// TestCompactMapRoundTripCommittedMaps converts the maps in the maps directory, which cover the
+// city names and owners of real scenarios
+func TestCompactMapRoundTripCommittedMaps(t *testing.T) {
+	filenames, err := filepath.Glob(filepath.Join("..", "maps", "*.json"))
+	if err != nil {
+		t.Fatalf("Glob returned error: %v", err)
+	}
+	for _, filename := range filenames {
+		mapData, err := ImportCiv5MapFileFromJson(filename)
+		if err != nil {
+			t.Fatalf("ImportCiv5MapFileFromJson(%q) returned error: %v", filename, err)
+		}
+		compactJson, err := NewCiv5CompactMapJson(mapData)
+		if err != nil {
+			t.Fatalf("NewCiv5CompactMapJson(%q) returned error: %v", filename, err)
+		}
+		decoded, err := compactJson.ToMapData()
+		if err != nil {
+			t.Fatalf("ToMapData(%q) returned error: %v", filename, err)
+		}
+		if !reflect.DeepEqual(decoded, mapData) {
+			t.Errorf("%s changed in the compact map round trip", filename)
+		}
+	}
+}
@@ -1,9 +1,13 @@
 package fileio
 
 import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
-	"path/filepath"
 	"strings"
 )
 
@@ -15,14 +19,199 @@ const (
 	FileTypeCiv5Replay FileType = ".civ5replay"
 	FileTypeCiv5Save   FileType = ".civ5save"
 	FileTypeJSON       FileType = ".json"
+	// FileTypeCiv5MapCompact is the FileFormat of a Civ5CompactMapJson document, which is
+	// detected as FileTypeJSON like the other exports
+	FileTypeCiv5MapCompact FileType = ".civ5mapcompact"
+	// FileTypeCiv5MapText is a map written by WriteCiv5MapText
+	FileTypeCiv5MapText FileType = ".civ5maptext"
+	FileTypeUnknown     FileType = ""
 )
 
+// Replay and save files both begin with this game name, followed by a uint32
+var civ5GameNameMagic = []byte("CIV5")
+
+// The compressed block of a save follows padding ending in [1 0] and starts with a zlib header
+var saveCompressedBlockMarker = []byte{0x01, 0x00, 0x78, 0x9C}
+
+// detectPrefixSize is how much of the start of a file DetectFileType reads to check the headers
+// of maps and text maps. It's also the size of the chunks that a save is scanned in.
+const detectPrefixSize = 64 * 1024
+
+// DetectFileType works out the type of a file of the given size from its contents rather than
+// its extension. Replays and saves start with the CIV5 game name and saves also have a zlib block
+// after the header, which can be anywhere in the file since the header holds the DLC, mod and
+// player lists. Maps have no magic number, so
+// the header has to have a plausible version and sizes that fit in the file. JSON files must
+// have a FileFormat written by one of the exporters and text maps start with a Civ5MapText line.
+// FileTypeUnknown is returned if none of these match.
+func DetectFileType(r io.ReaderAt, size int64) (FileType, error) {
+	prefix := make([]byte, min(size, detectPrefixSize))
+	if _, err := r.ReadAt(prefix, 0); err != nil && err != io.EOF {
+		return FileTypeUnknown, fmt.Errorf("failed to read file contents: %w", err)
+	}
+
+	switch {
+	case bytes.HasPrefix(prefix, civ5GameNameMagic):
+		if hasSaveCompressedBlock(r, size) {
+			return FileTypeCiv5Save, nil
+		}
+		return FileTypeCiv5Replay, nil
+	case isJsonExport(io.NewSectionReader(r, 0, size)):
+		return FileTypeJSON, nil
+	case bytes.HasPrefix(prefix, []byte(textMapMagic+" ")):
+		return FileTypeCiv5MapText, nil
+	case isPlausibleMap(prefix, size):
+		return FileTypeCiv5Map, nil
+	}
+	return FileTypeUnknown, nil
+}
+
+// DetectFileTypeFromFile opens filename and calls DetectFileType on it. A file whose type can't
+// be detected is an error.
+func DetectFileTypeFromFile(filename string) (FileType, error) {
+	inputFile, size, err := openInputFile(filename)
+	if err != nil {
+		return FileTypeUnknown, fmt.Errorf("failed to open file %q: %w", filename, err)
+	}
+	defer inputFile.Close()
+
+	fileType, err := DetectFileType(inputFile, size)
+	if err != nil {
+		return FileTypeUnknown, fmt.Errorf("failed to detect type of %q: %w", filename, err)
+	}
+	if fileType == FileTypeUnknown {
+		return FileTypeUnknown, fmt.Errorf("%q isn't a .civ5map, .civ5replay, .civ5save, exported .json or text map file", filename)
+	}
+	return fileType, nil
+}
+
+// hasSaveCompressedBlock checks a file for a zlib stream after the save marker that can actually
+// be inflated. The file is scanned in chunks, so a long header doesn't need to be read into
+// memory at once.
+func hasSaveCompressedBlock(r io.ReaderAt, size int64) bool {
+	// Consecutive chunks overlap so that a marker split between them is still found
+	overlap := int64(len(saveCompressedBlockMarker) - 1)
+	chunk := make([]byte, detectPrefixSize)
+	for chunkStart := int64(0); chunkStart < size; chunkStart += int64(len(chunk)) - overlap {
+		n, err := r.ReadAt(chunk[:min(int64(len(chunk)), size-chunkStart)], chunkStart)
+		if err != nil && err != io.EOF {
+			return false
+		}
+		for offset := 0; offset < n; {
+			index := bytes.Index(chunk[offset:n], saveCompressedBlockMarker)
+			if index < 0 {
+				break
+			}
+			blockStart := chunkStart + int64(offset+index+2)
+			if isZlibStream(io.NewSectionReader(r, blockStart, size-blockStart)) {
+				return true
+			}
+			offset += index + 1
+		}
+		if chunkStart+int64(n) >= size {
+			break
+		}
+	}
+	return false
+}
+
+// isZlibStream checks that r starts with a zlib stream that at least one byte can be inflated from
+func isZlibStream(r io.Reader) bool {
+	zlibReader, err := zlib.NewReader(r)
+	if err != nil {
+		return false
+	}
+	defer zlibReader.Close()
+	_, err = zlibReader.Read(make([]byte, 1))
+	return err == nil || err == io.EOF
+}
+
+// isJsonExport checks for a JSON object with the FileFormat of a map, compact map, replay or
+// save. The object is scanned a token at a time and the scan stops at FileFormat, which the
+// exporters write near the start, so the rest of the document isn't read.
+func isJsonExport(r io.Reader) bool {
+	decoder := json.NewDecoder(r)
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return false
+	}
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return false
+		}
+		if key != "FileFormat" {
+			if err := skipJsonValue(decoder); err != nil {
+				return false
+			}
+			continue
+		}
+		value, err := decoder.Token()
+		fileFormat, ok := value.(string)
+		if err != nil || !ok {
+			return false
+		}
+		switch FileType(strings.ToLower(fileFormat)) {
+		case FileTypeCiv5Map, FileTypeCiv5MapCompact, FileTypeCiv5Replay, FileTypeCiv5Save:
+			return true
+		}
+		return false
+	}
+	return false
+}
+
+// skipJsonValue reads past the next value in decoder, including every token of an object or array
+func skipJsonValue(decoder *json.Decoder) error {
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// isPlausibleMap checks that prefix starts with a map header with a version, a map size that
+// isn't empty or absurd, and lists and tiles that fit in the file's size. Versions without a
+// known layout are still detected, so that the parser can report them as unsupported.
+func isPlausibleMap(prefix []byte, size int64) bool {
+	header := Civ5MapHeader{}
+	if err := binary.Read(bytes.NewReader(prefix), binary.LittleEndian, &header); err != nil {
+		return false
+	}
+	version := mapVersion(header.ScenarioVersion)
+	if version == 0 {
+		return false
+	}
+	if header.Width == 0 || header.Height == 0 || uint64(header.Width)*uint64(header.Height) > MaxArrayLength {
+		return false
+	}
+
+	requiredSize := uint64(binary.Size(header)) +
+		uint64(header.TerrainDataSize) + uint64(header.FeatureTerrainDataSize) +
+		uint64(header.FeatureWonderDataSize) + uint64(header.ResourceDataSize) +
+		uint64(header.ModDataSize) + uint64(header.MapNameLength) + uint64(header.MapDescriptionLength) +
+		uint64(header.Width)*uint64(header.Height)*uint64(binary.Size(Civ5MapTile{}))
+	return requiredSize <= uint64(size)
+}
+
 // ExportFileToJson exports a Civilization 5 file to JSON format
 func ExportFileToJson(inputFilename string, outputFilename string) {
-	inputFileExtension := filepath.Ext(inputFilename)
+	fileType, err := DetectFileTypeFromFile(inputFilename)
+	if err != nil {
+		log.Fatal("Unable to export file to json: ", err)
+	}
 
-	switch strings.ToLower(inputFileExtension) {
-	case string(FileTypeCiv5Map):
+	switch fileType {
+	case FileTypeCiv5Map:
 		fmt.Println("Reading civ5map file")
 		mapData, err := ReadCiv5MapFile(inputFilename)
 		if err != nil {
@@ -33,7 +222,7 @@ func ExportFileToJson(inputFilename string, outputFilename string) {
 		if err := ExportCiv5MapFile(mapData, outputFilename); err != nil {
 			log.Fatal("Failed to export map: ", err)
 		}
-	case string(FileTypeCiv5Replay):
+	case FileTypeCiv5Replay:
 		fmt.Println("Importing civ5replay data")
 		replayData, err := ReadCiv5ReplayFile(inputFilename)
 		if err != nil {
@@ -44,9 +233,9 @@ func ExportFileToJson(inputFilename string, outputFilename string) {
 		if err := ExportCiv5ReplayFile(replayData, outputFilename); err != nil {
 			log.Fatal("Failed to export replay: ", err)
 		}
-	case string(FileTypeCiv5Save):
+	case FileTypeCiv5Save:
 		fmt.Println("Reading civ5save file")
-		saveData, err := ReadCiv5SaveFile(inputFilename, outputFilename+".decomp")
+		saveData, err := ReadCiv5SaveFile(inputFilename)
 		if err != nil {
 			log.Fatal("Failed to read save data: ", err)
 		}
@@ -56,23 +245,26 @@ func ExportFileToJson(inputFilename string, outputFilename string) {
 			log.Fatal("Failed to export save: ", err)
 		}
 	default:
-		log.Fatal("Unable to export file", inputFilename, "to json")
+		log.Fatal("Unable to export file ", inputFilename, " to json, it is already a ", fileType, " file")
 	}
 }
 
-// LoadReplayDataFromFile loads replay data from a file (either .civ5replay or .json)
+// LoadReplayDataFromFile loads replay data from a file (either a .civ5replay or a .json export)
 func LoadReplayDataFromFile(replayFilename string) *Civ5ReplayData {
-	replayFileExtension := filepath.Ext(replayFilename)
+	fileType, err := DetectFileTypeFromFile(replayFilename)
+	if err != nil {
+		log.Fatal("Failed to read replay data: ", err)
+	}
 
-	switch strings.ToLower(replayFileExtension) {
-	case string(FileTypeCiv5Replay):
+	switch fileType {
+	case FileTypeCiv5Replay:
 		fmt.Println("Reading replay from .civ5replay file")
 		replayData, err := ReadCiv5ReplayFile(replayFilename)
 		if err != nil {
 			log.Fatal("Failed to read replay data: ", err)
 		}
 		return replayData
-	case string(FileTypeJSON):
+	case FileTypeJSON:
 		fmt.Println("Importing replay data from json")
 		replayData, err := ImportCiv5ReplayFileFromJson(replayFilename)
 		if err != nil {
@@ -80,7 +272,7 @@ func LoadReplayDataFromFile(replayFilename string) *Civ5ReplayData {
 		}
 		return replayData
 	default:
-		log.Fatal("Replay file has invalid file extension")
+		log.Fatalf("Replay file %s is a %s file, not a replay", replayFilename, fileType)
 	}
 	return nil
 }
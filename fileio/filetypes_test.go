@@ -0,0 +1,139 @@
+package fileio
+
+import (
+	"bytes"
+	"compress/zlib"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectFileType(t *testing.T) {
+	var mapBuf bytes.Buffer
+	if err := WriteCiv5MapFile(newWriterTestMapData(MapVersion12), &mapBuf); err != nil {
+		t.Fatalf("WriteCiv5MapFile returned error: %v", err)
+	}
+
+	// A replay that happens to contain the marker bytes but no zlib stream after them
+	replayWithMarker := append(newFuzzReplayBytes(), 0x01, 0x00, 0x78, 0x9C, 0xFF, 0xFF)
+
+	// Saves with long DLC, mod and player lists have their compressed block far into the file
+	var lateBlock bytes.Buffer
+	lateBlock.Write(newFuzzReplayBytes())
+	lateBlock.Write(make([]byte, detectPrefixSize))
+	lateBlock.Write([]byte{2, 0, 0, 0, 0, 0, 1, 0})
+	writer := zlib.NewWriter(&lateBlock)
+	writer.Write(newFuzzDecompressedBytes())
+	writer.Close()
+
+	// The marker of this block is split between the first two chunks that a save is scanned in
+	var splitBlock bytes.Buffer
+	splitBlock.Write(newFuzzReplayBytes())
+	splitBlock.Write(make([]byte, detectPrefixSize-2-6-splitBlock.Len()))
+	splitBlock.Write([]byte{2, 0, 0, 0, 0, 0, 1, 0})
+	writer = zlib.NewWriter(&splitBlock)
+	writer.Write(newFuzzDecompressedBytes())
+	writer.Close()
+
+	tests := []struct {
+		name string
+		data []byte
+		want FileType
+	}{
+		{"map", mapBuf.Bytes(), FileTypeCiv5Map},
+		{"replay", newFuzzReplayBytes(), FileTypeCiv5Replay},
+		{"replay with marker bytes", replayWithMarker, FileTypeCiv5Replay},
+		{"save", newFuzzSaveBytes(), FileTypeCiv5Save},
+		{"save with a long header", lateBlock.Bytes(), FileTypeCiv5Save},
+		{"save marker across chunks", splitBlock.Bytes(), FileTypeCiv5Save},
+		{"map json", []byte(`{"GameName": "Civilization 5", "FileFormat": ".Civ5Map", "MapData": {}}`), FileTypeJSON},
+		{"replay json", []byte(` {"FileFormat": ".Civ5Replay"}`), FileTypeJSON},
+		{"compact map json", []byte(`{"FileFormat": ".Civ5MapCompact", "SchemaVersion": 1}`), FileTypeJSON},
+		{"json with FileFormat last", []byte(`{"MapData": {"Tiles": [[1, {"A": "B"}], []]}, "FileFormat": ".Civ5Map"}`), FileTypeJSON},
+		{"json cut off before FileFormat", []byte(`{"MapData": {"Tiles": [[1, 2`), FileTypeUnknown},
+		{"text map", []byte("Civ5MapText 1\nsize 0 0\ndata\n{}\n"), FileTypeCiv5MapText},
+		{"other json", []byte(`{"FileFormat": ".txt"}`), FileTypeUnknown},
+		{"truncated map", mapBuf.Bytes()[:50], FileTypeUnknown},
+		{"text", []byte("not a civ5 file at all"), FileTypeUnknown},
+		{"empty", []byte{}, FileTypeUnknown},
+	}
+	for _, tt := range tests {
+		got, err := DetectFileType(bytes.NewReader(tt.data), int64(len(tt.data)))
+		if err != nil {
+			t.Errorf("%s: DetectFileType returned error: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: DetectFileType() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDetectFileTypeFromFileIgnoresExtension(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "replay-from-discord")
+	if err := os.WriteFile(filename, newFuzzReplayBytes(), 0644); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+	if got, err := DetectFileTypeFromFile(filename); err != nil || got != FileTypeCiv5Replay {
+		t.Errorf("DetectFileTypeFromFile() = %q, %v, want %q", got, err, FileTypeCiv5Replay)
+	}
+
+	misnamed := filepath.Join(dir, "notes.civ5map")
+	if err := os.WriteFile(misnamed, []byte("hello"), 0644); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+	if _, err := DetectFileTypeFromFile(misnamed); err == nil {
+		t.Error("DetectFileTypeFromFile(text file) = nil error, want an error")
+	}
+}
+
+func TestExportSaveToJsonWritesNoSideFile(t *testing.T) {
+	dir := t.TempDir()
+	// Drop the checksum so the compressed block is damaged, which is when a .decomp file used to
+	// be written next to the output
+	save := newFuzzSaveBytes()
+	inputFilename := filepath.Join(dir, "autosave.Civ5Save")
+	if err := os.WriteFile(inputFilename, save[:len(save)-4], 0644); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+	outputFilename := filepath.Join(dir, "autosave.json")
+	ExportFileToJson(inputFilename, outputFilename)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to list %s: %v", dir, err)
+	}
+	if len(entries) != 2 {
+		names := []string{}
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		t.Errorf("ExportFileToJson left %v, want only the input and the json", names)
+	}
+	if _, err := ImportCiv5ReplayFileFromJson(outputFilename); err != nil {
+		t.Errorf("ImportCiv5ReplayFileFromJson returned error: %v", err)
+	}
+}
+
+func TestImportJsonChecksFileFormat(t *testing.T) {
+	dir := t.TempDir()
+	replayJson := filepath.Join(dir, "replay.json")
+	if err := ExportCiv5ReplayFile(&Civ5ReplayData{}, replayJson); err != nil {
+		t.Fatalf("ExportCiv5ReplayFile returned error: %v", err)
+	}
+	if _, err := ImportCiv5MapFileFromJson(replayJson); err == nil {
+		t.Error("ImportCiv5MapFileFromJson(replay json) = nil error, want an error")
+	}
+	if _, err := ImportCiv5ReplayFileFromJson(replayJson); err != nil {
+		t.Errorf("ImportCiv5ReplayFileFromJson returned error: %v", err)
+	}
+
+	saveJson := filepath.Join(dir, "save.json")
+	if err := ExportCiv5SaveFile(&Civ5SaveData{}, saveJson); err != nil {
+		t.Fatalf("ExportCiv5SaveFile returned error: %v", err)
+	}
+	if _, err := ImportCiv5ReplayFileFromJson(saveJson); err != nil {
+		t.Errorf("ImportCiv5ReplayFileFromJson(save json) returned error: %v", err)
+	}
+}
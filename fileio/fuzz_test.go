@@ -0,0 +1,322 @@
+package fileio
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"testing"
+)
+
+// The fuzz targets below only check that the readers return instead of panicking. They go
+// through the in-memory parsers, which the Read*File functions wrap. Run one with
+//
+//	go test ./fileio -run=^$ -fuzz=FuzzReadCiv5MapFile
+//
+// The seed corpus also runs as a normal test with go test.
+
+// addTruncatedSeeds adds data and a few prefixes of it to the seed corpus
+func addTruncatedSeeds(f *testing.F, data []byte) {
+	f.Add(data)
+	for _, size := range []int{0, 4, len(data) / 4, len(data) / 2, len(data) - 1} {
+		if size >= 0 && size < len(data) {
+			f.Add(data[:size])
+		}
+	}
+}
+
+// writeUint32s appends each value as a little endian uint32
+func writeUint32s(buf *bytes.Buffer, values ...uint32) {
+	for _, value := range values {
+		binary.Write(buf, binary.LittleEndian, value)
+	}
+}
+
+// writeFuzzEvents appends an event list with a single city founded on tile (1, 0)
+func writeFuzzEvents(buf *bytes.Buffer) {
+	writeUint32s(buf, 1)    // events
+	writeUint32s(buf, 3, 1) // turn, type id
+	writeUint32s(buf, 1)    // tiles
+	binary.Write(buf, binary.LittleEndian, [2]uint16{1, 0})
+	writeUint32s(buf, 0) // civ id
+	writeVarString(buf, "Rome is founded.")
+}
+
+// newFuzzReplayBytes builds a small replay with one civ, one dataset, one event and a 2x1 map
+func newFuzzReplayBytes() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("CIV5")
+	writeUint32s(&buf, 1)
+	writeVarString(&buf, "1.0.3.279")
+	writeVarString(&buf, "403694")
+	writeUint32s(&buf, 10) // current turn
+	buf.WriteByte(0)
+	writeVarString(&buf, "CIVILIZATION_ROME")
+	for _, s := range []string{"HANDICAP_PRINCE", "ERA_ANCIENT", "ERA_FUTURE", "GAMESPEED_STANDARD", "WORLDSIZE_DUEL", "Assets\\Maps\\Continents.lua"} {
+		writeVarString(&buf, s)
+	}
+	writeUint32s(&buf, 0, 0) // dlc, mods
+	writeVarString(&buf, "Rome")
+	writeVarString(&buf, "Augustus")
+	writeVarString(&buf, "PLAYERCOLOR_ROME")
+	buf.Write(make([]byte, 8))
+	writeVarString(&buf, "Assets\\Maps\\Continents.lua")
+
+	// Unknown block: five values, an array of one and an array with one more value than its count
+	writeUint32s(&buf, 5, 0, 0, 0, 0)
+	writeUint32s(&buf, 1, 7)
+	writeUint32s(&buf, 0, 9)
+	buf.WriteByte(0)
+
+	// Turn range
+	writeUint32s(&buf, 0)
+	binary.Write(&buf, binary.LittleEndian, int32(-4000))
+	writeUint32s(&buf, 10)
+	writeVarString(&buf, "3040 BC")
+	buf.Write(make([]byte, 8))
+
+	writeUint32s(&buf, 1, 0, 0, 0, 0) // civs, then the first civ's unknown variables
+	for _, s := range []string{"Augustus", "Roman Empire", "Rome", "Romans"} {
+		writeVarString(&buf, s)
+	}
+
+	writeUint32s(&buf, 1)
+	writeVarString(&buf, "Score")
+	writeUint32s(&buf, 1, 1, 1, 5, 120) // civs, datasets, entries, turn, value
+
+	writeUint32s(&buf, 0) // unknown value before events
+	writeFuzzEvents(&buf)
+
+	writeUint32s(&buf, 2, 1) // map width and height
+	writeUint32s(&buf, 2)
+	binary.Write(&buf, binary.LittleEndian, []Civ5ReplayTile{
+		{PlotType: ReplayPlotLand, TerrainType: 0, FeatureType: 0xFF},
+		{PlotType: ReplayPlotHills, TerrainType: 1, FeatureType: 0xFF},
+	})
+	return buf.Bytes()
+}
+
+// newFuzzSaveBytes builds a small save with two civs, a 2x1 world size grid, one game option and
+// a compressed block holding one event. Most of the unknown arrays are empty.
+func newFuzzSaveBytes() []byte {
+	var buf bytes.Buffer
+	empty := func(count int) { writeUint32s(&buf, make([]uint32, count)...) }
+
+	// Header, game settings and content
+	buf.WriteString("CIV5")
+	writeUint32s(&buf, 1)
+	writeVarString(&buf, "1.0.3.279")
+	writeVarString(&buf, "403694")
+	writeUint32s(&buf, 10)
+	buf.WriteByte(0)
+	writeVarString(&buf, "CIVILIZATION_ROME")
+	for _, s := range []string{"HANDICAP_PRINCE", "ERA_ANCIENT", "ERA_FUTURE", "GAMESPEED_STANDARD", "WORLDSIZE_DUEL", "Assets\\Maps\\Continents.lua"} {
+		writeVarString(&buf, s)
+	}
+	empty(2) // dlc, mods
+
+	// Player and map info
+	writeVarString(&buf, "Rome")
+	writeVarString(&buf, "Augustus")
+	writeVarString(&buf, "PLAYERCOLOR_ROME")
+	buf.Write(make([]byte, 16))
+	writeVarString(&buf, "1.0.3.279")
+	buf.Write(make([]byte, 16))
+	empty(4)
+	writeVarString(&buf, "Assets\\Maps\\Continents.lua")
+	empty(1)
+	writeUint32s(&buf, 1)
+	writeVarString(&buf, "Player 1")
+	empty(4)
+
+	// Civ roster, leaders and civ arrays
+	writeUint32s(&buf, 2)
+	writeVarString(&buf, "Rome")
+	writeVarString(&buf, "Geneva")
+	empty(2) // leader array, unknownBlock5Number
+	writeVarString(&buf, "Player 1")
+	empty(1)
+	buf.Write(make([]byte, 53))
+	empty(4)
+
+	// Climate
+	empty(1)
+	buf.Write(make([]byte, 8))
+	writeVarString(&buf, "TXT_KEY_CLIMATE_TEMPERATE")
+	buf.Write(make([]byte, 12))
+	writeVarString(&buf, "CLIMATE_TEMPERATE")
+	writeVarString(&buf, "TXT_KEY_CLIMATE_TEMPERATE_HELP")
+	writeVarString(&buf, "Temperate")
+	empty(11)
+
+	// Game name and turn
+	empty(4)
+	buf.Write(make([]byte, 15))
+	writeVarString(&buf, "Fuzz Game")
+	writeUint32s(&buf, 2)
+	buf.WriteByte(0)
+	writeUint32s(&buf, 10)
+	buf.Write(make([]byte, 5))
+	empty(2)
+	buf.Write(make([]byte, 2)) // no unknownArray4
+
+	// Second leader array and player setup
+	empty(2)
+	writeVarString(&buf, "Player 1")
+	buf.Write(make([]byte, 7))
+	writeVarString(&buf, "Assets\\Maps\\Continents.lua")
+	writeUint32s(&buf, 0, 500, 0)
+
+	// Minor civs, then player arrays and colors
+	writeUint32s(&buf, 2)
+	writeVarString(&buf, "")
+	writeVarString(&buf, "MINOR_CIV_GENEVA")
+	buf.Write(make([]byte, 77))
+	empty(2)
+	buf.Write(make([]byte, 8))
+	empty(1)
+	writeUint32s(&buf, 2)
+	writeVarString(&buf, "PLAYERCOLOR_ROME")
+	writeVarString(&buf, "PLAYERCOLOR_MINOR_WHITE")
+	buf.Write(make([]byte, 10))
+	empty(1)
+	buf.Write(make([]byte, 12))
+
+	// Sea level
+	writeVarString(&buf, "TXT_KEY_SEALEVEL_LOW")
+	buf.Write(make([]byte, 12))
+	writeVarString(&buf, "SEALEVEL_LOW")
+	writeVarString(&buf, "TXT_KEY_SEALEVEL_LOW_HELP")
+	writeVarString(&buf, "Low")
+	buf.Write(make([]byte, 5))
+	empty(2)
+	buf.Write(make([]byte, 12))
+	empty(1)
+	buf.WriteByte(0)
+
+	// Turn timer
+	empty(2)
+	writeVarString(&buf, "TXT_KEY_TURN_TIMER")
+	buf.Write(make([]byte, 12))
+	for _, s := range []string{"TURNTIMER_STANDARD", "TXT_KEY_TURN_TIMER_HELP", "Standard"} {
+		writeVarString(&buf, s)
+	}
+	empty(5)
+	buf.WriteByte(0)
+	empty(2) // victory flags, array after the turn timer
+
+	// World size, with the grid size the map is built with
+	empty(2)
+	writeVarString(&buf, "TXT_KEY_WORLDSIZE_DUEL")
+	writeVarString(&buf, "TXT_KEY_WORLDSIZE_DUEL_HELP")
+	buf.Write(make([]byte, 8))
+	for _, s := range []string{"WORLDSIZE_DUEL", "Duel", "Duel"} {
+		writeVarString(&buf, s)
+	}
+	empty(9)
+	writeUint32s(&buf, 2, 1)
+	empty(7)
+
+	// Game options
+	writeUint32s(&buf, 1)
+	writeVarString(&buf, "GAMEOPTION_QUICK_COMBAT")
+	writeUint32s(&buf, 1)
+	empty(1)
+	writeVarString(&buf, "1.0.3.279")
+	empty(3)
+
+	// Compressed block
+	buf.Write([]byte{2, 0, 0, 0, 0, 0, 1, 0})
+	writer := zlib.NewWriter(&buf)
+	writer.Write(newFuzzDecompressedBytes())
+	writer.Close()
+	return buf.Bytes()
+}
+
+// newFuzzDecompressedBytes builds the inflated block of an older save, with empty unit data
+// arrays and one event
+func newFuzzDecompressedBytes() []byte {
+	var buf bytes.Buffer
+	writeUint32s(&buf, 1, 0, 10, 0, 0)
+	binary.Write(&buf, binary.LittleEndian, int32(-4000))
+	writeUint32s(&buf, make([]uint32, 24)...)
+	buf.Write(make([]byte, 10))
+	writeUint32s(&buf, 0) // options
+	buf.Write(make([]byte, 1844))
+	writeUint32s(&buf, 0, 0, 0) // unit data arrays
+	buf.Write(make([]byte, 128+756+128))
+	writeUint32s(&buf, 0) // great people
+	buf.Write(make([]byte, 2*56+38))
+	writeFuzzEvents(&buf)
+	return buf.Bytes()
+}
+
+func FuzzReadCiv5MapFile(f *testing.F) {
+	var buf bytes.Buffer
+	if err := WriteCiv5MapFile(newWriterTestMapData(12), &buf); err != nil {
+		f.Fatalf("WriteCiv5MapFile returned error: %v", err)
+	}
+	addTruncatedSeeds(f, buf.Bytes())
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ParseCiv5Map(bytes.NewReader(data), int64(len(data)))
+	})
+}
+
+func FuzzReadCiv5ReplayFile(f *testing.F) {
+	addTruncatedSeeds(f, newFuzzReplayBytes())
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ParseCiv5Replay(bytes.NewReader(data), int64(len(data)))
+	})
+}
+
+func FuzzReadCiv5SaveFile(f *testing.F) {
+	addTruncatedSeeds(f, newFuzzSaveBytes())
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ParseCiv5Save(bytes.NewReader(data), int64(len(data)))
+	})
+}
+
+// FuzzReadDecompressed covers the parser for the zlib block of a save, which FuzzReadCiv5SaveFile
+// rarely reaches because the fuzzer has to get through the whole header first
+func FuzzReadDecompressed(f *testing.F) {
+	addTruncatedSeeds(f, newFuzzDecompressedBytes())
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		readDecompressed(bytes.NewReader(data), len(data))
+	})
+}
+
+// TestFuzzSeedsParse checks that the seeds are complete files, so the fuzzers start from inputs
+// that reach every section of the parsers
+func TestFuzzSeedsParse(t *testing.T) {
+	data := newFuzzReplayBytes()
+	replayData, err := ParseCiv5Replay(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("ParseCiv5Replay(seed) returned error: %v", err)
+	}
+	if len(replayData.AllCivs) != 1 || len(replayData.DatasetNames) != 1 || len(replayData.AllReplayEvents) != 1 {
+		t.Errorf("replay seed has %d civs, %d datasets and %d events, want 1 of each",
+			len(replayData.AllCivs), len(replayData.DatasetNames), len(replayData.AllReplayEvents))
+	}
+	if len(replayData.MapTiles) != 1 || len(replayData.MapTiles[0]) != 2 {
+		t.Errorf("replay seed tiles = %v, want a 2x1 map", replayData.MapTiles)
+	}
+
+	data = newFuzzSaveBytes()
+	saveData, err := ParseCiv5Save(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("ParseCiv5Save(seed) returned error: %v", err)
+	}
+	header := saveData.Header
+	if header.MapWidth != 2 || header.MapHeight != 1 || header.Climate != "CLIMATE_TEMPERATE" || header.SeaLevel != "SEALEVEL_LOW" {
+		t.Errorf("save seed header = %+v, want a 2x1 temperate map with a low sea level", header)
+	}
+	if len(header.GameOptions) != 1 || len(saveData.AllCivs) != 2 || saveData.AllCivs[1].Name != "MINOR_CIV_GENEVA" {
+		t.Errorf("save seed has options %v and civs %+v, want one option and Rome and Geneva", header.GameOptions, saveData.AllCivs)
+	}
+	if len(saveData.AllReplayEvents) != 1 || saveData.AllReplayEvents[0].Text != "Rome is founded." {
+		t.Errorf("save seed events = %+v, want the founding of Rome", saveData.AllReplayEvents)
+	}
+}
@@ -1,28 +1,170 @@
 package fileio
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"strings"
 )
 
+// CurrentSchemaVersion is the SchemaVersion written by the exporters. Documents without a
+// SchemaVersion are version 0, from before the field was added.
+const CurrentSchemaVersion = 2
+
 type Civ5MapJson struct {
-	GameName   string
-	FileFormat string
-	MapData    *Civ5MapData
+	GameName      string
+	FileFormat    string
+	SchemaVersion int
+	MapData       *Civ5MapData
 }
 
 type Civ5ReplayJson struct {
-	GameName   string
-	FileFormat string
-	ReplayData *Civ5ReplayData
+	GameName      string
+	FileFormat    string
+	SchemaVersion int
+	ReplayData    *Civ5ReplayData
 }
 
 type Civ5SaveJson struct {
-	GameName   string
-	FileFormat string
-	ReplayData *Civ5SaveData
+	GameName      string
+	FileFormat    string
+	SchemaVersion int
+	ReplayData    *Civ5SaveData
+}
+
+// jsonMigration upgrades a decoded JSON document by one schema version
+type jsonMigration func(document map[string]interface{}) error
+
+// jsonMigrations holds the migration chain of each document type. The migration at index i
+// upgrades a document from SchemaVersion i to i+1, so every chain has CurrentSchemaVersion steps.
+var jsonMigrations = map[FileType][]jsonMigration{
+	FileTypeCiv5Map:        {migrateMapJsonV0, migrateMapJsonV1},
+	FileTypeCiv5MapCompact: {migrateCompactMapJsonV0, migrateMapJsonV1},
+	FileTypeCiv5Replay:     {migrateUnchanged, migrateUnchanged},
+	FileTypeCiv5Save:       {migrateUnchanged, migrateUnchanged},
+}
+
+// migrateMapJsonV0 fills in the tile and player fields that version 0 maps didn't have. A missing
+// UnitId would otherwise read as unit 0, and a missing Playable would make every civ unplayable
+// once the map is written back out as a .civ5map. A missing Team would put every civ on team 0,
+// so each player gets its own team, as in maps without team data. A missing start position is
+// set to (-1, -1), the game's invalid plot, rather than left to read as tile (0, 0).
+func migrateMapJsonV0(document map[string]interface{}) error {
+	mapData, ok := document["MapData"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	rows, _ := mapData["MapTileImprovements"].([]interface{})
+	for _, row := range rows {
+		tiles, _ := row.([]interface{})
+		for _, tile := range tiles {
+			if tileFields, ok := tile.(map[string]interface{}); ok {
+				if _, found := tileFields["UnitId"]; !found {
+					tileFields["UnitId"] = InvalidUnitId
+				}
+			}
+		}
+	}
+
+	players, _ := mapData["Civ5PlayerData"].([]interface{})
+	for i, player := range players {
+		if playerFields, ok := player.(map[string]interface{}); ok {
+			if _, found := playerFields["Playable"]; !found {
+				playerFields["Playable"] = true
+			}
+			if _, found := playerFields["Team"]; !found {
+				playerFields["Team"] = i
+			}
+			for _, field := range []string{"StartPositionX", "StartPositionY"} {
+				if _, found := playerFields[field]; !found {
+					playerFields[field] = -1
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// migrateMapJsonV1 gives maps without a natural wonder list, such as the version 0 maps in the
+// maps folder, the default list that their FeatureWonderType values index into. Version 2 is when
+// natural wonders started being drawn from the list. Compact maps hold the list in MapData too, so
+// they use the same step.
+func migrateMapJsonV1(document map[string]interface{}) error {
+	mapData, ok := document["MapData"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if _, found := mapData["FeatureWonderList"]; !found {
+		mapData["FeatureWonderList"] = DefaultFeatureWonderList
+	}
+	return nil
+}
+
+// migrateUnchanged is the step for documents that a schema version doesn't need to rewrite.
+// Replays and saves gained only new fields in version 1, which read as zero values when missing,
+// and version 2 only changed maps.
+func migrateUnchanged(document map[string]interface{}) error {
+	return nil
+}
+
+// migrateCompactMapJsonV0 rejects version 0 compact maps, which can't exist since the format was
+// added in version 1
+func migrateCompactMapJsonV0(document map[string]interface{}) error {
+	return fmt.Errorf("compact maps start at schema version 1")
+}
+
+// migrateJsonDocument decodes jsonContents, runs the migrations needed to bring it up to
+// CurrentSchemaVersion, and returns the encoded result along with its file type
+func migrateJsonDocument(jsonContents []byte) ([]byte, FileType, error) {
+	var document map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(jsonContents))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		return nil, FileTypeUnknown, err
+	}
+	if document == nil {
+		return nil, FileTypeUnknown, fmt.Errorf("json document is empty")
+	}
+
+	fileFormat, _ := document["FileFormat"].(string)
+	fileType := FileType(strings.ToLower(fileFormat))
+	migrations, ok := jsonMigrations[fileType]
+	if !ok {
+		return nil, FileTypeUnknown, fmt.Errorf("unknown FileFormat %q", fileFormat)
+	}
+
+	schemaVersion := 0
+	if value, found := document["SchemaVersion"]; found {
+		number, ok := value.(json.Number)
+		version, err := number.Int64()
+		if !ok || err != nil || version < 0 {
+			return nil, fileType, fmt.Errorf("invalid SchemaVersion %v", value)
+		}
+		schemaVersion = int(version)
+	}
+	if schemaVersion > CurrentSchemaVersion {
+		return nil, fileType, fmt.Errorf("SchemaVersion %d is newer than the latest supported version %d", schemaVersion, CurrentSchemaVersion)
+	}
+	if schemaVersion == CurrentSchemaVersion {
+		return jsonContents, fileType, nil
+	}
+
+	for version := schemaVersion; version < CurrentSchemaVersion; version++ {
+		fmt.Printf("Migrating %s json from schema version %d to %d\n", fileFormat, version, version+1)
+		if err := migrations[version](document); err != nil {
+			return nil, fileType, fmt.Errorf("failed to migrate from schema version %d: %w", version, err)
+		}
+	}
+	document["SchemaVersion"] = CurrentSchemaVersion
+
+	migratedContents, err := json.Marshal(document)
+	if err != nil {
+		return nil, fileType, err
+	}
+	return migratedContents, fileType, nil
 }
 
 func ImportCiv5MapFileFromJson(inputFilename string) (*Civ5MapData, error) {
@@ -37,6 +179,25 @@ func ImportCiv5MapFileFromJson(inputFilename string) (*Civ5MapData, error) {
 		return nil, fmt.Errorf("failed to read json file %q: %w", inputFilename, err)
 	}
 
+	jsonContents, fileType, err := migrateJsonDocument(jsonContents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load json from %q: %w", inputFilename, err)
+	}
+	if fileType == FileTypeCiv5MapCompact {
+		var compactJson *Civ5CompactMapJson
+		if err := json.Unmarshal(jsonContents, &compactJson); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal json from %q: %w", inputFilename, err)
+		}
+		mapData, err := compactJson.ToMapData()
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand compact map from %q: %w", inputFilename, err)
+		}
+		return mapData, nil
+	}
+	if fileType != FileTypeCiv5Map {
+		return nil, fmt.Errorf("json in %q was exported from a %q file, not a map", inputFilename, fileType)
+	}
+
 	var civ5MapJson *Civ5MapJson
 	if err := json.Unmarshal(jsonContents, &civ5MapJson); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal json from %q: %w", inputFilename, err)
@@ -51,9 +212,10 @@ func ImportCiv5MapFileFromJson(inputFilename string) (*Civ5MapData, error) {
 
 func ExportCiv5MapFile(mapData *Civ5MapData, outputFilename string) error {
 	civ5MapJson := &Civ5MapJson{
-		GameName:   "Civilization 5",
-		FileFormat: ".Civ5Map",
-		MapData:    mapData,
+		GameName:      "Civilization 5",
+		FileFormat:    ".Civ5Map",
+		SchemaVersion: CurrentSchemaVersion,
+		MapData:       mapData,
 	}
 
 	file, err := json.MarshalIndent(civ5MapJson, "", " ")
@@ -81,6 +243,15 @@ func ImportCiv5ReplayFileFromJson(inputFilename string) (*Civ5ReplayData, error)
 		return nil, fmt.Errorf("failed to read json file %q: %w", inputFilename, err)
 	}
 
+	// A save export holds its replay events in the same ReplayData field
+	jsonContents, fileType, err := migrateJsonDocument(jsonContents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load json from %q: %w", inputFilename, err)
+	}
+	if fileType != FileTypeCiv5Replay && fileType != FileTypeCiv5Save {
+		return nil, fmt.Errorf("json in %q was exported from a %q file, not a replay or save", inputFilename, fileType)
+	}
+
 	var civ5ReplayJson *Civ5ReplayJson
 	if err := json.Unmarshal(jsonContents, &civ5ReplayJson); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal json from %q: %w", inputFilename, err)
@@ -95,9 +266,10 @@ func ImportCiv5ReplayFileFromJson(inputFilename string) (*Civ5ReplayData, error)
 
 func ExportCiv5ReplayFile(replayData *Civ5ReplayData, outputFilename string) error {
 	civ5ReplayJson := &Civ5ReplayJson{
-		GameName:   "Civilization 5",
-		FileFormat: ".Civ5Replay",
-		ReplayData: replayData,
+		GameName:      "Civilization 5",
+		FileFormat:    ".Civ5Replay",
+		SchemaVersion: CurrentSchemaVersion,
+		ReplayData:    replayData,
 	}
 
 	file, err := json.MarshalIndent(civ5ReplayJson, "", " ")
@@ -115,9 +287,10 @@ func ExportCiv5ReplayFile(replayData *Civ5ReplayData, outputFilename string) err
 
 func ExportCiv5SaveFile(saveData *Civ5SaveData, outputFilename string) error {
 	civ5SaveJson := &Civ5SaveJson{
-		GameName:   "Civilization 5",
-		FileFormat: ".Civ5Save",
-		ReplayData: saveData,
+		GameName:      "Civilization 5",
+		FileFormat:    ".Civ5Save",
+		SchemaVersion: CurrentSchemaVersion,
+		ReplayData:    saveData,
 	}
 
 	file, err := json.MarshalIndent(civ5SaveJson, "", " ")
@@ -0,0 +1,200 @@
+package fileio
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// legacyMapJson is a map exported before SchemaVersion, UnitId and Playable were written
+const legacyMapJson = `{
+ "GameName": "Civilization 5",
+ "FileFormat": ".Civ5Map",
+ "MapData": {
+  "MapHeader": {"Width": 2, "Height": 1},
+  "MapTiles": [[{"TerrainType": 0}, {"TerrainType": 1}]],
+  "MapTileImprovements": [[{"Owner": 0, "CityId": -1}, {"Owner": -1, "CityId": -1}]],
+  "Civ5PlayerData": [
+   {"CivType": "CIVILIZATION_ROME", "Index": 0, "TeamColor": "PLAYERCOLOR_ROME"},
+   {"CivType": "CIVILIZATION_GREECE", "Index": 1, "TeamColor": "PLAYERCOLOR_GREECE"}
+  ]
+ }
+}`
+
+func writeTestJson(t *testing.T, contents string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "test.json")
+	if err := os.WriteFile(filename, []byte(contents), 0644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	return filename
+}
+
+func TestImportLegacyMapJson(t *testing.T) {
+	mapData, err := ImportCiv5MapFileFromJson(writeTestJson(t, legacyMapJson))
+	if err != nil {
+		t.Fatalf("ImportCiv5MapFileFromJson returned error: %v", err)
+	}
+
+	for _, tile := range mapData.MapTileImprovements[0] {
+		if tile.UnitId != InvalidUnitId {
+			t.Errorf("tile UnitId = %d, want %d", tile.UnitId, InvalidUnitId)
+		}
+	}
+	if len(mapData.Civ5PlayerData) != 2 {
+		t.Fatalf("Civ5PlayerData = %+v, want two civs", mapData.Civ5PlayerData)
+	}
+	for i, player := range mapData.Civ5PlayerData {
+		if !player.Playable || player.Team != i {
+			t.Errorf("Civ5PlayerData[%d] = %+v, want a playable civ on team %d", i, player, i)
+		}
+		if player.StartPositionX != -1 || player.StartPositionY != -1 {
+			t.Errorf("Civ5PlayerData[%d] start position = (%d, %d), want (-1, -1)", i, player.StartPositionX, player.StartPositionY)
+		}
+	}
+	if len(mapData.FeatureWonderList) != len(DefaultFeatureWonderList) || mapData.FeatureWonderList[1] != "FEATURE_FUJI" {
+		t.Errorf("FeatureWonderList = %v, want the default list", mapData.FeatureWonderList)
+	}
+}
+
+func TestImportMapJsonKeepsCurrentFields(t *testing.T) {
+	mapData := newWriterTestMapData(12)
+	mapData.Civ5PlayerData[0].Playable = false
+	filename := filepath.Join(t.TempDir(), "map.json")
+	if err := ExportCiv5MapFile(mapData, filename); err != nil {
+		t.Fatalf("ExportCiv5MapFile returned error: %v", err)
+	}
+
+	imported, err := ImportCiv5MapFileFromJson(filename)
+	if err != nil {
+		t.Fatalf("ImportCiv5MapFileFromJson returned error: %v", err)
+	}
+	if imported.Civ5PlayerData[0].Playable {
+		t.Error("Playable = true after import, want the exported false")
+	}
+}
+
+func TestImportJsonRejectsNewerSchemaVersion(t *testing.T) {
+	filename := writeTestJson(t, `{"FileFormat": ".Civ5Replay", "SchemaVersion": 99, "ReplayData": {}}`)
+	if _, err := ImportCiv5ReplayFileFromJson(filename); err == nil {
+		t.Error("ImportCiv5ReplayFileFromJson(SchemaVersion 99) = nil error, want an error")
+	}
+}
+
+func TestMigrateJsonDocument(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		wantType FileType
+	}{
+		{"legacy map", legacyMapJson, FileTypeCiv5Map},
+		{"legacy replay", `{"FileFormat": ".Civ5Replay", "ReplayData": {"PlayerCiv": "CIVILIZATION_ROME"}}`, FileTypeCiv5Replay},
+		{"legacy save", `{"FileFormat": ".Civ5Save", "ReplayData": {}}`, FileTypeCiv5Save},
+		{"version 1 map", `{"FileFormat": ".Civ5Map", "SchemaVersion": 1, "MapData": {}}`, FileTypeCiv5Map},
+		{"version 1 compact map", `{"FileFormat": ".Civ5MapCompact", "SchemaVersion": 1, "MapData": {}}`, FileTypeCiv5MapCompact},
+		{"current replay", `{"FileFormat": ".civ5replay", "SchemaVersion": 2, "ReplayData": {}}`, FileTypeCiv5Replay},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrated, fileType, err := migrateJsonDocument([]byte(tt.contents))
+			if err != nil {
+				t.Fatalf("migrateJsonDocument returned error: %v", err)
+			}
+			if fileType != tt.wantType {
+				t.Errorf("file type = %q, want %q", fileType, tt.wantType)
+			}
+			var document struct{ SchemaVersion int }
+			if err := json.Unmarshal(migrated, &document); err != nil {
+				t.Fatalf("Unmarshal returned error: %v", err)
+			}
+			if document.SchemaVersion != CurrentSchemaVersion {
+				t.Errorf("SchemaVersion = %d, want %d", document.SchemaVersion, CurrentSchemaVersion)
+			}
+		})
+	}
+}
+
+func TestJsonMigrationChainsAreComplete(t *testing.T) {
+	for fileType, migrations := range jsonMigrations {
+		if len(migrations) != CurrentSchemaVersion {
+			t.Errorf("%q has %d migrations, want %d", fileType, len(migrations), CurrentSchemaVersion)
+		}
+	}
+}
+
+// TestJsonSchemasUpToDate checks the schemas in the schemas directory against JsonSchema.
+// Regenerate them with
+//
+//	UPDATE_SCHEMAS=1 go test ./fileio -run TestJsonSchemasUpToDate
+func TestJsonSchemasUpToDate(t *testing.T) {
+	schemaFiles := map[FileType]string{
+		FileTypeCiv5Map:        "civ5map.schema.json",
+		FileTypeCiv5MapCompact: "civ5mapcompact.schema.json",
+		FileTypeCiv5Replay:     "civ5replay.schema.json",
+		FileTypeCiv5Save:       "civ5save.schema.json",
+	}
+	for fileType, name := range schemaFiles {
+		schema, err := JsonSchema(fileType)
+		if err != nil {
+			t.Fatalf("JsonSchema(%q) returned error: %v", fileType, err)
+		}
+		filename := filepath.Join("..", "schemas", name)
+		if os.Getenv("UPDATE_SCHEMAS") != "" {
+			if err := os.WriteFile(filename, schema, 0644); err != nil {
+				t.Fatalf("WriteFile returned error: %v", err)
+			}
+			continue
+		}
+
+		committed, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatalf("ReadFile returned error: %v", err)
+		}
+		if !bytes.Equal(committed, schema) {
+			t.Errorf("%s is out of date, regenerate it with UPDATE_SCHEMAS=1", filename)
+		}
+	}
+}
+
+// TestExportCommittedMapKeepsTeams writes a committed legacy map out as a .civ5map, which must
+// keep every civ on its own team
+func TestExportCommittedMapKeepsTeams(t *testing.T) {
+	mapData, err := ImportCiv5MapFileFromJson(filepath.Join("..", "maps", "europe1939.json"))
+	if err != nil {
+		t.Fatalf("ImportCiv5MapFileFromJson returned error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteCiv5MapFile(mapData, &buf); err != nil {
+		t.Fatalf("WriteCiv5MapFile returned error: %v", err)
+	}
+	written, err := ParseCiv5Map(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("ParseCiv5Map returned error: %v", err)
+	}
+
+	teams := map[int]string{}
+	for _, player := range written.Civ5PlayerData {
+		if other, found := teams[player.Team]; found {
+			t.Errorf("%s and %s are both on team %d", player.CivType, other, player.Team)
+		}
+		teams[player.Team] = player.CivType
+	}
+	if len(teams) != len(mapData.Civ5PlayerData) {
+		t.Errorf("written map has %d teams, want one for each of the %d civs", len(teams), len(mapData.Civ5PlayerData))
+	}
+}
+
+// TestImportCommittedMaps loads the maps kept in the maps directory, which predate SchemaVersion
+func TestImportCommittedMaps(t *testing.T) {
+	filenames, err := filepath.Glob(filepath.Join("..", "maps", "*.json"))
+	if err != nil {
+		t.Fatalf("Glob returned error: %v", err)
+	}
+	for _, filename := range filenames {
+		if _, err := ImportCiv5MapFileFromJson(filename); err != nil {
+			t.Errorf("ImportCiv5MapFileFromJson(%q) returned error: %v", filename, err)
+		}
+	}
+}
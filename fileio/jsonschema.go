@@ -0,0 +1,142 @@
+package fileio
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// jsonSchemaDialect is the JSON Schema draft the generated schemas follow
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// jsonSchemaDocuments lists the exported JSON document of each file type
+var jsonSchemaDocuments = map[FileType]struct {
+	Title      string
+	FileFormat string
+	Document   interface{}
+}{
+	FileTypeCiv5Map:        {"Civ5 map JSON export", ".Civ5Map", Civ5MapJson{}},
+	FileTypeCiv5MapCompact: {"Civ5 compact map JSON export", ".Civ5MapCompact", Civ5CompactMapJson{}},
+	FileTypeCiv5Replay:     {"Civ5 replay JSON export", ".Civ5Replay", Civ5ReplayJson{}},
+	FileTypeCiv5Save:       {"Civ5 save JSON export", ".Civ5Save", Civ5SaveJson{}},
+}
+
+// jsonSchemaBuilder collects the schemas of the structs referenced by a document under $defs
+type jsonSchemaBuilder struct {
+	defs map[string]interface{}
+}
+
+// JsonSchema returns the JSON Schema of the document written by the JSON exporter for fileType,
+// at CurrentSchemaVersion. The schemas in the schemas directory are generated by this function.
+func JsonSchema(fileType FileType) ([]byte, error) {
+	document, ok := jsonSchemaDocuments[fileType]
+	if !ok {
+		return nil, fmt.Errorf("no json schema for file type %q", fileType)
+	}
+
+	builder := &jsonSchemaBuilder{defs: map[string]interface{}{}}
+	schema := builder.structSchema(reflect.TypeOf(document.Document))
+	properties := schema["properties"].(map[string]interface{})
+	// FileFormat is matched case-insensitively on import, so only the exporter's spelling is listed
+	properties["FileFormat"] = map[string]interface{}{"type": "string", "const": document.FileFormat}
+	properties["SchemaVersion"] = map[string]interface{}{"type": "integer", "const": CurrentSchemaVersion}
+	schema["required"] = []string{"GameName", "FileFormat", "SchemaVersion"}
+
+	schema["$schema"] = jsonSchemaDialect
+	schema["title"] = document.Title
+	schema["$defs"] = builder.defs
+
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// typeSchema returns the schema of a value of type t as encoding/json writes it
+func (builder *jsonSchemaBuilder) typeSchema(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Int, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		bits := t.Bits()
+		return map[string]interface{}{
+			"type":    "integer",
+			"minimum": -(int64(1) << (bits - 1)),
+			"maximum": int64(1)<<(bits-1) - 1,
+		}
+	case reflect.Uint, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{
+			"type":    "integer",
+			"minimum": 0,
+			"maximum": uint64(math.MaxUint64) >> (64 - t.Bits()),
+		}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Pointer:
+		return nullable(builder.typeSchema(t.Elem()))
+	case reflect.Slice:
+		// encoding/json writes a byte slice as a base64 string and a nil slice as null
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": []string{"string", "null"}, "contentEncoding": "base64"}
+		}
+		return map[string]interface{}{"type": []string{"array", "null"}, "items": builder.typeSchema(t.Elem())}
+	case reflect.Array:
+		return map[string]interface{}{
+			"type":     "array",
+			"items":    builder.typeSchema(t.Elem()),
+			"minItems": t.Len(),
+			"maxItems": t.Len(),
+		}
+	case reflect.Map:
+		schema := map[string]interface{}{
+			"type":                 []string{"object", "null"},
+			"additionalProperties": builder.typeSchema(t.Elem()),
+		}
+		if t.Key().Kind() != reflect.String {
+			schema["propertyNames"] = map[string]interface{}{"pattern": "^-?[0-9]+$"}
+		}
+		return schema
+	case reflect.Struct:
+		name := t.Name()
+		if _, found := builder.defs[name]; !found {
+			// Reserve the name first so a struct that refers to itself doesn't recurse forever
+			builder.defs[name] = nil
+			builder.defs[name] = builder.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + name}
+	}
+	// Interfaces and the other kinds aren't used by the exported data
+	return map[string]interface{}{}
+}
+
+// structSchema returns the object schema listing the exported fields of t
+func (builder *jsonSchemaBuilder) structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		properties[field.Name] = builder.typeSchema(field.Type)
+	}
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
+
+// nullable allows null in addition to the values accepted by schema
+func nullable(schema map[string]interface{}) map[string]interface{} {
+	if _, isRef := schema["$ref"]; isRef {
+		return map[string]interface{}{"anyOf": []interface{}{schema, map[string]interface{}{"type": "null"}}}
+	}
+	if schemaType, ok := schema["type"].(string); ok {
+		schema["type"] = []string{schemaType, "null"}
+	}
+	return schema
+}
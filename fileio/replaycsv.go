@@ -0,0 +1,142 @@
+package fileio
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+)
+
+// replayDatasetNames returns the names of the datasets in replayData, in the order of
+// DatasetNames followed by any others that only appear in DatasetValues
+func replayDatasetNames(replayData *Civ5ReplayData) []string {
+	names := append([]string{}, replayData.DatasetNames...)
+	var extraNames []string
+	for _, civDataset := range replayData.DatasetValues {
+		for name := range civDataset.DatasetValues {
+			if !slices.Contains(names, name) && !slices.Contains(extraNames, name) {
+				extraNames = append(extraNames, name)
+			}
+		}
+	}
+	slices.Sort(extraNames)
+	return append(names, extraNames...)
+}
+
+// replayCivName returns the short name of the civ at civIndex, or "civ N" if the replay doesn't
+// have one
+func replayCivName(replayData *Civ5ReplayData, civIndex int) string {
+	if civIndex >= 0 && civIndex < len(replayData.AllCivs) && replayData.AllCivs[civIndex].Name != "" {
+		return replayData.AllCivs[civIndex].Name
+	}
+	return fmt.Sprintf("civ %d", civIndex)
+}
+
+// WriteReplayDatasetsCsv writes the dataset values of replayData as a long format CSV with one
+// row per civ, dataset and turn, under the header civ,civ_name,dataset,turn,value
+func WriteReplayDatasetsCsv(replayData *Civ5ReplayData, writer io.Writer) error {
+	csvWriter := csv.NewWriter(writer)
+	if err := csvWriter.Write([]string{"civ", "civ_name", "dataset", "turn", "value"}); err != nil {
+		return err
+	}
+
+	datasetNames := replayDatasetNames(replayData)
+	for _, civDataset := range replayData.DatasetValues {
+		civName := replayCivName(replayData, civDataset.CivIndex)
+		for _, datasetName := range datasetNames {
+			for _, entry := range civDataset.DatasetValues[datasetName] {
+				record := []string{
+					strconv.Itoa(civDataset.CivIndex),
+					civName,
+					datasetName,
+					strconv.Itoa(entry.Turn),
+					strconv.Itoa(entry.Value),
+				}
+				if err := csvWriter.Write(record); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// WriteReplayDatasetsWideCsv writes the dataset values of replayData as a wide format CSV with
+// one row per dataset and turn and one column per civ. Civs without any values are left out
+// and a cell is empty if the civ has no value for that turn.
+func WriteReplayDatasetsWideCsv(replayData *Civ5ReplayData, writer io.Writer) error {
+	var civDatasets []Civ5ReplayCivDataset
+	header := []string{"dataset", "turn"}
+	for _, civDataset := range replayData.DatasetValues {
+		hasValues := false
+		for _, entries := range civDataset.DatasetValues {
+			hasValues = hasValues || len(entries) > 0
+		}
+		if hasValues {
+			civDatasets = append(civDatasets, civDataset)
+			header = append(header, replayCivName(replayData, civDataset.CivIndex))
+		}
+	}
+
+	csvWriter := csv.NewWriter(writer)
+	if err := csvWriter.Write(header); err != nil {
+		return err
+	}
+
+	for _, datasetName := range replayDatasetNames(replayData) {
+		// Column index -> turn -> value
+		columnValues := make([]map[int]int, len(civDatasets))
+		var turns []int
+		for i, civDataset := range civDatasets {
+			columnValues[i] = map[int]int{}
+			for _, entry := range civDataset.DatasetValues[datasetName] {
+				columnValues[i][entry.Turn] = entry.Value
+				if !slices.Contains(turns, entry.Turn) {
+					turns = append(turns, entry.Turn)
+				}
+			}
+		}
+		slices.Sort(turns)
+
+		for _, turn := range turns {
+			record := []string{datasetName, strconv.Itoa(turn)}
+			for _, values := range columnValues {
+				cell := ""
+				if value, found := values[turn]; found {
+					cell = strconv.Itoa(value)
+				}
+				record = append(record, cell)
+			}
+			if err := csvWriter.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// ExportReplayDatasetsCsv writes the dataset values of replayData to outputFilename, in the wide
+// format if wide is set and the long format otherwise
+func ExportReplayDatasetsCsv(replayData *Civ5ReplayData, outputFilename string, wide bool) error {
+	outputFile, err := os.Create(outputFilename)
+	if err != nil {
+		return fmt.Errorf("failed to create %q: %w", outputFilename, err)
+	}
+	defer outputFile.Close()
+
+	if wide {
+		err = WriteReplayDatasetsWideCsv(replayData, outputFile)
+	} else {
+		err = WriteReplayDatasetsCsv(replayData, outputFile)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write csv to %q: %w", outputFilename, err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package fileio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func newCsvTestReplayData() *Civ5ReplayData {
+	return &Civ5ReplayData{
+		AllCivs:      []Civ5ReplayCiv{{Name: "Rome"}, {Name: "Greece"}, {}},
+		DatasetNames: []string{"REPLAYDATASET_SCORE", "REPLAYDATASET_GOLD"},
+		DatasetValues: []Civ5ReplayCivDataset{
+			{CivIndex: 0, DatasetValues: map[string][]Civ5ReplayDataEntry{
+				"REPLAYDATASET_SCORE": {{Turn: 1, Value: 10}, {Turn: 2, Value: 12}},
+				"REPLAYDATASET_GOLD":  {{Turn: 1, Value: 5}},
+			}},
+			{CivIndex: 1, DatasetValues: map[string][]Civ5ReplayDataEntry{
+				"REPLAYDATASET_SCORE": {{Turn: 2, Value: 8}},
+			}},
+			{CivIndex: 2, DatasetValues: map[string][]Civ5ReplayDataEntry{}},
+		},
+	}
+}
+
+func TestWriteReplayDatasetsCsv(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteReplayDatasetsCsv(newCsvTestReplayData(), &buf); err != nil {
+		t.Fatalf("WriteReplayDatasetsCsv returned error: %v", err)
+	}
+
+	want := "civ,civ_name,dataset,turn,value\n" +
+		"0,Rome,REPLAYDATASET_SCORE,1,10\n" +
+		"0,Rome,REPLAYDATASET_SCORE,2,12\n" +
+		"0,Rome,REPLAYDATASET_GOLD,1,5\n" +
+		"1,Greece,REPLAYDATASET_SCORE,2,8\n"
+	if buf.String() != want {
+		t.Errorf("WriteReplayDatasetsCsv() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteReplayDatasetsWideCsv(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteReplayDatasetsWideCsv(newCsvTestReplayData(), &buf); err != nil {
+		t.Fatalf("WriteReplayDatasetsWideCsv returned error: %v", err)
+	}
+
+	// The third civ has no values, so it has no column
+	want := "dataset,turn,Rome,Greece\n" +
+		"REPLAYDATASET_SCORE,1,10,\n" +
+		"REPLAYDATASET_SCORE,2,12,8\n" +
+		"REPLAYDATASET_GOLD,1,5,\n"
+	if buf.String() != want {
+		t.Errorf("WriteReplayDatasetsWideCsv() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestReplayDatasetNamesIncludesUnlistedDatasets(t *testing.T) {
+	replayData := newCsvTestReplayData()
+	replayData.DatasetValues[1].DatasetValues["REPLAYDATASET_EXTRA"] = []Civ5ReplayDataEntry{{Turn: 1, Value: 1}}
+
+	got := replayDatasetNames(replayData)
+	want := []string{"REPLAYDATASET_SCORE", "REPLAYDATASET_GOLD", "REPLAYDATASET_EXTRA"}
+	if len(got) != len(want) {
+		t.Fatalf("replayDatasetNames() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("replayDatasetNames()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
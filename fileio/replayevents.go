@@ -0,0 +1,266 @@
+package fileio
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ReplayEventKind is what a replay event is about, worked out from its TypeId and Text
+type ReplayEventKind string
+
+const (
+	// Kinds given by the TypeId of the event
+	ReplayEventKindCityFounded     ReplayEventKind = "cityfounded"
+	ReplayEventKindTilesClaimed    ReplayEventKind = "tilesclaimed"
+	ReplayEventKindCityTransferred ReplayEventKind = "citytransferred"
+	ReplayEventKindTilesRazed      ReplayEventKind = "tilesrazed"
+
+	// Kinds of the other events, which are recognized from the English text of the event
+	ReplayEventKindWarDeclared     ReplayEventKind = "wardeclared"
+	ReplayEventKindPeaceMade       ReplayEventKind = "peacemade"
+	ReplayEventKindWonderCompleted ReplayEventKind = "wondercompleted"
+	ReplayEventKindGreatPersonBorn ReplayEventKind = "greatpersonborn"
+	ReplayEventKindPantheonFounded ReplayEventKind = "pantheonfounded"
+	ReplayEventKindReligionFounded ReplayEventKind = "religionfounded"
+	ReplayEventKindCityCaptured    ReplayEventKind = "citycaptured"
+	ReplayEventKindCityRazed       ReplayEventKind = "cityrazed"
+	ReplayEventKindCivEliminated   ReplayEventKind = "civeliminated"
+	ReplayEventKindEraEntered      ReplayEventKind = "eraentered"
+	ReplayEventKindOther           ReplayEventKind = "other"
+)
+
+// Civ5TypedReplayEvent is a replay event with the names in its text picked out. Which fields
+// are set depends on Kind:
+//
+//   - CityFounded, CityCaptured, CityRazed, CityTransferred and TilesRazed have CityName, if the
+//     text names the city
+//   - WarDeclared and PeaceMade have the other civ in OtherCivId and OtherCivName
+//   - WonderCompleted has WonderName
+//   - GreatPersonBorn has GreatPersonName and the CityName they were born in
+//   - ReligionFounded has ReligionName and the holy city in CityName, if the text names it
+//   - EraEntered has EraName
+//
+// CivId is the civ the event is about, which is the civ named at the start of the text if it
+// can be found in the replay's civs and the CivId of the event otherwise.
+type Civ5TypedReplayEvent struct {
+	Event           Civ5ReplayEvent
+	Kind            ReplayEventKind
+	CivId           int
+	CivName         string
+	OtherCivId      int // -1 if the event has no other civ or it isn't one of the replay's civs
+	OtherCivName    string
+	CityName        string
+	WonderName      string
+	GreatPersonName string
+	ReligionName    string
+	EraName         string
+}
+
+// replayEventPattern recognizes the text of one kind of event. Each named group sets the field
+// of the same name.
+type replayEventPattern struct {
+	Kind    ReplayEventKind
+	Pattern *regexp.Regexp
+}
+
+// replayReligionNames are the English names of the religions that come with the game
+var replayReligionNames = []string{
+	"Buddhism", "Christianity", "Confucianism", "Hinduism", "Islam", "Judaism", "Orthodoxy",
+	"Protestantism", "Shinto", "Sikhism", "Taoism", "Tengriism", "Zoroastrianism",
+}
+
+// The patterns are tried in order, so the more specific ones come first. Trailing punctuation
+// and a leading "The" are left out of the names. They only match English text.
+var replayEventPatterns = []replayEventPattern{
+	{ReplayEventKindWarDeclared, regexp.MustCompile(`^(?P<CivName>.+?) (?:has )?declared war on (?P<OtherCivName>.+?)[.!]*$`)},
+	{ReplayEventKindPeaceMade, regexp.MustCompile(`^(?P<CivName>.+?) (?:has )?made peace with (?P<OtherCivName>.+?)[.!]*$`)},
+	{ReplayEventKindPeaceMade, regexp.MustCompile(`^(?P<CivName>.+?) and (?P<OtherCivName>.+?) (?:have )?made peace[.!]*$`)},
+	{ReplayEventKindWonderCompleted, regexp.MustCompile(`^(?P<CivName>.+?) (?:completes|has completed|completed) (?:the )?(?P<WonderName>.+?)[.!]*$`)},
+	{ReplayEventKindGreatPersonBorn, regexp.MustCompile(`^(?P<GreatPersonName>.+?) (?:has been|was|is) born in (?P<CityName>.+?)[.!]*$`)},
+	{ReplayEventKindPantheonFounded, regexp.MustCompile(`^(?P<CivName>.+?) (?:has )?(?:started|founded|adopted) a [Pp]antheon\b.*$`)},
+	// "founded" alone could be a city or anything else, so a religion needs "the religion of" or one
+	// of the game's religion names, with or without the holy city
+	{ReplayEventKindReligionFounded, regexp.MustCompile(`^(?P<CivName>.+?) (?:has )?founded the religion of (?P<ReligionName>.+?)(?: in (?P<CityName>.+?))?[.!]*$`)},
+	{ReplayEventKindReligionFounded, regexp.MustCompile(`^(?P<CivName>.+?) (?:has )?founded (?P<ReligionName>` + strings.Join(replayReligionNames, "|") + `)(?: in (?P<CityName>.+?))?[.!]*$`)},
+	{ReplayEventKindCityCaptured, regexp.MustCompile(`^(?P<CityName>.+?) (?:was |has been )?captured by (?:the )?(?P<CivName>.+?)[.!]*$`)},
+	{ReplayEventKindCityRazed, regexp.MustCompile(`^(?P<CityName>.+?) (?:was|has been) (?:razed|burned to the ground)(?: by (?:the )?(?P<CivName>.+?))?[.!]*$`)},
+	// Civs and cities are both "destroyed", so classifyReplayEventText checks which one it is
+	{ReplayEventKindCivEliminated, regexp.MustCompile(`^(?P<CivName>.+?) (?:has been|have been|was) (?:destroyed|eliminated|defeated)[.!]*$`)},
+	{ReplayEventKindEraEntered, regexp.MustCompile(`^(?P<CivName>.+?) (?:has )?(?:entered|reached) the (?P<EraName>.+?)[.!]*$`)},
+}
+
+// FindReplayCiv returns the index in allCivs of the civ called name, matching its short name,
+// long name, demonym or leader without regard to case or a leading "The". It returns -1 if no
+// civ has that name.
+func FindReplayCiv(allCivs []Civ5ReplayCiv, name string) int {
+	name = trimArticle(name)
+	if name == "" {
+		return -1
+	}
+	for i, civ := range allCivs {
+		for _, civName := range []string{civ.Name, civ.LongName, civ.Demonym, civ.Leader} {
+			if civName != "" && strings.EqualFold(trimArticle(civName), name) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// trimArticle removes spaces and a leading "The" from name
+func trimArticle(name string) string {
+	name = strings.TrimSpace(name)
+	if len(name) > 4 && strings.EqualFold(name[:4], "the ") {
+		name = name[4:]
+	}
+	return name
+}
+
+// ClassifyReplayEvent works out the kind of event and the names in its text. allCivs are the
+// civs of the replay, which are used to look up the civs named in the text.
+//
+// Only city foundings, claimed tiles, transferred tiles and razed tiles have their own TypeId. The
+// other kinds are recognized from the text alone, which only works for replays from a game set to
+// English; in any other language those events are ReplayEventKindOther.
+func ClassifyReplayEvent(event Civ5ReplayEvent, allCivs []Civ5ReplayCiv) Civ5TypedReplayEvent {
+	typedEvent := Civ5TypedReplayEvent{
+		Event:      event,
+		Kind:       ReplayEventKindOther,
+		CivId:      event.CivId,
+		OtherCivId: -1,
+	}
+
+	switch event.TypeId {
+	case ReplayEventCityFounded:
+		typedEvent.Kind = ReplayEventKindCityFounded
+		typedEvent.CityName = strings.TrimSuffix(event.Text, " is founded.")
+	case ReplayEventTilesClaimed:
+		typedEvent.Kind = ReplayEventKindTilesClaimed
+	case ReplayEventCityTransferred:
+		typedEvent.Kind = ReplayEventKindCityTransferred
+		if textEvent, ok := classifyReplayEventText(event.Text, allCivs); ok {
+			typedEvent.CityName = textEvent.CityName
+		}
+	case ReplayEventTilesRazed:
+		typedEvent.Kind = ReplayEventKindTilesRazed
+		if textEvent, ok := classifyReplayEventText(event.Text, allCivs); ok {
+			typedEvent.CityName = textEvent.CityName
+		}
+	default:
+		if textEvent, ok := classifyReplayEventText(event.Text, allCivs); ok {
+			textEvent.Event = event
+			typedEvent = textEvent
+			if typedEvent.CivId == -1 {
+				typedEvent.CivId = event.CivId
+			}
+		}
+	}
+
+	if typedEvent.CivName == "" && typedEvent.CivId >= 0 && typedEvent.CivId < len(allCivs) {
+		typedEvent.CivName = allCivs[typedEvent.CivId].Name
+	}
+	return typedEvent
+}
+
+// classifyReplayEventText matches text against replayEventPatterns. The CivId of the result is
+// -1 if the text doesn't name one of allCivs.
+func classifyReplayEventText(text string, allCivs []Civ5ReplayCiv) (Civ5TypedReplayEvent, bool) {
+	text = strings.TrimSpace(text)
+	for _, pattern := range replayEventPatterns {
+		match := pattern.Pattern.FindStringSubmatch(text)
+		if match == nil {
+			continue
+		}
+
+		typedEvent := Civ5TypedReplayEvent{Kind: pattern.Kind, CivId: -1, OtherCivId: -1}
+		for i, groupName := range pattern.Pattern.SubexpNames() {
+			value := strings.TrimSpace(match[i])
+			switch groupName {
+			case "CivName":
+				typedEvent.CivName = trimArticle(value)
+			case "OtherCivName":
+				typedEvent.OtherCivName = trimArticle(value)
+			case "CityName":
+				typedEvent.CityName = value
+			case "WonderName":
+				typedEvent.WonderName = value
+			case "GreatPersonName":
+				typedEvent.GreatPersonName = value
+			case "ReligionName":
+				typedEvent.ReligionName = value
+			case "EraName":
+				typedEvent.EraName = value
+			}
+		}
+		typedEvent.CivId = FindReplayCiv(allCivs, typedEvent.CivName)
+		typedEvent.OtherCivId = FindReplayCiv(allCivs, typedEvent.OtherCivName)
+
+		// Something that was destroyed but isn't a civ is a city
+		if typedEvent.Kind == ReplayEventKindCivEliminated && typedEvent.CivId == -1 {
+			typedEvent.Kind = ReplayEventKindCityRazed
+			typedEvent.CityName = typedEvent.CivName
+			typedEvent.CivName = ""
+		}
+		return typedEvent, true
+	}
+	return Civ5TypedReplayEvent{}, false
+}
+
+// ClassifyReplayEvents classifies every event of replayData, in the same order
+func ClassifyReplayEvents(replayData *Civ5ReplayData) []Civ5TypedReplayEvent {
+	typedEvents := make([]Civ5TypedReplayEvent, len(replayData.AllReplayEvents))
+	for i, event := range replayData.AllReplayEvents {
+		typedEvents[i] = ClassifyReplayEvent(event, replayData.AllCivs)
+	}
+	return typedEvents
+}
+
+// Description returns a short English description of the event, e.g. "Rome declared war on
+// Greece". Events that weren't recognized are described by their text.
+func (typedEvent Civ5TypedReplayEvent) Description() string {
+	civName := typedEvent.CivName
+	if civName == "" {
+		civName = "Unknown civ"
+	}
+	switch typedEvent.Kind {
+	case ReplayEventKindCityFounded:
+		return fmt.Sprintf("%s founded %s", civName, typedEvent.CityName)
+	case ReplayEventKindTilesClaimed:
+		return fmt.Sprintf("%s claimed %d tiles", civName, len(typedEvent.Event.Tiles))
+	case ReplayEventKindCityTransferred:
+		if typedEvent.CityName != "" {
+			return fmt.Sprintf("%s took over %s", civName, typedEvent.CityName)
+		}
+		return fmt.Sprintf("%s took over %d tiles", civName, len(typedEvent.Event.Tiles))
+	case ReplayEventKindTilesRazed:
+		if typedEvent.CityName != "" {
+			return fmt.Sprintf("%s was razed", typedEvent.CityName)
+		}
+		return fmt.Sprintf("%d tiles were razed", len(typedEvent.Event.Tiles))
+	case ReplayEventKindWarDeclared:
+		return fmt.Sprintf("%s declared war on %s", civName, typedEvent.OtherCivName)
+	case ReplayEventKindPeaceMade:
+		return fmt.Sprintf("%s made peace with %s", civName, typedEvent.OtherCivName)
+	case ReplayEventKindWonderCompleted:
+		return fmt.Sprintf("%s completed %s", civName, typedEvent.WonderName)
+	case ReplayEventKindGreatPersonBorn:
+		return fmt.Sprintf("%s was born in %s", typedEvent.GreatPersonName, typedEvent.CityName)
+	case ReplayEventKindPantheonFounded:
+		return fmt.Sprintf("%s founded a pantheon", civName)
+	case ReplayEventKindReligionFounded:
+		if typedEvent.CityName != "" {
+			return fmt.Sprintf("%s founded %s in %s", civName, typedEvent.ReligionName, typedEvent.CityName)
+		}
+		return fmt.Sprintf("%s founded %s", civName, typedEvent.ReligionName)
+	case ReplayEventKindCityCaptured:
+		return fmt.Sprintf("%s captured %s", civName, typedEvent.CityName)
+	case ReplayEventKindCityRazed:
+		return fmt.Sprintf("%s was razed", typedEvent.CityName)
+	case ReplayEventKindCivEliminated:
+		return fmt.Sprintf("%s was eliminated", civName)
+	case ReplayEventKindEraEntered:
+		return fmt.Sprintf("%s entered the %s", civName, typedEvent.EraName)
+	}
+	return typedEvent.Event.Text
+}
@@ -0,0 +1,179 @@
+package fileio
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"testing"
+)
+
+var eventTestCivs = []Civ5ReplayCiv{
+	{Leader: "Augustus Caesar", LongName: "Roman Empire", Name: "Rome", Demonym: "Roman"},
+	{Leader: "Alexander", LongName: "Greek Empire", Name: "Greece", Demonym: "Greek"},
+}
+
+func TestClassifyReplayEvent(t *testing.T) {
+	tests := []struct {
+		name  string
+		event Civ5ReplayEvent
+		want  Civ5TypedReplayEvent
+	}{
+		{
+			"city founded",
+			Civ5ReplayEvent{TypeId: ReplayEventCityFounded, CivId: 0, Text: "Rome is founded."},
+			Civ5TypedReplayEvent{Kind: ReplayEventKindCityFounded, CivId: 0, CivName: "Rome", OtherCivId: -1, CityName: "Rome"},
+		},
+		{
+			"tiles claimed",
+			Civ5ReplayEvent{TypeId: ReplayEventTilesClaimed, CivId: 1},
+			Civ5TypedReplayEvent{Kind: ReplayEventKindTilesClaimed, CivId: 1, CivName: "Greece", OtherCivId: -1},
+		},
+		{
+			"war declared",
+			Civ5ReplayEvent{CivId: -1, Text: "Rome has declared war on Greece!"},
+			Civ5TypedReplayEvent{Kind: ReplayEventKindWarDeclared, CivId: 0, CivName: "Rome", OtherCivId: 1, OtherCivName: "Greece"},
+		},
+		{
+			"peace made",
+			Civ5ReplayEvent{CivId: 1, Text: "The Greek Empire made peace with the Roman Empire."},
+			Civ5TypedReplayEvent{Kind: ReplayEventKindPeaceMade, CivId: 1, CivName: "Greek Empire", OtherCivId: 0, OtherCivName: "Roman Empire"},
+		},
+		{
+			"wonder",
+			Civ5ReplayEvent{CivId: 0, Text: "Rome completes the Great Library!"},
+			Civ5TypedReplayEvent{Kind: ReplayEventKindWonderCompleted, CivId: 0, CivName: "Rome", OtherCivId: -1, WonderName: "Great Library"},
+		},
+		{
+			"great person",
+			Civ5ReplayEvent{CivId: 1, Text: "Homer has been born in Athens!"},
+			Civ5TypedReplayEvent{Kind: ReplayEventKindGreatPersonBorn, CivId: 1, CivName: "Greece", OtherCivId: -1, GreatPersonName: "Homer", CityName: "Athens"},
+		},
+		{
+			"religion",
+			Civ5ReplayEvent{CivId: 1, Text: "Greece has founded Buddhism in Athens!"},
+			Civ5TypedReplayEvent{Kind: ReplayEventKindReligionFounded, CivId: 1, CivName: "Greece", OtherCivId: -1, ReligionName: "Buddhism", CityName: "Athens"},
+		},
+		{
+			"religion without a holy city",
+			Civ5ReplayEvent{CivId: 0, Text: "Rome has founded Christianity!"},
+			Civ5TypedReplayEvent{Kind: ReplayEventKindReligionFounded, CivId: 0, CivName: "Rome", OtherCivId: -1, ReligionName: "Christianity"},
+		},
+		{
+			"custom religion",
+			Civ5ReplayEvent{CivId: 0, Text: "Rome has founded the religion of Jupiter in Antium."},
+			Civ5TypedReplayEvent{Kind: ReplayEventKindReligionFounded, CivId: 0, CivName: "Rome", OtherCivId: -1, ReligionName: "Jupiter", CityName: "Antium"},
+		},
+		{
+			"pantheon",
+			Civ5ReplayEvent{CivId: 0, Text: "Rome has founded a Pantheon (God of War)."},
+			Civ5TypedReplayEvent{Kind: ReplayEventKindPantheonFounded, CivId: 0, CivName: "Rome", OtherCivId: -1},
+		},
+		{
+			"city captured",
+			Civ5ReplayEvent{CivId: 0, Text: "Athens was captured by the Roman!!!"},
+			Civ5TypedReplayEvent{Kind: ReplayEventKindCityCaptured, CivId: 0, CivName: "Roman", OtherCivId: -1, CityName: "Athens"},
+		},
+		{
+			"civ eliminated",
+			Civ5ReplayEvent{CivId: -1, Text: "Greece has been destroyed!"},
+			Civ5TypedReplayEvent{Kind: ReplayEventKindCivEliminated, CivId: 1, CivName: "Greece", OtherCivId: -1},
+		},
+		{
+			"city destroyed",
+			Civ5ReplayEvent{CivId: -1, Text: "Sparta has been destroyed!"},
+			Civ5TypedReplayEvent{Kind: ReplayEventKindCityRazed, CivId: -1, OtherCivId: -1, CityName: "Sparta"},
+		},
+		{
+			"era",
+			Civ5ReplayEvent{CivId: 0, Text: "Rome has entered the Medieval Era!"},
+			Civ5TypedReplayEvent{Kind: ReplayEventKindEraEntered, CivId: 0, CivName: "Rome", OtherCivId: -1, EraName: "Medieval Era"},
+		},
+		{
+			"city transferred",
+			Civ5ReplayEvent{TypeId: ReplayEventCityTransferred, CivId: 1, Text: "Antium was captured by the Greek!!!"},
+			Civ5TypedReplayEvent{Kind: ReplayEventKindCityTransferred, CivId: 1, CivName: "Greece", OtherCivId: -1, CityName: "Antium"},
+		},
+		{
+			"unrecognized",
+			Civ5ReplayEvent{CivId: 0, Text: "Something happened."},
+			Civ5TypedReplayEvent{Kind: ReplayEventKindOther, CivId: 0, CivName: "Rome", OtherCivId: -1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ClassifyReplayEvent(tt.event, eventTestCivs)
+			tt.want.Event = tt.event
+			if got.Kind != tt.want.Kind || got.CivId != tt.want.CivId || got.CivName != tt.want.CivName ||
+				got.OtherCivId != tt.want.OtherCivId || got.OtherCivName != tt.want.OtherCivName ||
+				got.CityName != tt.want.CityName || got.WonderName != tt.want.WonderName ||
+				got.GreatPersonName != tt.want.GreatPersonName || got.ReligionName != tt.want.ReligionName ||
+				got.EraName != tt.want.EraName || got.Event.Text != tt.event.Text {
+				t.Errorf("ClassifyReplayEvent() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyReplayEventTextFixture(t *testing.T) {
+	file, err := os.Open("testdata/replay_events_en.txt")
+	if err != nil {
+		t.Fatalf("failed to open fixture: %v", err)
+	}
+	defer file.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kind, text, found := strings.Cut(line, "\t")
+		if !found {
+			t.Fatalf("fixture line %q has no tab between the kind and the text", line)
+		}
+		// A TypeId without a kind of its own, so the text decides
+		event := Civ5ReplayEvent{TypeId: 5, CivId: 0, Text: text}
+		if got := ClassifyReplayEvent(event, eventTestCivs); got.Kind != ReplayEventKind(kind) {
+			t.Errorf("ClassifyReplayEvent(%q).Kind = %q, want %q", text, got.Kind, kind)
+		}
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	if count == 0 {
+		t.Error("fixture has no events")
+	}
+}
+
+func TestFindReplayCiv(t *testing.T) {
+	tests := []struct {
+		name string
+		want int
+	}{
+		{"Rome", 0},
+		{"the roman empire", 0},
+		{"Greek", 1},
+		{"Alexander", 1},
+		{"Carthage", -1},
+		{"", -1},
+	}
+	for _, tt := range tests {
+		if got := FindReplayCiv(eventTestCivs, tt.name); got != tt.want {
+			t.Errorf("FindReplayCiv(%q) = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTypedReplayEventDescription(t *testing.T) {
+	typedEvent := ClassifyReplayEvent(Civ5ReplayEvent{CivId: 0, Text: "Rome has declared war on Greece!"}, eventTestCivs)
+	if got, want := typedEvent.Description(), "Rome declared war on Greece"; got != want {
+		t.Errorf("Description() = %q, want %q", got, want)
+	}
+
+	other := ClassifyReplayEvent(Civ5ReplayEvent{CivId: 0, Text: "Something happened."}, eventTestCivs)
+	if got, want := other.Description(), "Something happened."; got != want {
+		t.Errorf("Description() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,491 @@
+package fileio
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The text map format is a plain text form of Civ5MapData meant to be read in diffs. A file
+// looks like
+//
+//	Civ5MapText 1
+//	size 4 2
+//	legend terrain g 0 TERRAIN_GRASS
+//	legend terrain o 6 TERRAIN_OCEAN
+//	...
+//	layer terrain
+//	oggo
+//	oooo
+//	...
+//	city 1 0 0 "Roma"
+//	unit 2 1 0
+//	data
+//	{ ... }
+//
+// The first line has the SchemaVersion of the data. Every layer is one tile field drawn as a grid
+// of characters, one line per map row from the top (north) of the map down, and the legend lines
+// give the value of each character with the name it has in the map's lists. City ids and names
+// and unit ids are listed per tile since a character per city or unit would run out. The JSON
+// after the data line holds the rest of the map, which has no tiles.
+const textMapMagic = "Civ5MapText"
+
+// textMapEmptyChar is used for the value a layer uses for tiles without a feature, owner, etc.
+const textMapEmptyChar = '.'
+
+// textMapPalette lists the characters used for values that don't get one from their name or
+// digit. Digits aren't included so that a digit always stands for itself.
+const textMapPalette = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ!$%&()*+,-/:;<=>?@[]^_{|}~'\"`\\"
+
+// textMapLayer is a tile field stored as a character grid
+type textMapLayer struct {
+	Name        string
+	Field       string
+	Improvement bool // Field is in Civ5MapTileImprovement rather than Civ5MapTilePhysical
+	// ValueName returns the name of value shown in the legend, or "" if it has none
+	ValueName func(mapData *Civ5MapData, value int) string
+}
+
+var textMapLayers = []textMapLayer{
+	{"terrain", "TerrainType", false, listValueName(func(m *Civ5MapData) []string { return m.TerrainList })},
+	{"elevation", "Elevation", false, elevationName},
+	{"features", "FeatureTerrainType", false, listValueName(func(m *Civ5MapData) []string { return m.FeatureTerrainList })},
+	{"wonders", "FeatureWonderType", false, listValueName(func(m *Civ5MapData) []string { return m.FeatureWonderList })},
+	{"resources", "ResourceType", false, listValueName(func(m *Civ5MapData) []string { return m.ResourceList })},
+	{"resourceamounts", "ResourceAmount", false, nil},
+	{"rivers", "RiverData", false, nil},
+	{"continents", "Continent", false, nil},
+	{"owners", "Owner", true, ownerName},
+	{"improvements", "Improvement", true, listValueName(func(m *Civ5MapData) []string { return m.TileImprovementList })},
+	{"routes", "RouteType", true, nil},
+	{"routeowners", "RouteOwner", true, ownerName},
+}
+
+// listValueName names a value by its index in one of the map's string lists
+func listValueName(list func(mapData *Civ5MapData) []string) func(*Civ5MapData, int) string {
+	return func(mapData *Civ5MapData, value int) string {
+		values := list(mapData)
+		if value < 0 || value >= len(values) {
+			return ""
+		}
+		return values[value]
+	}
+}
+
+func elevationName(mapData *Civ5MapData, value int) string {
+	switch value {
+	case ElevationFlat:
+		return "FLAT"
+	case ElevationHills:
+		return "HILLS"
+	case ElevationMountain:
+		return "MOUNTAIN"
+	}
+	return ""
+}
+
+// ownerName names a tile owner by the civ type of the player, as the political map colors it
+func ownerName(mapData *Civ5MapData, value int) string {
+	if IsInvalidTileOwner(value) {
+		return ""
+	}
+	civIndex, found := mapData.CityOwnerIndexMap[value]
+	if !found || civIndex < 0 || civIndex >= len(mapData.Civ5PlayerData) {
+		return ""
+	}
+	return mapData.Civ5PlayerData[civIndex].CivType
+}
+
+// isEmptyTextMapValue reports whether value means a tile has no feature, owner, etc.
+func isEmptyTextMapValue(value int) bool {
+	return value == -1 || value == 0xFF
+}
+
+// assignLegendChars picks a character for each of the sorted values. The empty value gets
+// textMapEmptyChar, single digits stand for themselves if they have no name, and named values
+// get the first free letter of their name after the prefix, so TERRAIN_GRASS is g.
+func assignLegendChars(values []int, names []string) (map[int]byte, error) {
+	chars := make(map[int]byte, len(values))
+	used := map[byte]bool{}
+	for i, value := range values {
+		candidates := ""
+		if isEmptyTextMapValue(value) && !used[textMapEmptyChar] {
+			candidates = string(textMapEmptyChar)
+		} else if names[i] != "" {
+			_, suffix, found := strings.Cut(names[i], "_")
+			if !found {
+				suffix = names[i]
+			}
+			candidates = strings.ToLower(suffix) + strings.ToUpper(suffix)
+			candidates = strings.Map(func(r rune) rune {
+				if r >= '0' && r <= '9' {
+					return -1
+				}
+				return r
+			}, candidates)
+		} else if value >= 0 && value <= 9 {
+			candidates = strconv.Itoa(value)
+		}
+		candidates += textMapPalette
+
+		for j := 0; j < len(candidates); j++ {
+			c := candidates[j]
+			if c > ' ' && c < 0x7F && c != '#' && !used[c] {
+				chars[value] = c
+				used[c] = true
+				break
+			}
+		}
+		if _, found := chars[value]; !found {
+			return nil, fmt.Errorf("too many different values for one character each")
+		}
+	}
+	return chars, nil
+}
+
+// WriteCiv5MapText writes mapData to writer in the text map format
+func WriteCiv5MapText(mapData *Civ5MapData, writer io.Writer) error {
+	width, height, err := checkTileGrids(mapData)
+	if err != nil {
+		return err
+	}
+	hasImprovements := len(mapData.MapTileImprovements) > 0
+
+	type layerGrid struct {
+		layer  textMapLayer
+		values [][]int
+		chars  map[int]byte
+	}
+	grids := make([]layerGrid, 0, len(textMapLayers))
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%s %d\n", textMapMagic, CurrentSchemaVersion)
+	fmt.Fprintf(&builder, "size %d %d\n", width, height)
+
+	for _, layer := range textMapLayers {
+		var values [][]int
+		if !layer.Improvement {
+			values, err = tileGridField(mapData.MapTiles, layer.Field)
+		} else if hasImprovements {
+			values, err = tileGridField(mapData.MapTileImprovements, layer.Field)
+		} else {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		var distinct []int
+		for _, row := range values {
+			for _, value := range row {
+				if !slices.Contains(distinct, value) {
+					distinct = append(distinct, value)
+				}
+			}
+		}
+		slices.Sort(distinct)
+		names := make([]string, len(distinct))
+		if layer.ValueName != nil {
+			for i, value := range distinct {
+				names[i] = layer.ValueName(mapData, value)
+			}
+		}
+		chars, err := assignLegendChars(distinct, names)
+		if err != nil {
+			return fmt.Errorf("layer %s has %w", layer.Name, err)
+		}
+
+		for i, value := range distinct {
+			legend := fmt.Sprintf("legend %s %c %d %s", layer.Name, chars[value], value, names[i])
+			builder.WriteString(strings.TrimRight(legend, " ") + "\n")
+		}
+		grids = append(grids, layerGrid{layer: layer, values: values, chars: chars})
+	}
+
+	builder.WriteString("# Rows are listed from the top (north) of the map down\n")
+	for _, grid := range grids {
+		fmt.Fprintf(&builder, "layer %s\n", grid.layer.Name)
+		for i := height - 1; i >= 0; i-- {
+			for _, value := range grid.values[i] {
+				builder.WriteByte(grid.chars[value])
+			}
+			builder.WriteByte('\n')
+		}
+	}
+
+	for i, row := range mapData.MapTileImprovements {
+		for j, tile := range row {
+			if tile.CityId != InvalidCityId || tile.CityName != "" {
+				fmt.Fprintf(&builder, "city %d %d %d %s\n", j, i, tile.CityId, strconv.Quote(tile.CityName))
+			}
+		}
+	}
+	for i, row := range mapData.MapTileImprovements {
+		for j, tile := range row {
+			if tile.UnitId != InvalidUnitId {
+				fmt.Fprintf(&builder, "unit %d %d %d\n", j, i, tile.UnitId)
+			}
+		}
+	}
+
+	otherData := *mapData
+	otherData.MapTiles = nil
+	otherData.MapTileImprovements = nil
+	data, err := json.MarshalIndent(&otherData, "", " ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal map data: %w", err)
+	}
+	builder.WriteString("data\n")
+	builder.Write(data)
+	builder.WriteByte('\n')
+
+	_, err = io.WriteString(writer, builder.String())
+	return err
+}
+
+// textMapParser holds the position in the lines of a text map
+type textMapParser struct {
+	lines []string
+	index int
+}
+
+// next returns the next line that isn't blank or a comment, or false at the end of the file
+func (parser *textMapParser) next() (string, bool) {
+	for parser.index < len(parser.lines) {
+		line := parser.lines[parser.index]
+		parser.index++
+		if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			return line, true
+		}
+	}
+	return "", false
+}
+
+// errorf returns an error for the line that was just read
+func (parser *textMapParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", parser.index, fmt.Sprintf(format, args...))
+}
+
+// ParseCiv5MapText reads a map written by WriteCiv5MapText
+func ParseCiv5MapText(reader io.Reader) (*Civ5MapData, error) {
+	contents, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.ReplaceAll(string(contents), "\r\n", "\n")
+	parser := &textMapParser{lines: strings.Split(text, "\n")}
+
+	line, _ := parser.next()
+	var schemaVersion int
+	if _, err := fmt.Sscanf(line, textMapMagic+" %d", &schemaVersion); err != nil {
+		return nil, fmt.Errorf("not a text map, expected %s on the first line", textMapMagic)
+	}
+	// Text maps were added in version 1
+	if schemaVersion < 1 || schemaVersion > CurrentSchemaVersion {
+		return nil, fmt.Errorf("text map has SchemaVersion %d, only versions 1 to %d are supported", schemaVersion, CurrentSchemaVersion)
+	}
+
+	line, _ = parser.next()
+	var width, height int
+	if _, err := fmt.Sscanf(line, "size %d %d", &width, &height); err != nil {
+		return nil, parser.errorf("expected the map size, got %q", line)
+	}
+	// Check each dimension before multiplying so that the area can't overflow
+	for _, dimension := range []int{width, height} {
+		if dimension < 0 || dimension > MaxArrayLength {
+			return nil, &ErrImplausibleLength{Section: "text map size", Length: int64(dimension)}
+		}
+	}
+	if width*height > MaxArrayLength {
+		return nil, &ErrImplausibleLength{Section: "text map size", Length: int64(width) * int64(height)}
+	}
+
+	layerIndex := map[string]int{}
+	for i, layer := range textMapLayers {
+		layerIndex[layer.Name] = i
+	}
+	legends := make([]map[byte]int, len(textMapLayers))
+	grids := make([][][]int, len(textMapLayers))
+	type tileValue struct {
+		X, Y, Value int
+		Name        string
+	}
+	var cities, units []tileValue
+	var data string
+
+	for data == "" {
+		line, ok := parser.next()
+		if !ok {
+			return nil, fmt.Errorf("text map has no data section")
+		}
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "legend":
+			if len(fields) < 4 || len(fields[2]) != 1 {
+				return nil, parser.errorf("invalid legend %q", line)
+			}
+			index, found := layerIndex[fields[1]]
+			if !found {
+				return nil, parser.errorf("unknown layer %q", fields[1])
+			}
+			value, err := strconv.Atoi(fields[3])
+			if err != nil {
+				return nil, parser.errorf("invalid legend value %q", fields[3])
+			}
+			if legends[index] == nil {
+				legends[index] = map[byte]int{}
+			}
+			if _, found := legends[index][fields[2][0]]; found {
+				return nil, parser.errorf("%q is in the %s legend twice", fields[2], fields[1])
+			}
+			legends[index][fields[2][0]] = value
+		case "layer":
+			if len(fields) != 2 {
+				return nil, parser.errorf("invalid layer %q", line)
+			}
+			index, found := layerIndex[fields[1]]
+			if !found {
+				return nil, parser.errorf("unknown layer %q", fields[1])
+			}
+			if grids[index] != nil {
+				return nil, parser.errorf("layer %s is listed twice", fields[1])
+			}
+			grid := make([][]int, height)
+			for i := height - 1; i >= 0; i-- {
+				if parser.index >= len(parser.lines) {
+					return nil, fmt.Errorf("layer %s has fewer than %d rows", fields[1], height)
+				}
+				row := parser.lines[parser.index]
+				parser.index++
+				if len(row) != width {
+					return nil, parser.errorf("layer %s row has %d characters, expected %d", fields[1], len(row), width)
+				}
+				grid[i] = make([]int, width)
+				for j := 0; j < width; j++ {
+					value, found := legends[index][row[j]]
+					if !found {
+						return nil, parser.errorf("%q isn't in the %s legend", row[j], fields[1])
+					}
+					grid[i][j] = value
+				}
+			}
+			grids[index] = grid
+		case "city", "unit":
+			var tile tileValue
+			if _, err := fmt.Sscanf(line, fields[0]+" %d %d %d", &tile.X, &tile.Y, &tile.Value); err != nil {
+				return nil, parser.errorf("invalid %s %q", fields[0], line)
+			}
+			if tile.X < 0 || tile.X >= width || tile.Y < 0 || tile.Y >= height {
+				return nil, parser.errorf("%s at (%d, %d) is outside the map", fields[0], tile.X, tile.Y)
+			}
+			if fields[0] == "city" {
+				nameStart := strings.IndexByte(line, '"')
+				if nameStart < 0 {
+					return nil, parser.errorf("city %q has no name", line)
+				}
+				name, err := strconv.Unquote(strings.TrimSpace(line[nameStart:]))
+				if err != nil {
+					return nil, parser.errorf("invalid city name in %q", line)
+				}
+				tile.Name = name
+				cities = append(cities, tile)
+			} else {
+				units = append(units, tile)
+			}
+		case "data":
+			data = strings.Join(parser.lines[parser.index:], "\n")
+		default:
+			return nil, parser.errorf("unexpected %q", fields[0])
+		}
+	}
+
+	// The data section is upgraded by the same migrations as a map json
+	document := fmt.Sprintf(`{"FileFormat": ".Civ5Map", "SchemaVersion": %d, "MapData": %s}`, schemaVersion, data)
+	migrated, _, err := migrateJsonDocument([]byte(document))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the data section: %w", err)
+	}
+	var mapJson Civ5MapJson
+	if err := json.Unmarshal(migrated, &mapJson); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the data section: %w", err)
+	}
+	mapData := mapJson.MapData
+	if mapData == nil {
+		return nil, fmt.Errorf("text map data section is empty")
+	}
+
+	hasImprovements := false
+	for i, layer := range textMapLayers {
+		if layer.Improvement && grids[i] != nil {
+			hasImprovements = true
+		}
+	}
+	mapData.MapTiles = newPhysicalTileGrid(width, height)
+	if hasImprovements {
+		mapData.MapTileImprovements = newImprovementTileGrid(width, height)
+	} else if len(cities) > 0 || len(units) > 0 {
+		return nil, fmt.Errorf("text map has cities or units but no owners, improvements or routes")
+	}
+	for i, layer := range textMapLayers {
+		if layer.Improvement && !hasImprovements {
+			continue
+		}
+		if grids[i] == nil {
+			return nil, fmt.Errorf("text map is missing layer %s", layer.Name)
+		}
+		if layer.Improvement {
+			setTileGridField(mapData.MapTileImprovements, layer.Field, grids[i])
+		} else {
+			setTileGridField(mapData.MapTiles, layer.Field, grids[i])
+		}
+	}
+
+	for _, row := range mapData.MapTileImprovements {
+		for _, tile := range row {
+			tile.CityId = InvalidCityId
+			tile.UnitId = InvalidUnitId
+		}
+	}
+	for _, city := range cities {
+		tile := mapData.MapTileImprovements[city.Y][city.X]
+		tile.CityId = city.Value
+		tile.CityName = city.Name
+	}
+	for _, unit := range units {
+		mapData.MapTileImprovements[unit.Y][unit.X].UnitId = unit.Value
+	}
+	return mapData, nil
+}
+
+// ReadCiv5MapTextFile reads a text map from filename
+func ReadCiv5MapTextFile(filename string) (*Civ5MapData, error) {
+	inputFile, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open text map %q: %w", filename, err)
+	}
+	defer inputFile.Close()
+
+	mapData, err := ParseCiv5MapText(bufio.NewReader(inputFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read text map %q: %w", filename, err)
+	}
+	return mapData, nil
+}
+
+// ExportCiv5MapTextFile writes mapData to outputFilename in the text map format
+func ExportCiv5MapTextFile(mapData *Civ5MapData, outputFilename string) error {
+	outputFile, err := os.Create(outputFilename)
+	if err != nil {
+		return fmt.Errorf("failed to create %q: %w", outputFilename, err)
+	}
+	defer outputFile.Close()
+
+	if err := WriteCiv5MapText(mapData, outputFile); err != nil {
+		return fmt.Errorf("failed to write text map to %q: %w", outputFilename, err)
+	}
+	return nil
+}
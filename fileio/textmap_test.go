@@ -0,0 +1,171 @@
+package fileio
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTextMapRoundTrip(t *testing.T) {
+	mapData := newWriterTestMapData(MapVersion12)
+	mapData.MapTileImprovements[1][2].CityName = `Monaco "the rock"`
+
+	var buf bytes.Buffer
+	if err := WriteCiv5MapText(mapData, &buf); err != nil {
+		t.Fatalf("WriteCiv5MapText returned error: %v", err)
+	}
+	got, err := ParseCiv5MapText(&buf)
+	if err != nil {
+		t.Fatalf("ParseCiv5MapText returned error: %v", err)
+	}
+	if !reflect.DeepEqual(got, mapData) {
+		t.Errorf("text map round trip = %+v, want %+v", got, mapData)
+	}
+}
+
+func TestWriteCiv5MapTextLayout(t *testing.T) {
+	mapData := newWriterTestMapData(MapVersion12)
+	var buf bytes.Buffer
+	if err := WriteCiv5MapText(mapData, &buf); err != nil {
+		t.Fatalf("WriteCiv5MapText returned error: %v", err)
+	}
+	text := buf.String()
+
+	// Row 1 is written first since rows are listed from the top of the map
+	for _, want := range []string{
+		fmt.Sprintf("Civ5MapText %d\nsize 3 2\n", CurrentSchemaVersion),
+		"legend terrain g 0 TERRAIN_GRASS\nlegend terrain o 1 TERRAIN_OCEAN\n",
+		"legend features . 255\n",
+		"layer terrain\nogo\ngog\n",
+		"layer elevation\nhhh\nfff\n",
+		"legend owners c 32 MINOR_CIV_MONACO\n",
+		"layer owners\n.cc\nr..\n",
+		`city 0 0 0 "Rome"`,
+		"unit 2 0 1\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text map doesn't contain %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestTextMapWithoutImprovements(t *testing.T) {
+	mapData := newWriterTestMapData(MapVersion12)
+	mapData.MapTileImprovements = nil
+
+	var buf bytes.Buffer
+	if err := WriteCiv5MapText(mapData, &buf); err != nil {
+		t.Fatalf("WriteCiv5MapText returned error: %v", err)
+	}
+	if strings.Contains(buf.String(), "layer owners") {
+		t.Error("text map of a map without improvements has an owners layer")
+	}
+	got, err := ParseCiv5MapText(&buf)
+	if err != nil {
+		t.Fatalf("ParseCiv5MapText returned error: %v", err)
+	}
+	if !reflect.DeepEqual(got, mapData) {
+		t.Errorf("text map round trip = %+v, want %+v", got, mapData)
+	}
+}
+
+func TestParseCiv5MapTextErrors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCiv5MapText(newWriterTestMapData(MapVersion12), &buf); err != nil {
+		t.Fatalf("WriteCiv5MapText returned error: %v", err)
+	}
+	valid := buf.String()
+	magic := fmt.Sprintf("Civ5MapText %d", CurrentSchemaVersion)
+
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"not a text map", "hello\n"},
+		{"newer version", strings.Replace(valid, magic, "Civ5MapText 99", 1)},
+		{"version 0", strings.Replace(valid, magic, "Civ5MapText 0", 1)},
+		{"row too short", strings.Replace(valid, "layer rivers\n010\n", "layer rivers\n01\n", 1)},
+		{"character not in legend", strings.Replace(valid, "layer rivers\n010\n", "layer rivers\n01z\n", 1)},
+		{"missing layer", strings.Replace(valid, "layer rivers", "layer continents", 1)},
+		{"city outside map", strings.Replace(valid, `city 0 0 0 "Rome"`, `city 9 0 0 "Rome"`, 1)},
+		{"no data", valid[:strings.Index(valid, "data\n")]},
+	}
+	for _, tt := range tests {
+		if _, err := ParseCiv5MapText(strings.NewReader(tt.text)); err == nil {
+			t.Errorf("%s: ParseCiv5MapText() = nil error, want an error", tt.name)
+		}
+	}
+	// The area of this size overflows int and would wrap around to a negative number
+	overflow := strings.Replace(valid, "size 3 2", fmt.Sprintf("size %d 2", math.MaxInt/2+1), 1)
+	var lengthErr *ErrImplausibleLength
+	if _, err := ParseCiv5MapText(strings.NewReader(overflow)); !errors.As(err, &lengthErr) {
+		t.Errorf("ParseCiv5MapText(overflowing size) error = %v, want an ErrImplausibleLength", err)
+	}
+}
+
+// TestParseCiv5MapTextMigratesData checks that the data section of an older text map is upgraded
+// like a map json
+func TestParseCiv5MapTextMigratesData(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCiv5MapText(newWriterTestMapData(MapVersion12), &buf); err != nil {
+		t.Fatalf("WriteCiv5MapText returned error: %v", err)
+	}
+	layers, data, _ := strings.Cut(buf.String(), "data\n")
+	var document map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &document); err != nil {
+		t.Fatalf("Unmarshal returned error: %v", err)
+	}
+	delete(document, "FeatureWonderList")
+	oldData, _ := json.Marshal(document)
+	layers = strings.Replace(layers, fmt.Sprintf("Civ5MapText %d", CurrentSchemaVersion), "Civ5MapText 1", 1)
+
+	mapData, err := ParseCiv5MapText(strings.NewReader(layers + "data\n" + string(oldData)))
+	if err != nil {
+		t.Fatalf("ParseCiv5MapText returned error: %v", err)
+	}
+	if len(mapData.FeatureWonderList) != len(DefaultFeatureWonderList) {
+		t.Errorf("FeatureWonderList = %v, want the default list", mapData.FeatureWonderList)
+	}
+}
+
+func TestAssignLegendCharsRunsOut(t *testing.T) {
+	values := make([]int, 200)
+	for i := range values {
+		values[i] = i + 100
+	}
+	if _, err := assignLegendChars(values, make([]string, len(values))); err == nil {
+		t.Error("assignLegendChars(200 values) = nil error, want an error")
+	}
+}
+
+// TestTextMapRoundTripCommittedMaps checks that the maps in the maps directory survive the text
+// format unchanged
+func TestTextMapRoundTripCommittedMaps(t *testing.T) {
+	filenames, err := filepath.Glob(filepath.Join("..", "maps", "*.json"))
+	if err != nil {
+		t.Fatalf("Glob returned error: %v", err)
+	}
+	for _, filename := range filenames {
+		mapData, err := ImportCiv5MapFileFromJson(filename)
+		if err != nil {
+			t.Fatalf("ImportCiv5MapFileFromJson(%q) returned error: %v", filename, err)
+		}
+		var buf bytes.Buffer
+		if err := WriteCiv5MapText(mapData, &buf); err != nil {
+			t.Fatalf("WriteCiv5MapText(%q) returned error: %v", filename, err)
+		}
+		got, err := ParseCiv5MapText(&buf)
+		if err != nil {
+			t.Fatalf("ParseCiv5MapText(%q) returned error: %v", filename, err)
+		}
+		if !reflect.DeepEqual(got, mapData) {
+			t.Errorf("%s changed in the text map round trip", filename)
+		}
+	}
+}
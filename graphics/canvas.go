@@ -3,6 +3,7 @@ package graphics
 import (
 	"fmt"
 	"image"
+	"unicode/utf8"
 
 	"github.com/fogleman/gg"
 )
@@ -31,6 +32,7 @@ type Canvas interface {
 
 	// Text operations
 	DrawString(text string, x, y float64)
+	MeasureString(text string) (width, height float64)
 
 	// Final output
 	Image() image.Image
@@ -90,6 +92,10 @@ func (d *DrawingContext) DrawString(text string, x, y float64) {
 	d.dc.DrawString(text, x, y)
 }
 
+func (d *DrawingContext) MeasureString(text string) (width, height float64) {
+	return d.dc.MeasureString(text)
+}
+
 func (d *DrawingContext) Image() image.Image {
 	return d.dc.Image()
 }
@@ -161,6 +167,11 @@ func (m *MockCanvas) DrawString(text string, x, y float64) {
 		fmt.Sprintf("DrawString(\"%s\", %.2f, %.2f)", text, x, y))
 }
 
+// MeasureString measures text in gg's default 7x13 font without recording an operation
+func (m *MockCanvas) MeasureString(text string) (width, height float64) {
+	return 7 * float64(utf8.RuneCountInString(text)), 13
+}
+
 func (m *MockCanvas) Image() image.Image {
 	// Return a simple 1x1 image for testing
 	return image.NewRGBA(image.Rect(0, 0, 1, 1))
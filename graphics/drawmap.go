@@ -11,10 +11,17 @@ import (
 
 // DrawingConfig holds configuration for map drawing
 type DrawingConfig struct {
-	Radius float64
+	Radius             float64
+	ShowStartPositions bool
+	ShowFeatures       bool           // Draw a pattern for forests, jungles, marshes and other terrain features
+	ShowHills          bool           // Draw two rounded hilltops on each hill tile
+	ShadedRelief       bool           // Shade land tiles by the slope of the terrain, see TileHillshade
+	ShowNaturalWonders bool           // Draw an icon and a name label on each natural wonder
+	Resources          ResourceFilter // Resources to draw a glyph for, none by default
+	ShowImprovements   bool           // Draw a symbol on each improved tile and a legend of the symbols
 }
 
-// DefaultDrawingConfig returns the default drawing configuration
+// DefaultDrawingConfig returns the default drawing configuration, which has every optional layer off
 func DefaultDrawingConfig() *DrawingConfig {
 	return &DrawingConfig{
 		Radius: 16.0,
@@ -46,6 +53,20 @@ func (mr *MapRenderer) DrawMountain(canvas Canvas, imageX, imageY float64) {
 	canvas.Fill()
 }
 
+// DrawHills draws two rounded hilltops in the lower half of the tile, below any feature pattern
+func (mr *MapRenderer) DrawHills(canvas Canvas, imageX, imageY float64) {
+	radius := mr.config.Radius
+	canvas.SetLineWidth(1.5)
+	canvas.SetColor(94, 78, 48) // brown
+	for _, hill := range []struct{ OffsetX, OffsetY, Size float64 }{{-0.25, -0.6, 0.28}, {0.22, -0.55, 0.22}} {
+		for _, segment := range arcLines(imageX+hill.OffsetX*radius, imageY+hill.OffsetY*radius, hill.Size*radius, 6) {
+			canvas.DrawLine(segment.X1, segment.Y1, segment.X2, segment.Y2)
+			canvas.Stroke()
+		}
+	}
+	canvas.SetLineWidth(1.0)
+}
+
 // GetNewCityColor returns a modified city color for better visibility
 func (mr *MapRenderer) GetNewCityColor(cityColor color.RGBA) color.RGBA {
 	return mr.InterpolateColor(cityColor, color.RGBA{255, 255, 255, 255}, 0.2)
@@ -65,8 +86,24 @@ func (mr *MapRenderer) drawEntity(canvas Canvas, entity Entity) {
 	switch entity.Type {
 	case EntityMountain:
 		mr.DrawMountain(canvas, entity.X, entity.Y)
+	case EntityHills:
+		if mr.config.ShowHills {
+			mr.DrawHills(canvas, entity.X, entity.Y)
+		}
 	case EntityCity:
 		mr.DrawCityIcon(canvas, entity.X, entity.Y, color.RGBA{entity.R, entity.G, entity.B, 255})
+	default:
+		mr.drawFeature(canvas, entity)
+	}
+}
+
+// drawTileFeature draws the feature pattern of tile (row, col) if features are shown
+func (mr *MapRenderer) drawTileFeature(canvas Canvas, mapData *fileio.Civ5MapData, row, col int) {
+	if !mr.config.ShowFeatures {
+		return
+	}
+	if feature, ok := TileFeatureEntity(mapData, row, col, mr.config.Radius); ok {
+		mr.drawEntity(canvas, feature)
 	}
 }
 
@@ -75,9 +112,13 @@ func (mr *MapRenderer) DrawTerrainTiles(canvas Canvas, mapData *fileio.Civ5MapDa
 	for i := 0; i < mapHeight; i++ {
 		for j := 0; j < mapWidth; j++ {
 			hex := PhysicalHexTile(mapData, i, j, mr.config.Radius)
+			if mr.config.ShadedRelief {
+				hex = ShadeHexTile(mapData, mapHeight, mapWidth, i, j, hex)
+			}
 			canvas.DrawRegularPolygon(6, hex.X, hex.Y, mr.config.Radius, math.Pi/2)
 			canvas.SetColor(hex.R, hex.G, hex.B)
 			canvas.Fill()
+			mr.drawTileFeature(canvas, mapData, i, j)
 
 			for _, entity := range TileEntities(mapData, i, j, mr.config.Radius, color.RGBA{255, 255, 255, 255}) {
 				mr.drawEntity(canvas, entity)
@@ -96,9 +137,13 @@ func (mr *MapRenderer) DrawTerritoryTiles(canvas Canvas, mapData *fileio.Civ5Map
 	for i := 0; i < mapHeight; i++ {
 		for j := 0; j < mapWidth; j++ {
 			hex, cityColor := PoliticalHexTile(mapData, i, j, mr.config.Radius)
+			if mr.config.ShadedRelief {
+				hex = ShadeHexTile(mapData, mapHeight, mapWidth, i, j, hex)
+			}
 			canvas.DrawRegularPolygon(6, hex.X, hex.Y, mr.config.Radius, math.Pi/2)
 			canvas.SetColor(hex.R, hex.G, hex.B)
 			canvas.Fill()
+			mr.drawTileFeature(canvas, mapData, i, j)
 
 			for _, entity := range TileEntities(mapData, i, j, mr.config.Radius, cityColor) {
 				mr.drawEntity(canvas, entity)
@@ -141,15 +186,42 @@ func (mr *MapRenderer) DrawRoads(canvas Canvas, mapData *fileio.Civ5MapData, map
 	}
 }
 
+// DrawStartPositionMarker draws a start position marker at the specified position
+func (mr *MapRenderer) DrawStartPositionMarker(canvas Canvas, imageX, imageY float64, fillColor, ringColor color.RGBA) {
+	canvas.DrawRegularPolygon(24, imageX, imageY, mr.config.Radius*0.6, 0)
+	canvas.SetColor(ringColor.R, ringColor.G, ringColor.B)
+	canvas.Fill()
+
+	canvas.DrawRegularPolygon(24, imageX, imageY, mr.config.Radius*0.4, 0)
+	canvas.SetColor(fillColor.R, fillColor.G, fillColor.B)
+	canvas.Fill()
+}
+
+// DrawStartPositions draws a marker on each player's start position
+func (mr *MapRenderer) DrawStartPositions(canvas Canvas, mapData *fileio.Civ5MapData, mapHeight, mapWidth int) {
+	for _, marker := range StartPositionMarkers(mapData, mapHeight, mapWidth, mr.config.Radius) {
+		mr.DrawStartPositionMarker(canvas, marker.X, marker.Y, marker.Fill, marker.Ring)
+	}
+}
+
+// DrawStartPositionNames draws the civ name below each player's start position
+func (mr *MapRenderer) DrawStartPositionNames(canvas Canvas, mapData *fileio.Civ5MapData, mapHeight, mapWidth int) {
+	for _, marker := range StartPositionMarkers(mapData, mapHeight, mapWidth, mr.config.Radius) {
+		canvas.SetColor(marker.Label.R, marker.Label.G, marker.Label.B)
+		canvas.DrawString(marker.Label.Text, marker.Label.X, marker.Label.Y)
+	}
+}
+
 // DrawPhysicalMap creates a physical map image using the abstracted canvas
 func (mr *MapRenderer) DrawPhysicalMap(canvas Canvas, mapData *fileio.Civ5MapData) image.Image {
 	mapHeight := len(mapData.MapTiles)
 	mapWidth := len(mapData.MapTiles[0])
 
 	maxImageWidth, maxImageHeight := fileio.GetImagePosition(mapHeight, mapWidth, mr.config.Radius)
+	legendWidth := mr.ImprovementLegendWidth(canvas, mapData, mapHeight, mapWidth, maxImageHeight)
 
-	// Resize canvas to fit the map
-	canvas.Resize(int(maxImageWidth), int(maxImageHeight))
+	// Resize canvas to fit the map and the legend beside it
+	canvas.Resize(int(maxImageWidth+legendWidth), int(maxImageHeight))
 
 	fmt.Println("Map height: ", mapHeight, ", width: ", mapWidth)
 
@@ -161,6 +233,18 @@ func (mr *MapRenderer) DrawPhysicalMap(canvas Canvas, mapData *fileio.Civ5MapDat
 	if len(mapData.MapTileImprovements) > 0 {
 		mr.DrawRoads(canvas, mapData, mapHeight, mapWidth)
 	}
+	if mr.config.ShowImprovements {
+		mr.DrawImprovements(canvas, mapData, mapHeight, mapWidth)
+	}
+	if mr.config.ShowNaturalWonders {
+		mr.DrawNaturalWonders(canvas, mapData, mapHeight, mapWidth)
+	}
+	if !mr.config.Resources.IsEmpty() {
+		mr.DrawResources(canvas, mapData, mapHeight, mapWidth)
+	}
+	if mr.config.ShowStartPositions {
+		mr.DrawStartPositions(canvas, mapData, mapHeight, mapWidth)
+	}
 
 	// Draw city names on top of hexes
 	canvas.InvertY()
@@ -168,6 +252,18 @@ func (mr *MapRenderer) DrawPhysicalMap(canvas Canvas, mapData *fileio.Civ5MapDat
 	if len(mapData.MapTileImprovements) > 0 {
 		mr.DrawPhysicalCityNames(canvas, mapData, mapHeight, mapWidth)
 	}
+	if mr.config.ShowNaturalWonders {
+		mr.DrawNaturalWonderNames(canvas, mapData, mapHeight, mapWidth)
+	}
+	if !mr.config.Resources.IsEmpty() {
+		mr.DrawResourceAmounts(canvas, mapData, mapHeight, mapWidth)
+	}
+	if mr.config.ShowStartPositions {
+		mr.DrawStartPositionNames(canvas, mapData, mapHeight, mapWidth)
+	}
+	if mr.config.ShowImprovements {
+		mr.DrawImprovementLegend(canvas, mapData, mapHeight, mapWidth, maxImageWidth, maxImageHeight)
+	}
 
 	return canvas.Image()
 }
@@ -230,9 +326,10 @@ func (mr *MapRenderer) DrawPoliticalMap(canvas Canvas, mapData *fileio.Civ5MapDa
 	mapWidth := len(mapData.MapTiles[0])
 
 	maxImageWidth, maxImageHeight := fileio.GetImagePosition(mapHeight, mapWidth, mr.config.Radius)
+	legendWidth := mr.ImprovementLegendWidth(canvas, mapData, mapHeight, mapWidth, maxImageHeight)
 
-	// Resize canvas to fit the map
-	canvas.Resize(int(maxImageWidth), int(maxImageHeight))
+	// Resize canvas to fit the map and the legend beside it
+	canvas.Resize(int(maxImageWidth+legendWidth), int(maxImageHeight))
 
 	fmt.Println("Map height: ", mapHeight, ", width: ", mapWidth)
 
@@ -243,10 +340,34 @@ func (mr *MapRenderer) DrawPoliticalMap(canvas Canvas, mapData *fileio.Civ5MapDa
 	mr.DrawBorders(canvas, mapData, mapHeight, mapWidth)
 	mr.DrawRivers(canvas, mapData, mapHeight, mapWidth)
 	mr.DrawRoads(canvas, mapData, mapHeight, mapWidth)
+	if mr.config.ShowImprovements {
+		mr.DrawImprovements(canvas, mapData, mapHeight, mapWidth)
+	}
+	if mr.config.ShowNaturalWonders {
+		mr.DrawNaturalWonders(canvas, mapData, mapHeight, mapWidth)
+	}
+	if !mr.config.Resources.IsEmpty() {
+		mr.DrawResources(canvas, mapData, mapHeight, mapWidth)
+	}
+	if mr.config.ShowStartPositions {
+		mr.DrawStartPositions(canvas, mapData, mapHeight, mapWidth)
+	}
 
 	canvas.InvertY()
 	// Draw city names on top of hexes
 	mr.DrawPoliticalCityNames(canvas, mapData, mapHeight, mapWidth)
+	if mr.config.ShowNaturalWonders {
+		mr.DrawNaturalWonderNames(canvas, mapData, mapHeight, mapWidth)
+	}
+	if !mr.config.Resources.IsEmpty() {
+		mr.DrawResourceAmounts(canvas, mapData, mapHeight, mapWidth)
+	}
+	if mr.config.ShowStartPositions {
+		mr.DrawStartPositionNames(canvas, mapData, mapHeight, mapWidth)
+	}
+	if mr.config.ShowImprovements {
+		mr.DrawImprovementLegend(canvas, mapData, mapHeight, mapWidth, maxImageWidth, maxImageHeight)
+	}
 
 	return canvas.Image()
 }
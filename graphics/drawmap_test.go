@@ -2,8 +2,10 @@ package graphics
 
 import (
 	"fmt"
+	"image"
 	"image/color"
 	"math"
+	"strings"
 	"testing"
 
 	"github.com/samuelyuan/Civ5MapImage/fileio"
@@ -493,3 +495,268 @@ func TestSaveImage(t *testing.T) {
 		t.Errorf("SaveImage() ops = %v, want [SavePNG(\"output.png\")]", ops)
 	}
 }
+
+func TestDrawStartPositionMarker(t *testing.T) {
+	mr := NewMapRenderer(DefaultDrawingConfig())
+	canvas := NewMockCanvas(100, 100)
+
+	mr.DrawStartPositionMarker(canvas, 10, 20, color.RGBA{1, 2, 3, 255}, color.RGBA{4, 5, 6, 255})
+
+	ops := canvas.GetOperations()
+	want := []string{
+		"DrawRegularPolygon(24, 10.00, 20.00, 9.60, 0.00)",
+		"SetColor(4, 5, 6)",
+		"Fill()",
+		"DrawRegularPolygon(24, 10.00, 20.00, 6.40, 0.00)",
+		"SetColor(1, 2, 3)",
+		"Fill()",
+	}
+	if fmt.Sprint(ops) != fmt.Sprint(want) {
+		t.Errorf("DrawStartPositionMarker() ops = %v, want %v", ops, want)
+	}
+}
+
+func TestDrawPhysicalMapStartPositionsOnlyWhenEnabled(t *testing.T) {
+	mapData := newFullMapDataForRender()
+	mapData.Civ5PlayerData = []*fileio.Civ5PlayerData{
+		{Index: 0, CivType: "CIVILIZATION_ROME", TeamColor: "PLAYERCOLOR_BLACK"},
+	}
+
+	countLabels := func(config *DrawingConfig) int {
+		canvas := NewMockCanvas(1, 1)
+		NewMapRenderer(config).DrawPhysicalMap(canvas, mapData)
+		count := 0
+		for _, op := range canvas.GetOperations() {
+			if strings.HasPrefix(op, `DrawString("Rome"`) {
+				count++
+			}
+		}
+		return count
+	}
+
+	if got := countLabels(DefaultDrawingConfig()); got != 0 {
+		t.Errorf("default config drew %d start position labels, want 0", got)
+	}
+	config := DefaultDrawingConfig()
+	config.ShowStartPositions = true
+	if got := countLabels(config); got != 1 {
+		t.Errorf("ShowStartPositions drew %d start position labels, want 1", got)
+	}
+}
+
+// newLayerTestMapData returns a height x width grassland map without features, natural wonders,
+// resources, improvements or owners, for the tests of the optional layers to fill in
+func newLayerTestMapData(height, width int) *fileio.Civ5MapData {
+	mapData := &fileio.Civ5MapData{
+		TerrainList:         []string{"TERRAIN_GRASS"},
+		FeatureTerrainList:  []string{},
+		FeatureWonderList:   []string{},
+		MapTiles:            make([][]*fileio.Civ5MapTilePhysical, height),
+		MapTileImprovements: make([][]*fileio.Civ5MapTileImprovement, height),
+	}
+	for i := 0; i < height; i++ {
+		mapData.MapTiles[i] = make([]*fileio.Civ5MapTilePhysical, width)
+		mapData.MapTileImprovements[i] = make([]*fileio.Civ5MapTileImprovement, width)
+		for j := 0; j < width; j++ {
+			mapData.MapTiles[i][j] = &fileio.Civ5MapTilePhysical{ResourceType: 0xFF, FeatureTerrainType: 0xFF, FeatureWonderType: 0xFF}
+			mapData.MapTileImprovements[i][j] = &fileio.Civ5MapTileImprovement{Owner: -1, CityId: -1, RouteType: 255, Improvement: 255}
+		}
+	}
+	return mapData
+}
+
+func TestLayerNames(t *testing.T) {
+	tests := []struct {
+		name func(string) string
+		in   string
+		want string
+	}{
+		{NaturalWonderName, "FEATURE_FUJI", "Mt. Fuji"},
+		{NaturalWonderName, "FEATURE_SOLOMONS_MINES", "King Solomon's Mines"},
+		{NaturalWonderName, "FEATURE_LAKE_TITICACA", "Lake Titicaca"},
+		{ImprovementName, "IMPROVEMENT_LUMBERMILL", "Lumber Mill"},
+		{ImprovementName, "IMPROVEMENT_GOODY_HUT", "Ancient Ruins"},
+		{ImprovementName, "IMPROVEMENT_STONE_WORKS", "Stone Works"},
+	}
+	for _, tt := range tests {
+		if got := tt.name(tt.in); got != tt.want {
+			t.Errorf("name of %q = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestLayerSymbolsAreDistinct checks that no two types of a layer share a symbol, including the
+// symbol given to types added by mods
+func TestLayerSymbolsAreDistinct(t *testing.T) {
+	wonderIcons := map[string]string{"mod wonder": fmt.Sprint(NaturalWonderMarkers(newWonderTestMapData(), 2, 3, 16)[1].Icon)}
+	for feature, wonder := range naturalWonders {
+		wonderIcons[feature] = fmt.Sprint(wonder.Icon)
+	}
+	resourceGlyphs := map[string]string{}
+	for resource, info := range resources {
+		resourceGlyphs[resource] = fmt.Sprint(info.Category, info.Color)
+	}
+	// Improvements must differ in shape and not only in color
+	shape := func(symbol ImprovementSymbol) string { return fmt.Sprint(symbol.Sides, symbol.Rotation, symbol.Style) }
+	improvementSymbols := map[string]string{"mod improvement": shape(ImprovementSymbolOf("IMPROVEMENT_STONE_WORKS"))}
+	for improvementType, info := range improvements {
+		improvementSymbols[improvementType] = shape(info.Symbol)
+	}
+
+	for layer, symbols := range map[string]map[string]string{
+		"natural wonders": wonderIcons,
+		"resources":       resourceGlyphs,
+		"improvements":    improvementSymbols,
+	} {
+		seen := map[string]string{}
+		for typeName, symbol := range symbols {
+			if other, found := seen[symbol]; found {
+				t.Errorf("%s: %s and %s have the same symbol %s", layer, typeName, other, symbol)
+			}
+			seen[symbol] = typeName
+		}
+	}
+
+	for _, feature := range fileio.DefaultFeatureWonderList {
+		if _, ok := naturalWonders[feature]; !ok {
+			t.Errorf("%s has no icon", feature)
+		}
+	}
+	if got := ResourceCategoryOf("RESOURCE_TEA"); got != ResourceOther {
+		t.Errorf("ResourceCategoryOf(RESOURCE_TEA) = %q, want %q", got, ResourceOther)
+	}
+}
+
+func TestDrawLayerSymbols(t *testing.T) {
+	tests := []struct {
+		name string
+		draw func(mr *MapRenderer, canvas Canvas)
+		want []string
+	}{
+		{
+			"forest",
+			func(mr *MapRenderer, canvas Canvas) { mr.DrawForest(canvas, 10, 20) },
+			[]string{
+				"SetColor(34, 78, 36)",
+				"DrawRegularPolygon(3, 4.40, 16.80, 4.80, 3.14)",
+				"Fill()",
+				"DrawRegularPolygon(3, 15.60, 16.80, 4.80, 3.14)",
+				"Fill()",
+				"DrawRegularPolygon(3, 10.00, 24.00, 4.80, 3.14)",
+				"Fill()",
+			},
+		},
+		{
+			"Mt. Fuji",
+			func(mr *MapRenderer, canvas Canvas) {
+				mr.DrawNaturalWonderIcon(canvas, 10, 20, naturalWonders["FEATURE_FUJI"].Icon)
+			},
+			[]string{
+				"DrawRegularPolygon(24, 10.00, 20.00, 9.60, 0.00)",
+				"SetColor(212, 175, 55)",
+				"Fill()",
+				"DrawRegularPolygon(24, 10.00, 20.00, 7.68, 0.00)",
+				"SetColor(40, 36, 60)",
+				"Fill()",
+				"DrawRegularPolygon(3, 10.00, 20.00, 5.44, 3.14)",
+				"SetColor(236, 240, 250)",
+				"Fill()",
+			},
+		},
+		{
+			"iron",
+			func(mr *MapRenderer, canvas Canvas) {
+				mr.DrawResourceGlyph(canvas, 10, 20, ResourceStrategic, resources["RESOURCE_IRON"].Color)
+			},
+			[]string{
+				"DrawRegularPolygon(4, 10.00, 20.00, 4.80, 0.00)",
+				"SetColor(250, 250, 240)",
+				"Fill()",
+				"DrawRegularPolygon(4, 10.00, 20.00, 3.20, 0.00)",
+				"SetColor(140, 144, 156)",
+				"Fill()",
+			},
+		},
+		{
+			"mine",
+			func(mr *MapRenderer, canvas Canvas) {
+				mr.DrawImprovementSymbol(canvas, 10, 20, improvements["IMPROVEMENT_MINE"].Symbol)
+			},
+			[]string{
+				"DrawRegularPolygon(16, 10.00, 20.00, 4.16, 0.00)",
+				"SetColor(44, 40, 36)",
+				"Fill()",
+				"DrawRegularPolygon(3, 10.00, 20.00, 2.88, 3.14)",
+				"SetColor(160, 160, 172)",
+				"Fill()",
+			},
+		},
+		{
+			"bunker",
+			func(mr *MapRenderer, canvas Canvas) {
+				mr.DrawImprovementSymbol(canvas, 10, 20, improvements["IMPROVEMENT_BUNKER"].Symbol)
+			},
+			[]string{
+				"DrawRegularPolygon(16, 10.00, 20.00, 4.16, 0.00)",
+				"SetColor(44, 40, 36)",
+				"Fill()",
+				"DrawRegularPolygon(4, 10.00, 20.00, 2.88, 0.00)",
+				"SetColor(120, 130, 96)",
+				"Fill()",
+				"DrawRegularPolygon(4, 10.00, 20.00, 1.60, 0.00)",
+				"SetColor(44, 40, 36)",
+				"Fill()",
+			},
+		},
+	}
+	for _, tt := range tests {
+		canvas := NewMockCanvas(100, 100)
+		tt.draw(NewMapRenderer(DefaultDrawingConfig()), canvas)
+		if ops := canvas.GetOperations(); fmt.Sprint(ops) != fmt.Sprint(tt.want) {
+			t.Errorf("%s ops = %v, want %v", tt.name, ops, tt.want)
+		}
+	}
+}
+
+// TestOptionalLayersOnlyWhenEnabled draws each optional layer's test map with the default config
+// and with the layer turned on, counting an op that only the layer draws
+func TestOptionalLayersOnlyWhenEnabled(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapData *fileio.Civ5MapData
+		enable  func(config *DrawingConfig)
+		op      string
+	}{
+		{"features", newFeatureTestMapData(1, 0xFF), func(config *DrawingConfig) { config.ShowFeatures = true }, "SetColor(34, 78, 36)"},
+		{"natural wonders", newWonderTestMapData(), func(config *DrawingConfig) { config.ShowNaturalWonders = true }, `DrawString("Mt. Fuji"`},
+		{"resources", newResourceTestMapData(), func(config *DrawingConfig) { config.Resources, _ = ParseResourceFilter("RESOURCE_IRON") }, `DrawString("6"`},
+		{"improvements", newImprovementTestMapData(), func(config *DrawingConfig) { config.ShowImprovements = true }, `DrawString("Farm"`},
+	}
+	for _, tt := range tests {
+		countOps := func(config *DrawingConfig, draw func(*MapRenderer, Canvas, *fileio.Civ5MapData) image.Image) int {
+			canvas := NewMockCanvas(1, 1)
+			draw(NewMapRenderer(config), canvas, tt.mapData)
+			count := 0
+			for _, op := range canvas.GetOperations() {
+				if strings.HasPrefix(op, tt.op) {
+					count++
+				}
+			}
+			return count
+		}
+		enabled := DefaultDrawingConfig()
+		tt.enable(enabled)
+
+		for mode, draw := range map[string]func(*MapRenderer, Canvas, *fileio.Civ5MapData) image.Image{
+			"physical":  (*MapRenderer).DrawPhysicalMap,
+			"political": (*MapRenderer).DrawPoliticalMap,
+		} {
+			if got := countOps(DefaultDrawingConfig(), draw); got != 0 {
+				t.Errorf("%s map without %s drew %d %s, want none", mode, tt.name, got, tt.op)
+			}
+			if got := countOps(enabled, draw); got != 1 {
+				t.Errorf("%s map with %s drew %d %s, want 1", mode, tt.name, got, tt.op)
+			}
+		}
+	}
+}
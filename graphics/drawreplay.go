@@ -6,7 +6,6 @@ import (
 	"image/color"
 	"image/gif"
 	"os"
-	"strings"
 
 	"github.com/samuelyuan/Civ5MapImage/fileio"
 	"github.com/samuelyuan/Civ5MapImage/graphics/quantize"
@@ -16,37 +15,11 @@ const (
 	GIF_DELAY = 100
 )
 
-// Replay event type ids, as encoded in Civ5ReplayEvent.TypeId
-const (
-	ReplayEventCityFounded     = 1
-	ReplayEventTilesClaimed    = 2
-	ReplayEventCityTransferred = 3
-	ReplayEventTilesRazed      = 4
-)
-
 // Helper function to setup civ player data from replay
 func setupCivPlayerData(mapData *fileio.Civ5MapData, replayData *fileio.Civ5ReplayData) {
 	if len(mapData.Civ5PlayerData) == 0 || !replayData.IsReplayFile {
 		fmt.Println("Rebuilding civ player data from replay file...")
-		mapData.Civ5PlayerData = make([]*fileio.Civ5PlayerData, 0)
-		for i := 0; i < len(replayData.AllCivs); i++ {
-			civName := replayData.AllCivs[i].Name
-
-			if strings.Contains(civName, "CIVILIZATION") || strings.Contains(civName, "MINOR_CIV") {
-				mapData.Civ5PlayerData = append(mapData.Civ5PlayerData, &fileio.Civ5PlayerData{
-					Index:     i,
-					CivType:   civName,
-					TeamColor: replayData.AllCivs[i].LongName,
-				})
-			} else {
-				civName = strings.ReplaceAll(civName, " ", "")
-				mapData.Civ5PlayerData = append(mapData.Civ5PlayerData, &fileio.Civ5PlayerData{
-					Index:     i,
-					CivType:   fmt.Sprintf("CIVILIZATION_%s", strings.ToUpper(civName)),
-					TeamColor: fmt.Sprintf("PLAYERCOLOR_%s", strings.ToUpper(civName)),
-				})
-			}
-		}
+		mapData.Civ5PlayerData = fileio.BuildPlayerDataFromReplayCivs(replayData.AllCivs)
 	} else {
 		// Swap player civilization to index 0
 		indexPlayerCivilization := -1
@@ -114,37 +87,6 @@ func ValidateReplayCompatibility(mapData *fileio.Civ5MapData, replayData *fileio
 	return nil
 }
 
-// applyReplayEvent applies a single replay event's effect to the map tile improvements,
-// mutating mapData in place. nextCityId is the city id to assign if this event founds a
-// new city; the (possibly incremented) next available city id is returned so the caller
-// can thread it into the next call.
-func applyReplayEvent(mapData *fileio.Civ5MapData, event fileio.Civ5ReplayEvent, nextCityId int) int {
-	switch event.TypeId {
-	case ReplayEventCityFounded:
-		// Set city id
-		for _, tile := range event.Tiles {
-			mapData.MapTileImprovements[tile.Y][tile.X].CityId = nextCityId
-			mapData.MapTileImprovements[tile.Y][tile.X].CityName = strings.TrimSuffix(event.Text, " is founded.")
-			nextCityId += 1
-		}
-	case ReplayEventTilesClaimed, ReplayEventCityTransferred:
-		// Change owner to new civ id
-		for _, tile := range event.Tiles {
-			mapData.MapTileImprovements[tile.Y][tile.X].Owner = event.CivId
-		}
-	case ReplayEventTilesRazed:
-		for _, tile := range event.Tiles {
-			// Remove city from map
-			mapData.MapTileImprovements[tile.Y][tile.X].Owner = -1
-			mapData.MapTileImprovements[tile.Y][tile.X].CityId = -1
-			mapData.MapTileImprovements[tile.Y][tile.X].CityName = ""
-			// Set razed city tile to road
-			mapData.MapTileImprovements[tile.Y][tile.X].RouteType = 2
-		}
-	}
-	return nextCityId
-}
-
 // resetCityOwnerIndexMap rebuilds mapData.CityOwnerIndexMap as an identity mapping over the
 // replay's civs (civ index i maps to itself). This differs from how map/save loading builds
 // the same field (buildCityOwnerMaps maps a raw file owner slot to a compact player array
@@ -186,9 +128,17 @@ func renderReplayFrame(renderer *MapRenderer, canvas Canvas, mapData *fileio.Civ
 }
 
 // DrawReplay renders the given map/replay pair into an animated GIF at outputFilename.
+// If mapData is nil, the map is built from the tiles stored in the replay itself.
 // It returns an error (rather than panicking) if the map and replay are incompatible, or if
 // the output file cannot be written.
 func DrawReplay(mapData *fileio.Civ5MapData, replayData *fileio.Civ5ReplayData, outputFilename string) error {
+	if mapData == nil && replayData != nil {
+		replayMapData, err := fileio.BuildMapDataFromReplay(replayData)
+		if err != nil {
+			return fmt.Errorf("no map was given and the replay map couldn't be used: %w", err)
+		}
+		mapData = replayMapData
+	}
 	if err := ValidateReplayCompatibility(mapData, replayData); err != nil {
 		return fmt.Errorf("replay is not compatible with map: %w", err)
 	}
@@ -216,7 +166,7 @@ func DrawReplay(mapData *fileio.Civ5MapData, replayData *fileio.Civ5ReplayData,
 
 		for i, event := range replayTurns[turn] {
 			fmt.Println("Replay event", i, ":", event)
-			maxCityId = applyReplayEvent(mapData, event, maxCityId)
+			maxCityId = fileio.ApplyReplayEvent(mapData, event, maxCityId)
 		}
 
 		fmt.Println("Drawing map for turn", turn)
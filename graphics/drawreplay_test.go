@@ -9,158 +9,6 @@ import (
 	"github.com/samuelyuan/Civ5MapImage/fileio"
 )
 
-// newTestReplayMapData builds a 1x2 map data grid for applyReplayEvent tests.
-func newTestReplayMapData() *fileio.Civ5MapData {
-	return &fileio.Civ5MapData{
-		MapTileImprovements: [][]*fileio.Civ5MapTileImprovement{
-			{
-				{X: 0, Y: 0, CityId: -1, Owner: -1},
-				{X: 1, Y: 0, CityId: -1, Owner: -1},
-			},
-		},
-	}
-}
-
-func TestApplyReplayEventCityFounded(t *testing.T) {
-	mapData := newTestReplayMapData()
-	event := fileio.Civ5ReplayEvent{
-		TypeId: ReplayEventCityFounded,
-		Text:   "Rome is founded.",
-		Tiles:  []fileio.Civ5ReplayEventTile{{X: 0, Y: 0}},
-	}
-
-	nextCityId := applyReplayEvent(mapData, event, 0)
-
-	tile := mapData.MapTileImprovements[0][0]
-	if tile.CityId != 0 {
-		t.Errorf("CityId = %d, want 0", tile.CityId)
-	}
-	if tile.CityName != "Rome" {
-		t.Errorf("CityName = %q, want Rome", tile.CityName)
-	}
-	if nextCityId != 1 {
-		t.Errorf("nextCityId = %d, want 1", nextCityId)
-	}
-}
-
-func TestApplyReplayEventCityFoundedMultipleTiles(t *testing.T) {
-	mapData := &fileio.Civ5MapData{
-		MapTileImprovements: [][]*fileio.Civ5MapTileImprovement{
-			{
-				{X: 0, Y: 0, CityId: -1},
-				{X: 1, Y: 0, CityId: -1},
-			},
-		},
-	}
-	event := fileio.Civ5ReplayEvent{
-		TypeId: ReplayEventCityFounded,
-		Text:   "Paris is founded.",
-		Tiles: []fileio.Civ5ReplayEventTile{
-			{X: 0, Y: 0},
-			{X: 1, Y: 0},
-		},
-	}
-
-	nextCityId := applyReplayEvent(mapData, event, 5)
-
-	// Each tile in the event gets its own incrementing city id.
-	if mapData.MapTileImprovements[0][0].CityId != 5 {
-		t.Errorf("tile 0 CityId = %d, want 5", mapData.MapTileImprovements[0][0].CityId)
-	}
-	if mapData.MapTileImprovements[0][1].CityId != 6 {
-		t.Errorf("tile 1 CityId = %d, want 6", mapData.MapTileImprovements[0][1].CityId)
-	}
-	if nextCityId != 7 {
-		t.Errorf("nextCityId = %d, want 7", nextCityId)
-	}
-}
-
-func TestApplyReplayEventTilesClaimed(t *testing.T) {
-	mapData := newTestReplayMapData()
-	event := fileio.Civ5ReplayEvent{
-		TypeId: ReplayEventTilesClaimed,
-		CivId:  3,
-		Tiles:  []fileio.Civ5ReplayEventTile{{X: 1, Y: 0}},
-	}
-
-	nextCityId := applyReplayEvent(mapData, event, 2)
-
-	if mapData.MapTileImprovements[0][1].Owner != 3 {
-		t.Errorf("Owner = %d, want 3", mapData.MapTileImprovements[0][1].Owner)
-	}
-	// Non-founding events must not advance the city id counter.
-	if nextCityId != 2 {
-		t.Errorf("nextCityId = %d, want unchanged 2", nextCityId)
-	}
-}
-
-func TestApplyReplayEventCityTransferred(t *testing.T) {
-	mapData := newTestReplayMapData()
-	event := fileio.Civ5ReplayEvent{
-		TypeId: ReplayEventCityTransferred,
-		CivId:  7,
-		Tiles:  []fileio.Civ5ReplayEventTile{{X: 0, Y: 0}},
-	}
-
-	applyReplayEvent(mapData, event, 0)
-
-	if mapData.MapTileImprovements[0][0].Owner != 7 {
-		t.Errorf("Owner = %d, want 7", mapData.MapTileImprovements[0][0].Owner)
-	}
-}
-
-func TestApplyReplayEventTilesRazed(t *testing.T) {
-	mapData := &fileio.Civ5MapData{
-		MapTileImprovements: [][]*fileio.Civ5MapTileImprovement{
-			{
-				{X: 0, Y: 0, CityId: 4, CityName: "Carthage", Owner: 2, RouteType: 0},
-			},
-		},
-	}
-	event := fileio.Civ5ReplayEvent{
-		TypeId: ReplayEventTilesRazed,
-		Tiles:  []fileio.Civ5ReplayEventTile{{X: 0, Y: 0}},
-	}
-
-	nextCityId := applyReplayEvent(mapData, event, 5)
-
-	tile := mapData.MapTileImprovements[0][0]
-	if tile.Owner != -1 {
-		t.Errorf("Owner = %d, want -1", tile.Owner)
-	}
-	if tile.CityId != -1 {
-		t.Errorf("CityId = %d, want -1", tile.CityId)
-	}
-	if tile.CityName != "" {
-		t.Errorf("CityName = %q, want empty", tile.CityName)
-	}
-	if tile.RouteType != 2 {
-		t.Errorf("RouteType = %d, want 2 (road)", tile.RouteType)
-	}
-	// Razing does not found a city, so the counter is unaffected.
-	if nextCityId != 5 {
-		t.Errorf("nextCityId = %d, want unchanged 5", nextCityId)
-	}
-}
-
-func TestApplyReplayEventUnknownTypeIsNoOp(t *testing.T) {
-	mapData := newTestReplayMapData()
-	event := fileio.Civ5ReplayEvent{
-		TypeId: 99,
-		Tiles:  []fileio.Civ5ReplayEventTile{{X: 0, Y: 0}},
-	}
-
-	nextCityId := applyReplayEvent(mapData, event, 3)
-
-	tile := mapData.MapTileImprovements[0][0]
-	if tile.CityId != -1 || tile.Owner != -1 {
-		t.Errorf("unknown event type mutated tile: %+v", tile)
-	}
-	if nextCityId != 3 {
-		t.Errorf("nextCityId = %d, want unchanged 3", nextCityId)
-	}
-}
-
 func TestSetupCivPlayerDataRebuildsFromReplay(t *testing.T) {
 	mapData := &fileio.Civ5MapData{
 		Civ5PlayerData: []*fileio.Civ5PlayerData{}, // empty triggers rebuild
@@ -253,7 +101,7 @@ func newValidReplayFixtures() (*fileio.Civ5MapData, *fileio.Civ5ReplayData) {
 		PlayerCiv:    "CIVILIZATION_ROME",
 		AllCivs:      []fileio.Civ5ReplayCiv{{Name: "CIVILIZATION_ROME"}},
 		AllReplayEvents: []fileio.Civ5ReplayEvent{
-			{Turn: 1, TypeId: ReplayEventTilesClaimed, CivId: 0, Tiles: []fileio.Civ5ReplayEventTile{{X: 0, Y: 0}}},
+			{Turn: 1, TypeId: fileio.ReplayEventTilesClaimed, CivId: 0, Tiles: []fileio.Civ5ReplayEventTile{{X: 0, Y: 0}}},
 		},
 		// Matches the 1x1 map above, as a real .civ5replay file's embedded dimensions would.
 		MapWidth:  1,
@@ -319,7 +167,7 @@ func TestValidateReplayCompatibilityTileOutOfBounds(t *testing.T) {
 	mapData, replayData := newValidReplayFixtures()
 	// The map is 1x1, so tile (5, 5) is out of bounds.
 	replayData.AllReplayEvents = []fileio.Civ5ReplayEvent{
-		{Turn: 3, TypeId: ReplayEventTilesClaimed, Tiles: []fileio.Civ5ReplayEventTile{{X: 5, Y: 5}}},
+		{Turn: 3, TypeId: fileio.ReplayEventTilesClaimed, Tiles: []fileio.Civ5ReplayEventTile{{X: 5, Y: 5}}},
 	}
 
 	err := ValidateReplayCompatibility(mapData, replayData)
@@ -364,7 +212,7 @@ func TestValidateReplayCompatibilityUnknownDimensionsFallsBackToTileScan(t *test
 	replayData.MapWidth = 0
 	replayData.MapHeight = 0
 	replayData.AllReplayEvents = []fileio.Civ5ReplayEvent{
-		{Turn: 3, TypeId: ReplayEventTilesClaimed, Tiles: []fileio.Civ5ReplayEventTile{{X: 5, Y: 5}}},
+		{Turn: 3, TypeId: fileio.ReplayEventTilesClaimed, Tiles: []fileio.Civ5ReplayEventTile{{X: 5, Y: 5}}},
 	}
 
 	err := ValidateReplayCompatibility(mapData, replayData)
@@ -420,3 +268,37 @@ func TestDrawReplayHandlesNilCityOwnerIndexMap(t *testing.T) {
 		t.Fatalf("DrawReplay() with nil CityOwnerIndexMap returned error: %v", err)
 	}
 }
+
+func TestDrawReplayUsesReplayTilesWithoutMap(t *testing.T) {
+	mapData, replayData := newValidReplayFixtures()
+	replayData.MapTiles = mapData.MapTiles
+
+	outputPath := filepath.Join(t.TempDir(), "replay.gif")
+	if err := DrawReplay(nil, replayData, outputPath); err != nil {
+		t.Fatalf("DrawReplay() without a map returned error: %v", err)
+	}
+	if _, err := os.Stat(outputPath); err != nil {
+		t.Fatalf("DrawReplay() did not write an output file: %v", err)
+	}
+}
+
+func TestDrawReplayWithoutMapOrReplayTiles(t *testing.T) {
+	_, replayData := newValidReplayFixtures()
+
+	err := DrawReplay(nil, replayData, filepath.Join(t.TempDir(), "replay.gif"))
+	if err == nil {
+		t.Fatal("DrawReplay() without any map = nil error, want an error")
+	}
+	if !strings.Contains(err.Error(), "no tile data") {
+		t.Errorf("DrawReplay() error = %q, want it to mention the missing tiles", err)
+	}
+}
+
+// TestReplayFramesLeaveOptionalLayersOff checks the default config that DrawReplay draws its
+// frames with, so that new map layers don't change replays
+func TestReplayFramesLeaveOptionalLayersOff(t *testing.T) {
+	config := DefaultDrawingConfig()
+	if config.ShowFeatures || config.ShadedRelief || config.ShowNaturalWonders || !config.Resources.IsEmpty() || config.ShowImprovements {
+		t.Errorf("DefaultDrawingConfig() = %+v, want every optional layer off", config)
+	}
+}
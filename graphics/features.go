@@ -0,0 +1,179 @@
+package graphics
+
+import (
+	"math"
+	"strings"
+
+	"github.com/samuelyuan/Civ5MapImage/fileio"
+)
+
+// Feature marker types, one per terrain feature. The patterns are kept inside the hex so that
+// the fill of the next tile doesn't cover them.
+const (
+	EntityForest      EntityType = "forest"
+	EntityJungle      EntityType = "jungle"
+	EntityMarsh       EntityType = "marsh"
+	EntityIce         EntityType = "ice"
+	EntityOasis       EntityType = "oasis"
+	EntityFloodPlains EntityType = "floodplains"
+	EntityFallout     EntityType = "fallout"
+	EntityAtoll       EntityType = "atoll"
+)
+
+// featureEntityTypes maps the feature types in FeatureTerrainList to their marker
+var featureEntityTypes = map[string]EntityType{
+	"FEATURE_FOREST":       EntityForest,
+	"FEATURE_JUNGLE":       EntityJungle,
+	"FEATURE_MARSH":        EntityMarsh,
+	"FEATURE_ICE":          EntityIce,
+	"FEATURE_OASIS":        EntityOasis,
+	"FEATURE_FLOOD_PLAINS": EntityFloodPlains,
+	"FEATURE_FALLOUT":      EntityFallout,
+	"FEATURE_ATOLL":        EntityAtoll,
+}
+
+// TileFeatureEntity returns the feature marker for tile (row, col), or false if the tile has no
+// feature with a pattern. Impassable features (e.g. FEATURE_IMPASSABLE_FOREST) use the same
+// pattern as the regular feature.
+func TileFeatureEntity(mapData *fileio.Civ5MapData, row, col int, radius float64) (Entity, bool) {
+	feature := strings.Replace(fileio.GetFeatureString(mapData, row, col), "IMPASSABLE_", "", 1)
+	entityType, ok := featureEntityTypes[feature]
+	if !ok {
+		return Entity{}, false
+	}
+	x, y := fileio.GetImagePosition(row, col, radius)
+	return Entity{Type: entityType, X: x, Y: y}, true
+}
+
+// drawFeature draws the pattern for a feature marker
+func (mr *MapRenderer) drawFeature(canvas Canvas, entity Entity) {
+	switch entity.Type {
+	case EntityForest:
+		mr.DrawForest(canvas, entity.X, entity.Y)
+	case EntityJungle:
+		mr.DrawJungle(canvas, entity.X, entity.Y)
+	case EntityMarsh:
+		mr.DrawMarsh(canvas, entity.X, entity.Y)
+	case EntityIce:
+		mr.DrawIce(canvas, entity.X, entity.Y)
+	case EntityOasis:
+		mr.DrawOasis(canvas, entity.X, entity.Y)
+	case EntityFloodPlains:
+		mr.DrawFloodPlains(canvas, entity.X, entity.Y)
+	case EntityFallout:
+		mr.DrawFallout(canvas, entity.X, entity.Y)
+	case EntityAtoll:
+		mr.DrawAtoll(canvas, entity.X, entity.Y)
+	}
+}
+
+// DrawForest draws three pine trees
+func (mr *MapRenderer) DrawForest(canvas Canvas, imageX, imageY float64) {
+	radius := mr.config.Radius
+	canvas.SetColor(34, 78, 36) // dark green
+	for _, offset := range [][2]float64{{-0.35, -0.2}, {0.35, -0.2}, {0, 0.25}} {
+		canvas.DrawRegularPolygon(3, imageX+offset[0]*radius, imageY+offset[1]*radius, radius*0.3, math.Pi)
+		canvas.Fill()
+	}
+}
+
+// DrawJungle draws a dense cluster of round canopies
+func (mr *MapRenderer) DrawJungle(canvas Canvas, imageX, imageY float64) {
+	radius := mr.config.Radius
+	canvas.SetColor(24, 102, 48) // bright dark green
+	canvas.DrawRegularPolygon(12, imageX, imageY, radius*0.2, 0)
+	canvas.Fill()
+	for i := 0; i < 5; i++ {
+		angle := math.Pi/2 + float64(i)*2*math.Pi/5
+		canvas.DrawRegularPolygon(12, imageX+0.45*radius*math.Cos(angle), imageY+0.45*radius*math.Sin(angle), radius*0.18, 0)
+		canvas.Fill()
+	}
+}
+
+// DrawMarsh draws short strokes of standing water with reeds
+func (mr *MapRenderer) DrawMarsh(canvas Canvas, imageX, imageY float64) {
+	radius := mr.config.Radius
+	canvas.SetLineWidth(1.5)
+	canvas.SetColor(62, 110, 118) // blue green
+	for _, offsetY := range []float64{-0.35, 0, 0.35} {
+		canvas.DrawLine(imageX-0.4*radius, imageY+offsetY*radius, imageX+0.4*radius, imageY+offsetY*radius)
+		canvas.Stroke()
+	}
+	canvas.SetColor(86, 96, 40) // olive
+	for _, offsetX := range []float64{-0.2, 0.2} {
+		canvas.DrawLine(imageX+offsetX*radius, imageY-0.15*radius, imageX+offsetX*radius, imageY+0.5*radius)
+		canvas.Stroke()
+	}
+	canvas.SetLineWidth(1.0)
+}
+
+// DrawIce draws a pale sheet of ice with cracks across it
+func (mr *MapRenderer) DrawIce(canvas Canvas, imageX, imageY float64) {
+	radius := mr.config.Radius
+	canvas.DrawRegularPolygon(6, imageX, imageY, radius*0.8, math.Pi/2)
+	canvas.SetColor(222, 236, 245) // icy white
+	canvas.Fill()
+
+	canvas.SetColor(160, 190, 210) // pale blue
+	canvas.DrawLine(imageX-0.5*radius, imageY+0.2*radius, imageX, imageY-0.1*radius)
+	canvas.Stroke()
+	canvas.DrawLine(imageX, imageY-0.1*radius, imageX+0.45*radius, imageY+0.3*radius)
+	canvas.Stroke()
+	canvas.DrawLine(imageX, imageY-0.1*radius, imageX+0.1*radius, imageY-0.5*radius)
+	canvas.Stroke()
+}
+
+// DrawOasis draws a pool of water between two palms
+func (mr *MapRenderer) DrawOasis(canvas Canvas, imageX, imageY float64) {
+	radius := mr.config.Radius
+	canvas.DrawRegularPolygon(16, imageX, imageY, radius*0.3, 0)
+	canvas.SetColor(64, 140, 196) // blue
+	canvas.Fill()
+
+	canvas.SetColor(46, 120, 44) // green
+	for _, offsetX := range []float64{-0.5, 0.5} {
+		canvas.DrawRegularPolygon(12, imageX+offsetX*radius, imageY+0.2*radius, radius*0.15, 0)
+		canvas.Fill()
+	}
+}
+
+// DrawFloodPlains draws two wavy bands of fertile silt along the river
+func (mr *MapRenderer) DrawFloodPlains(canvas Canvas, imageX, imageY float64) {
+	radius := mr.config.Radius
+	canvas.SetLineWidth(1.5)
+	canvas.SetColor(112, 150, 92) // silt green
+	for _, offsetY := range []float64{-0.25, 0.25} {
+		// Zigzag of four segments, alternating up and down
+		wave := 0.08 * radius
+		for k := 0; k < 4; k++ {
+			x1 := imageX + (-0.5+0.25*float64(k))*radius
+			y := imageY + offsetY*radius
+			canvas.DrawLine(x1, y+wave, x1+0.25*radius, y-wave)
+			canvas.Stroke()
+			wave = -wave
+		}
+	}
+	canvas.SetLineWidth(1.0)
+}
+
+// DrawFallout draws yellow hatching over the tile
+func (mr *MapRenderer) DrawFallout(canvas Canvas, imageX, imageY float64) {
+	radius := mr.config.Radius
+	canvas.SetColor(206, 196, 40) // yellow
+	for _, offset := range []float64{-0.3, 0, 0.3} {
+		canvas.DrawLine(imageX+(offset-0.25)*radius, imageY-0.4*radius, imageX+(offset+0.25)*radius, imageY+0.4*radius)
+		canvas.Stroke()
+	}
+}
+
+// DrawAtoll draws a ring of sand around a lagoon
+func (mr *MapRenderer) DrawAtoll(canvas Canvas, imageX, imageY float64) {
+	radius := mr.config.Radius
+	canvas.DrawRegularPolygon(16, imageX, imageY, radius*0.45, 0)
+	canvas.SetColor(214, 204, 160) // sand
+	canvas.Fill()
+
+	canvas.DrawRegularPolygon(16, imageX, imageY, radius*0.3, 0)
+	canvas.SetColor(95, 149, 149) // coast
+	canvas.Fill()
+}
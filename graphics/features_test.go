@@ -0,0 +1,66 @@
+package graphics
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/samuelyuan/Civ5MapImage/fileio"
+)
+
+func newFeatureTestMapData(features ...int) *fileio.Civ5MapData {
+	mapData := newLayerTestMapData(1, len(features))
+	mapData.FeatureTerrainList = []string{"FEATURE_ICE", "FEATURE_FOREST", "FEATURE_IMPASSABLE_JUNGLE", "FEATURE_MOD_ONLY"}
+	for i, feature := range features {
+		mapData.MapTiles[0][i].FeatureTerrainType = feature
+	}
+	return mapData
+}
+
+func TestTileFeatureEntity(t *testing.T) {
+	mapData := newFeatureTestMapData(1, 2, 3, 0xFF)
+
+	entity, ok := TileFeatureEntity(mapData, 0, 0, 16)
+	wantX, wantY := fileio.GetImagePosition(0, 0, 16)
+	if !ok || entity != (Entity{Type: EntityForest, X: wantX, Y: wantY}) {
+		t.Errorf("TileFeatureEntity(forest) = %+v, %v", entity, ok)
+	}
+	if entity, ok := TileFeatureEntity(mapData, 0, 1, 16); !ok || entity.Type != EntityJungle {
+		t.Errorf("TileFeatureEntity(impassable jungle) = %+v, %v, want a jungle", entity, ok)
+	}
+	// Unknown features and tiles without one have no marker
+	for col := 2; col < 4; col++ {
+		if entity, ok := TileFeatureEntity(mapData, 0, col, 16); ok {
+			t.Errorf("TileFeatureEntity(0, %d) = %+v, want none", col, entity)
+		}
+	}
+}
+
+func TestDrawFeaturePatternsStayInsideHex(t *testing.T) {
+	mr := NewMapRenderer(DefaultDrawingConfig())
+	// Distance from the center to the middle of a hex edge
+	inradius := 16 * math.Sqrt(3) / 2
+	for _, entityType := range featureEntityTypes {
+		canvas := NewMockCanvas(100, 100)
+		mr.drawFeature(canvas, Entity{Type: entityType, X: 50, Y: 50})
+
+		ops := canvas.GetOperations()
+		if len(ops) == 0 {
+			t.Errorf("%s pattern drew nothing", entityType)
+		}
+		for _, op := range ops {
+			var sides int
+			var x, y, radius, rotation, x2, y2 float64
+			if n, _ := fmt.Sscanf(op, "DrawRegularPolygon(%d, %f, %f, %f, %f)", &sides, &x, &y, &radius, &rotation); n == 5 {
+				if math.Hypot(x-50, y-50)+radius > inradius {
+					t.Errorf("%s pattern op %q reaches outside the hex", entityType, op)
+				}
+			}
+			if n, _ := fmt.Sscanf(op, "DrawLine(%f, %f, %f, %f)", &x, &y, &x2, &y2); n == 4 {
+				if math.Hypot(x-50, y-50) > inradius || math.Hypot(x2-50, y2-50) > inradius {
+					t.Errorf("%s pattern op %q reaches outside the hex", entityType, op)
+				}
+			}
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package graphics
 
 import (
+	"fmt"
 	"image/color"
 	"math"
 	"strings"
@@ -99,6 +100,7 @@ type EntityType string
 
 const (
 	EntityMountain EntityType = "mountain"
+	EntityHills    EntityType = "hills"
 	EntityCity     EntityType = "city"
 )
 
@@ -109,7 +111,7 @@ type Entity struct {
 	R, G, B uint8
 }
 
-// TileEntities returns the mountain/city markers for tile (row, col), or nil if it has neither.
+// TileEntities returns the mountain/hills/city markers for tile (row, col), or nil if it has none.
 // cityColor is used for a city marker, if present.
 func TileEntities(mapData *fileio.Civ5MapData, row, col int, radius float64, cityColor color.RGBA) []Entity {
 	x, y := fileio.GetImagePosition(row, col, radius)
@@ -118,6 +120,9 @@ func TileEntities(mapData *fileio.Civ5MapData, row, col int, radius float64, cit
 	if fileio.TileHasMountain(mapData, row, col) {
 		entities = append(entities, Entity{Type: EntityMountain, X: x, Y: y})
 	}
+	if fileio.TileHasHills(mapData, row, col) {
+		entities = append(entities, Entity{Type: EntityHills, X: x, Y: y})
+	}
 	if fileio.TileHasCity(mapData, row, col) {
 		entities = append(entities, Entity{Type: EntityCity, X: x, Y: y, R: cityColor.R, G: cityColor.G, B: cityColor.B})
 	}
@@ -302,3 +307,77 @@ func PoliticalCityNameLabel(mapData *fileio.Civ5MapData, mapHeight, mapWidth, ro
 	textColor := blendColor(cityColor, color.RGBA{255, 255, 255, 255}, 0.2)
 	return ColoredText{Text: cityName, X: x, Y: y, R: textColor.R, G: textColor.G, B: textColor.B}
 }
+
+// StartPositionMarker is a player's start position marker: a circle filled with the civ's primary
+// color and ringed with its secondary color, plus a label with the civ name below it.
+type StartPositionMarker struct {
+	X, Y  float64
+	Fill  color.RGBA
+	Ring  color.RGBA
+	Label ColoredText
+}
+
+// civDisplayName returns the name to label a player with: the civ name override if the map sets
+// one, otherwise the civ type without its prefix (e.g. "CIVILIZATION_ROME" becomes "Rome").
+func civDisplayName(player *fileio.Civ5PlayerData) string {
+	if player.CivName != "" {
+		return player.CivName
+	}
+	name := player.CivType
+	for _, prefix := range []string{"CIVILIZATION_", "MINOR_CIV_"} {
+		name = strings.TrimPrefix(name, prefix)
+	}
+	if name == "" {
+		return fmt.Sprintf("Player %d", player.Index+1)
+	}
+	words := strings.Split(strings.ToLower(name), "_")
+	for i, word := range words {
+		if word != "" {
+			words[i] = strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// StartPositionMarkers returns a marker for every player whose start position lies on the map.
+// Players without a start position, which is (-1, -1), are skipped.
+// City states swap their primary and secondary colors, matching the territory convention.
+// Players with an unrecognized team color get a white marker ringed in black.
+func StartPositionMarkers(mapData *fileio.Civ5MapData, mapHeight, mapWidth int, radius float64) []StartPositionMarker {
+	var markers []StartPositionMarker
+	for _, player := range mapData.Civ5PlayerData {
+		row, col := player.StartPositionY, player.StartPositionX
+		if row < 0 || col < 0 || row >= mapHeight || col >= mapWidth {
+			continue
+		}
+
+		fill := color.RGBA{255, 255, 255, 255}
+		ring := color.RGBA{0, 0, 0, 255}
+		if renderColor, ok := civColorMap[player.TeamColor]; ok {
+			fill, ring = renderColor.OuterColor, renderColor.InnerColor
+			if strings.Contains(player.CivType, "MINOR") {
+				fill, ring = ring, fill
+			}
+		}
+
+		name := civDisplayName(player)
+		x, y := fileio.GetImagePosition(row, col, radius)
+		labelX, labelY := fileio.GetImagePosition(InvertedRow(mapHeight, row), col, radius)
+		textColor := blendColor(ring, color.RGBA{255, 255, 255, 255}, 0.2)
+		markers = append(markers, StartPositionMarker{
+			X:    x,
+			Y:    y,
+			Fill: fill,
+			Ring: ring,
+			Label: ColoredText{
+				Text: name,
+				X:    labelX - (6.0 * float64(len(name)) / 2.0),
+				Y:    labelY,
+				R:    textColor.R,
+				G:    textColor.G,
+				B:    textColor.B,
+			},
+		})
+	}
+	return markers
+}
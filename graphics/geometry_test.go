@@ -2,6 +2,7 @@ package graphics
 
 import (
 	"image/color"
+	"path/filepath"
 	"reflect"
 	"testing"
 
@@ -348,6 +349,17 @@ func TestTileEntitiesMountain(t *testing.T) {
 	}
 }
 
+func TestTileEntitiesHills(t *testing.T) {
+	mapData := &fileio.Civ5MapData{
+		MapTiles:            [][]*fileio.Civ5MapTilePhysical{{{Elevation: 1}}},
+		MapTileImprovements: [][]*fileio.Civ5MapTileImprovement{{{CityId: -1}}},
+	}
+	entities := TileEntities(mapData, 0, 0, 16.0, color.RGBA{255, 255, 255, 255})
+	if len(entities) != 1 || entities[0].Type != EntityHills {
+		t.Fatalf("TileEntities() = %+v, want a single EntityHills", entities)
+	}
+}
+
 func TestTileEntitiesCity(t *testing.T) {
 	mapData := &fileio.Civ5MapData{
 		MapTiles:            [][]*fileio.Civ5MapTilePhysical{{{Elevation: 0}}},
@@ -393,3 +405,73 @@ func TestTileEntitiesNoImprovementDataIsSafe(t *testing.T) {
 		t.Errorf("TileEntities() with no improvement data = %v, want nil", entities)
 	}
 }
+
+func newStartPositionTestMap() *fileio.Civ5MapData {
+	return &fileio.Civ5MapData{
+		Civ5PlayerData: []*fileio.Civ5PlayerData{
+			{Index: 0, CivType: "CIVILIZATION_ROME", TeamColor: "PLAYERCOLOR_BLACK", StartPositionX: 1, StartPositionY: 0},
+			{Index: 1, CivType: "MINOR_CIV_MONACO", TeamColor: "PLAYERCOLOR_BLACK", StartPositionX: 0, StartPositionY: 1},
+			{Index: 2, CivType: "CIVILIZATION_GREECE", TeamColor: "PLAYERCOLOR_BLACK", StartPositionX: 5, StartPositionY: 0},
+			{Index: 3, CivName: "Free Cities", TeamColor: "", StartPositionX: 0, StartPositionY: 0},
+		},
+	}
+}
+
+func TestStartPositionMarkers(t *testing.T) {
+	const radius = 16.0
+	markers := StartPositionMarkers(newStartPositionTestMap(), 2, 2, radius)
+
+	// Greece starts off the map and is skipped.
+	if len(markers) != 3 {
+		t.Fatalf("StartPositionMarkers() returned %d markers, want 3", len(markers))
+	}
+
+	renderColor := civColorMap["PLAYERCOLOR_BLACK"]
+	wantX, wantY := fileio.GetImagePosition(0, 1, radius)
+	if markers[0].X != wantX || markers[0].Y != wantY {
+		t.Errorf("Rome marker at (%.2f, %.2f), want (%.2f, %.2f)", markers[0].X, markers[0].Y, wantX, wantY)
+	}
+	if markers[0].Fill != renderColor.OuterColor || markers[0].Ring != renderColor.InnerColor {
+		t.Errorf("Rome marker colors = %v/%v, want outer/inner civ colors", markers[0].Fill, markers[0].Ring)
+	}
+	if markers[1].Fill != renderColor.InnerColor || markers[1].Ring != renderColor.OuterColor {
+		t.Errorf("Monaco marker colors = %v/%v, want swapped city state colors", markers[1].Fill, markers[1].Ring)
+	}
+	if markers[2].Fill != (color.RGBA{255, 255, 255, 255}) || markers[2].Ring != (color.RGBA{0, 0, 0, 255}) {
+		t.Errorf("unknown color marker = %v/%v, want white ringed in black", markers[2].Fill, markers[2].Ring)
+	}
+
+	labels := []string{markers[0].Label.Text, markers[1].Label.Text, markers[2].Label.Text}
+	if labels[0] != "Rome" || labels[1] != "Monaco" || labels[2] != "Free Cities" {
+		t.Errorf("StartPositionMarkers() labels = %v, want [Rome Monaco Free Cities]", labels)
+	}
+}
+
+// TestStartPositionMarkersSkipLegacyPlayers checks that a committed map exported before start
+// positions were kept doesn't stack every civ on tile (0, 0)
+func TestStartPositionMarkersSkipLegacyPlayers(t *testing.T) {
+	mapData, err := fileio.ImportCiv5MapFileFromJson(filepath.Join("..", "maps", "europe1939.json"))
+	if err != nil {
+		t.Fatalf("ImportCiv5MapFileFromJson returned error: %v", err)
+	}
+	header := mapData.MapHeader
+	if markers := StartPositionMarkers(mapData, int(header.Height), int(header.Width), 16); len(markers) != 0 {
+		t.Errorf("StartPositionMarkers() returned %d markers for players without start positions, want 0", len(markers))
+	}
+}
+
+func TestCivDisplayName(t *testing.T) {
+	tests := []struct {
+		player *fileio.Civ5PlayerData
+		want   string
+	}{
+		{&fileio.Civ5PlayerData{CivType: "CIVILIZATION_AUSTRIA_HUNGARY"}, "Austria Hungary"},
+		{&fileio.Civ5PlayerData{CivType: "CIVILIZATION_ROME", CivName: "Roman Republic"}, "Roman Republic"},
+		{&fileio.Civ5PlayerData{Index: 4}, "Player 5"},
+	}
+	for _, tt := range tests {
+		if got := civDisplayName(tt.player); got != tt.want {
+			t.Errorf("civDisplayName(%+v) = %q, want %q", tt.player, got, tt.want)
+		}
+	}
+}
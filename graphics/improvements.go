@@ -0,0 +1,295 @@
+package graphics
+
+import (
+	"hash/fnv"
+	"image/color"
+	"math"
+
+	"github.com/samuelyuan/Civ5MapImage/fileio"
+)
+
+// SymbolStyle is how the shape of an improvement symbol is filled
+type SymbolStyle int
+
+const (
+	SymbolFilled SymbolStyle = iota
+	SymbolHollow             // Only the outline of the shape
+	SymbolDotted             // Filled with a dark dot in the middle
+)
+
+// ImprovementSymbol is the small regular polygon drawn on a tile with an improvement
+type ImprovementSymbol struct {
+	Sides    int
+	Rotation float64
+	Style    SymbolStyle
+	Color    color.RGBA
+}
+
+// improvement is the display name and symbol of a tile improvement
+type improvement struct {
+	Name   string
+	Symbol ImprovementSymbol
+}
+
+// improvements holds the tile improvements of the base game and its expansions. Every one has
+// its own shape and style, so they can be told apart without the colors: triangles point up,
+// down, left or right, squares are upright or diamonds, pentagons and hexagons have two
+// orientations, and there are octagons and circles.
+var improvements = map[string]improvement{
+	"IMPROVEMENT_FISHING_BOATS":      {"Fishing Boats", ImprovementSymbol{3, 0, SymbolFilled, color.RGBA{90, 160, 236, 255}}},
+	"IMPROVEMENT_MINE":               {"Mine", ImprovementSymbol{3, math.Pi, SymbolFilled, color.RGBA{160, 160, 172, 255}}},
+	"IMPROVEMENT_CAMP":               {"Camp", ImprovementSymbol{3, math.Pi / 2, SymbolFilled, color.RGBA{206, 156, 96, 255}}},
+	"IMPROVEMENT_BRAZILWOOD_CAMP":    {"Brazilwood Camp", ImprovementSymbol{3, -math.Pi / 2, SymbolFilled, color.RGBA{176, 64, 64, 255}}},
+	"IMPROVEMENT_FARM":               {"Farm", ImprovementSymbol{4, 0, SymbolFilled, color.RGBA{232, 200, 60, 255}}},
+	"IMPROVEMENT_QUARRY":             {"Quarry", ImprovementSymbol{4, math.Pi / 4, SymbolFilled, color.RGBA{212, 202, 182, 255}}},
+	"IMPROVEMENT_FORT":               {"Fort", ImprovementSymbol{5, 0, SymbolFilled, color.RGBA{204, 64, 52, 255}}},
+	"IMPROVEMENT_PLANTATION":         {"Plantation", ImprovementSymbol{5, math.Pi, SymbolFilled, color.RGBA{70, 170, 70, 255}}},
+	"IMPROVEMENT_LUMBERMILL":         {"Lumber Mill", ImprovementSymbol{6, 0, SymbolFilled, color.RGBA{160, 104, 52, 255}}},
+	"IMPROVEMENT_WELL":               {"Oil Well", ImprovementSymbol{6, math.Pi / 2, SymbolFilled, color.RGBA{196, 120, 220, 255}}},
+	"IMPROVEMENT_CITADEL":            {"Citadel", ImprovementSymbol{8, 0, SymbolFilled, color.RGBA{228, 44, 44, 255}}},
+	"IMPROVEMENT_PASTURE":            {"Pasture", ImprovementSymbol{16, 0, SymbolFilled, color.RGBA{150, 220, 100, 255}}},
+	"IMPROVEMENT_CITY_RUINS":         {"City Ruins", ImprovementSymbol{3, 0, SymbolHollow, color.RGBA{130, 130, 130, 255}}},
+	"IMPROVEMENT_BARBARIAN_CAMP":     {"Barbarian Encampment", ImprovementSymbol{3, math.Pi, SymbolHollow, color.RGBA{230, 40, 30, 255}}},
+	"IMPROVEMENT_MOAI":               {"Moai", ImprovementSymbol{3, math.Pi / 2, SymbolHollow, color.RGBA{176, 170, 130, 255}}},
+	"IMPROVEMENT_BUNKER":             {"Bunker", ImprovementSymbol{4, 0, SymbolHollow, color.RGBA{120, 130, 96, 255}}},
+	"IMPROVEMENT_ACADEMY":            {"Academy", ImprovementSymbol{4, math.Pi / 4, SymbolHollow, color.RGBA{100, 130, 236, 255}}},
+	"IMPROVEMENT_LANDMARK":           {"Landmark", ImprovementSymbol{5, 0, SymbolHollow, color.RGBA{244, 224, 124, 255}}},
+	"IMPROVEMENT_OFFSHORE_PLATFORM":  {"Offshore Platform", ImprovementSymbol{6, 0, SymbolHollow, color.RGBA{70, 204, 224, 255}}},
+	"IMPROVEMENT_POLDER":             {"Polder", ImprovementSymbol{6, math.Pi / 2, SymbolHollow, color.RGBA{124, 204, 124, 255}}},
+	"IMPROVEMENT_HOLY_SITE":          {"Holy Site", ImprovementSymbol{8, 0, SymbolHollow, color.RGBA{250, 240, 200, 255}}},
+	"IMPROVEMENT_TRADING_POST":       {"Trading Post", ImprovementSymbol{16, 0, SymbolHollow, color.RGBA{240, 180, 40, 255}}},
+	"IMPROVEMENT_ARCHAEOLOGICAL_DIG": {"Archaeological Dig", ImprovementSymbol{3, 0, SymbolDotted, color.RGBA{176, 124, 84, 255}}},
+	"IMPROVEMENT_TERRACE_FARM":       {"Terrace Farm", ImprovementSymbol{4, 0, SymbolDotted, color.RGBA{184, 214, 84, 255}}},
+	"IMPROVEMENT_CUSTOMS_HOUSE":      {"Customs House", ImprovementSymbol{4, math.Pi / 4, SymbolDotted, color.RGBA{244, 196, 44, 255}}},
+	"IMPROVEMENT_CHATEAU":            {"Chateau", ImprovementSymbol{5, 0, SymbolDotted, color.RGBA{204, 164, 236, 255}}},
+	"IMPROVEMENT_FEITORIA":           {"Feitoria", ImprovementSymbol{5, math.Pi, SymbolDotted, color.RGBA{64, 184, 164, 255}}},
+	"IMPROVEMENT_MANUFACTORY":        {"Manufactory", ImprovementSymbol{6, 0, SymbolDotted, color.RGBA{232, 124, 44, 255}}},
+	"IMPROVEMENT_KASBAH":             {"Kasbah", ImprovementSymbol{8, 0, SymbolDotted, color.RGBA{222, 172, 112, 255}}},
+	"IMPROVEMENT_GOODY_HUT":          {"Ancient Ruins", ImprovementSymbol{16, 0, SymbolDotted, color.RGBA{250, 250, 250, 255}}},
+}
+
+// modImprovementShapes are the shapes, orientations and styles that no improvement in the table
+// above uses, kept for improvements added by mods so they can't be mistaken for one of the game's
+var modImprovementShapes = unusedImprovementShapes()
+
+// modImprovementColors are the colors of the symbols of improvements added by mods
+var modImprovementColors = []color.RGBA{
+	{255, 255, 255, 255}, // white
+	{255, 110, 180, 255}, // pink
+	{120, 230, 255, 255}, // sky blue
+	{200, 255, 90, 255},  // lime
+	{255, 150, 60, 255},  // orange
+	{170, 140, 255, 255}, // lavender
+	{255, 235, 120, 255}, // pale yellow
+	{60, 220, 170, 255},  // mint
+}
+
+// unusedImprovementShapes returns every symbol shape the table could give an improvement that it
+// doesn't, in a fixed order, with the color left unset
+func unusedImprovementShapes() []ImprovementSymbol {
+	rotations := []struct {
+		sides     int
+		rotations []float64
+	}{
+		{3, []float64{0, math.Pi, math.Pi / 2, -math.Pi / 2}},
+		{4, []float64{0, math.Pi / 4}},
+		{5, []float64{0, math.Pi}},
+		{6, []float64{0, math.Pi / 2}},
+		{8, []float64{0}},
+		{16, []float64{0}},
+	}
+	used := map[ImprovementSymbol]bool{}
+	for _, info := range improvements {
+		shape := info.Symbol
+		shape.Color = color.RGBA{}
+		used[shape] = true
+	}
+
+	var shapes []ImprovementSymbol
+	for _, style := range []SymbolStyle{SymbolFilled, SymbolHollow, SymbolDotted} {
+		for _, shape := range rotations {
+			for _, rotation := range shape.rotations {
+				symbol := ImprovementSymbol{Sides: shape.sides, Rotation: rotation, Style: style}
+				if !used[symbol] {
+					shapes = append(shapes, symbol)
+				}
+			}
+		}
+	}
+	return shapes
+}
+
+// improvementSymbolOffset places the symbol to the right of the tile center, clear of the
+// resource glyph in the upper left and the hills below
+const (
+	improvementSymbolOffsetX = 0.45
+	improvementSymbolOffsetY = -0.1
+)
+
+// ImprovementName returns the name to show for an improvement in the legend, e.g. "Lumber Mill"
+// for IMPROVEMENT_LUMBERMILL. Improvements added by mods are named after their type.
+func ImprovementName(improvementType string) string {
+	if info, ok := improvements[improvementType]; ok {
+		return info.Name
+	}
+	return titleCaseType(improvementType, "IMPROVEMENT_")
+}
+
+// ImprovementSymbolOf returns the symbol of an improvement. Improvements added by mods get one of
+// the shapes the game's improvements don't use, in one of a few bright colors, both picked by a
+// hash of the type so each mod improvement keeps the same symbol from map to map.
+func ImprovementSymbolOf(improvementType string) ImprovementSymbol {
+	if info, ok := improvements[improvementType]; ok {
+		return info.Symbol
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(improvementType))
+	sum := hash.Sum32()
+	symbol := modImprovementShapes[int(sum&0xFFFF)%len(modImprovementShapes)]
+	symbol.Color = modImprovementColors[int(sum>>16)%len(modImprovementColors)]
+	return symbol
+}
+
+// ImprovementMarker is an improvement's symbol on a tile
+type ImprovementMarker struct {
+	Improvement string
+	X, Y        float64
+	Symbol      ImprovementSymbol
+}
+
+// ImprovementMarkers returns a marker for every improved tile on the map
+func ImprovementMarkers(mapData *fileio.Civ5MapData, mapHeight, mapWidth int, radius float64) []ImprovementMarker {
+	var markers []ImprovementMarker
+	for i := 0; i < mapHeight; i++ {
+		for j := 0; j < mapWidth; j++ {
+			improvementType := fileio.GetImprovementString(mapData, i, j)
+			if improvementType == "" {
+				continue
+			}
+			x, y := fileio.GetImagePosition(i, j, radius)
+			markers = append(markers, ImprovementMarker{
+				Improvement: improvementType,
+				X:           x + improvementSymbolOffsetX*radius,
+				Y:           y + improvementSymbolOffsetY*radius,
+				Symbol:      ImprovementSymbolOf(improvementType),
+			})
+		}
+	}
+	return markers
+}
+
+// LegendEntry is one line of the improvement legend
+type LegendEntry struct {
+	Name   string
+	Symbol ImprovementSymbol
+}
+
+// ImprovementLegend returns a legend entry for each improvement on the map, in the order of the
+// map's improvement list
+func ImprovementLegend(mapData *fileio.Civ5MapData, mapHeight, mapWidth int) []LegendEntry {
+	found := make(map[string]bool)
+	for _, marker := range ImprovementMarkers(mapData, mapHeight, mapWidth, 1) {
+		found[marker.Improvement] = true
+	}
+
+	var entries []LegendEntry
+	for _, improvementType := range mapData.TileImprovementList {
+		if found[improvementType] {
+			entries = append(entries, LegendEntry{Name: ImprovementName(improvementType), Symbol: ImprovementSymbolOf(improvementType)})
+			// Mod lists can repeat a type, so only list it once
+			delete(found, improvementType)
+		}
+	}
+	return entries
+}
+
+// DrawImprovementSymbol draws an improvement's symbol on a dark disc
+func (mr *MapRenderer) DrawImprovementSymbol(canvas Canvas, imageX, imageY float64, symbol ImprovementSymbol) {
+	canvas.DrawRegularPolygon(16, imageX, imageY, mr.config.Radius*0.26, 0)
+	canvas.SetColor(44, 40, 36) // dark brown
+	canvas.Fill()
+
+	canvas.DrawRegularPolygon(symbol.Sides, imageX, imageY, mr.config.Radius*0.18, symbol.Rotation)
+	canvas.SetColor(symbol.Color.R, symbol.Color.G, symbol.Color.B)
+	canvas.Fill()
+
+	// Cut the inside of the shape or a dot out of it in the disc color
+	switch symbol.Style {
+	case SymbolHollow:
+		canvas.DrawRegularPolygon(symbol.Sides, imageX, imageY, mr.config.Radius*0.1, symbol.Rotation)
+	case SymbolDotted:
+		canvas.DrawRegularPolygon(16, imageX, imageY, mr.config.Radius*0.07, 0)
+	default:
+		return
+	}
+	canvas.SetColor(44, 40, 36)
+	canvas.Fill()
+}
+
+// DrawImprovements draws the symbol of every improvement on the map
+func (mr *MapRenderer) DrawImprovements(canvas Canvas, mapData *fileio.Civ5MapData, mapHeight, mapWidth int) {
+	for _, marker := range ImprovementMarkers(mapData, mapHeight, mapWidth, mr.config.Radius) {
+		mr.DrawImprovementSymbol(canvas, marker.X, marker.Y, marker.Symbol)
+	}
+}
+
+// Layout of the improvement legend, which has a panel of its own to the right of the map
+const (
+	legendPadding    = 6.0
+	legendLineHeight = 16.0
+)
+
+// improvementLegendLayout returns the column width and the number of entries per column of a
+// legend that fits in imageHeight, and the width of the panel that holds all of its columns.
+// Names are measured in the canvas font so the widest one fits its column.
+func improvementLegendLayout(canvas Canvas, entries []LegendEntry, imageHeight float64) (columnWidth float64, perColumn int, panelWidth float64) {
+	if len(entries) == 0 {
+		return 0, 0, 0
+	}
+	widestName := 0.0
+	for _, entry := range entries {
+		width, _ := canvas.MeasureString(entry.Name)
+		widestName = max(widestName, width)
+	}
+	columnWidth = legendLineHeight + 2 + math.Ceil(widestName) + legendPadding
+	perColumn = max(1, int((imageHeight-2*legendPadding)/legendLineHeight))
+	columns := (len(entries) + perColumn - 1) / perColumn
+	return columnWidth, perColumn, legendPadding + columnWidth*float64(columns)
+}
+
+// ImprovementLegendWidth returns the width to add to the right of the map for the improvement
+// legend, which is 0 when improvements aren't shown or the map has none
+func (mr *MapRenderer) ImprovementLegendWidth(canvas Canvas, mapData *fileio.Civ5MapData, mapHeight, mapWidth int, imageHeight float64) float64 {
+	if !mr.config.ShowImprovements {
+		return 0
+	}
+	_, _, panelWidth := improvementLegendLayout(canvas, ImprovementLegend(mapData, mapHeight, mapWidth), imageHeight)
+	return panelWidth
+}
+
+// DrawImprovementLegend draws the symbol and name of each improvement on the map in the panel
+// that starts at x = left, in columns as tall as the image. It draws after the image is flipped
+// back for text, so y points down.
+func (mr *MapRenderer) DrawImprovementLegend(canvas Canvas, mapData *fileio.Civ5MapData, mapHeight, mapWidth int, left, imageHeight float64) {
+	entries := ImprovementLegend(mapData, mapHeight, mapWidth)
+	columnWidth, perColumn, panelWidth := improvementLegendLayout(canvas, entries, imageHeight)
+	if len(entries) == 0 {
+		return
+	}
+
+	canvas.DrawRectangle(left, 0, panelWidth, imageHeight)
+	canvas.SetColor(24, 24, 28) // near black
+	canvas.Fill()
+
+	for i, entry := range entries {
+		x := left + legendPadding + columnWidth*float64(i/perColumn)
+		top := legendPadding + legendLineHeight*float64(i%perColumn)
+		// Flipping y mirrors each polygon, so mirror the rotation back to match the map
+		symbol := entry.Symbol
+		symbol.Rotation = math.Pi - symbol.Rotation
+		mr.DrawImprovementSymbol(canvas, x+legendLineHeight/2, top+legendLineHeight/2, symbol)
+
+		canvas.SetColor(240, 240, 240) // white
+		canvas.DrawString(entry.Name, x+legendLineHeight+2, top+legendLineHeight*0.75)
+	}
+}
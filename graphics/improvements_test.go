@@ -0,0 +1,133 @@
+package graphics
+
+import (
+	"fmt"
+	"image/color"
+	"slices"
+	"testing"
+
+	"github.com/samuelyuan/Civ5MapImage/fileio"
+)
+
+func newImprovementTestMapData() *fileio.Civ5MapData {
+	mapData := newLayerTestMapData(2, 3)
+	mapData.TileImprovementList = []string{"IMPROVEMENT_FARM", "IMPROVEMENT_MINE", "IMPROVEMENT_FORT", "IMPROVEMENT_STONE_WORKS"}
+	mapData.MapTileImprovements[0][0].Improvement = 2
+	mapData.MapTileImprovements[0][1].Improvement = 0
+	mapData.MapTileImprovements[1][0].Improvement = 0
+	mapData.MapTileImprovements[1][1].Improvement = 3
+	return mapData
+}
+
+func TestImprovementMarkers(t *testing.T) {
+	markers := ImprovementMarkers(newImprovementTestMapData(), 2, 3, 16)
+
+	want := []struct {
+		improvement string
+		row, col    int
+	}{
+		{"IMPROVEMENT_FORT", 0, 0},
+		{"IMPROVEMENT_FARM", 0, 1},
+		{"IMPROVEMENT_FARM", 1, 0},
+		{"IMPROVEMENT_STONE_WORKS", 1, 1},
+	}
+	if len(markers) != len(want) {
+		t.Fatalf("ImprovementMarkers() = %+v, want %d markers", markers, len(want))
+	}
+	for i, w := range want {
+		marker := markers[i]
+		x, _ := fileio.GetImagePosition(w.row, w.col, 16)
+		if marker.Improvement != w.improvement || marker.X <= x {
+			t.Errorf("markers[%d] = %+v, want %s right of x = %v", i, marker, w.improvement, x)
+		}
+	}
+}
+
+func TestImprovementLegend(t *testing.T) {
+	entries := ImprovementLegend(newImprovementTestMapData(), 2, 3)
+
+	// One entry per improvement on the map, in list order, leaving out the unused mine
+	want := []string{"Farm", "Fort", "Stone Works"}
+	if len(entries) != len(want) {
+		t.Fatalf("ImprovementLegend() = %+v, want %v", entries, want)
+	}
+	for i, name := range want {
+		if entries[i].Name != name {
+			t.Errorf("entries[%d].Name = %q, want %q", i, entries[i].Name, name)
+		}
+	}
+	if entries[0].Symbol != improvements["IMPROVEMENT_FARM"].Symbol {
+		t.Errorf("entries[0].Symbol = %+v, want the farm symbol", entries[0].Symbol)
+	}
+}
+
+func TestImprovementLegendHasItsOwnPanel(t *testing.T) {
+	config := DefaultDrawingConfig()
+	config.ShowImprovements = true
+	canvas := NewMockCanvas(1, 1)
+	NewMapRenderer(config).DrawPoliticalMap(canvas, newImprovementTestMapData())
+
+	// The 107 pixel wide map gets a 107 pixel panel for one column of entries, the widest
+	// being "Stone Works" at 77 pixels
+	mapWidth, mapHeight := fileio.GetImagePosition(2, 3, 16)
+	ops := canvas.GetOperations()
+	if want := fmt.Sprintf("Resize(%d, %d)", int(mapWidth+107), int(mapHeight)); ops[0] != want {
+		t.Errorf("first op = %q, want %q", ops[0], want)
+	}
+	wantPanel := fmt.Sprintf("DrawRectangle(%.2f, 0.00, 107.00, %.2f)", mapWidth, mapHeight)
+	if !slices.Contains(ops, wantPanel) {
+		t.Errorf("ops = %v, want the legend panel %q right of the map", ops, wantPanel)
+	}
+
+	// A legend taller than the image wraps into more columns instead
+	entries := ImprovementLegend(newImprovementTestMapData(), 2, 3)
+	if columnWidth, perColumn, panelWidth := improvementLegendLayout(canvas, entries, 40); perColumn != 1 || panelWidth != legendPadding+3*columnWidth {
+		t.Errorf("improvementLegendLayout(40) = %v, %d, %v, want three columns of one entry", columnWidth, perColumn, panelWidth)
+	}
+}
+
+// TestImprovementLegendFitsWidestName lays out the legend with the widest names the game has in
+// gg's own font and checks each name ends inside the panel
+func TestImprovementLegendFitsWidestName(t *testing.T) {
+	canvas := NewDrawingContext(1, 1)
+	var entries []LegendEntry
+	for _, typeName := range []string{"IMPROVEMENT_FARM", "IMPROVEMENT_CUSTOMS_HOUSE", "IMPROVEMENT_TRADING_POST", "IMPROVEMENT_MOD_WORKSHOP"} {
+		entries = append(entries, LegendEntry{ImprovementName(typeName), ImprovementSymbolOf(typeName)})
+	}
+	for _, imageHeight := range []float64{40, 400} {
+		columnWidth, perColumn, panelWidth := improvementLegendLayout(canvas, entries, imageHeight)
+		for i, entry := range entries {
+			width, _ := canvas.MeasureString(entry.Name)
+			right := legendPadding + columnWidth*float64(i/perColumn) + legendLineHeight + 2 + width
+			if right > panelWidth-legendPadding {
+				t.Errorf("%q in a %v pixel tall legend ends at %v, past the %v pixel panel", entry.Name, imageHeight, right, panelWidth)
+			}
+		}
+	}
+}
+
+func TestImprovementSymbolOfModImprovements(t *testing.T) {
+	builtIn := map[ImprovementSymbol]string{}
+	for improvementType, info := range improvements {
+		shape := info.Symbol
+		shape.Color = color.RGBA{}
+		builtIn[shape] = improvementType
+	}
+
+	seen := map[ImprovementSymbol]string{}
+	for _, improvementType := range []string{"IMPROVEMENT_STONE_WORKS", "IMPROVEMENT_MOD_WORKSHOP", "IMPROVEMENT_SHRINE", "IMPROVEMENT_WINDMILL"} {
+		symbol := ImprovementSymbolOf(improvementType)
+		if symbol != ImprovementSymbolOf(improvementType) {
+			t.Errorf("ImprovementSymbolOf(%s) changed between calls", improvementType)
+		}
+		shape := symbol
+		shape.Color = color.RGBA{}
+		if other, found := builtIn[shape]; found {
+			t.Errorf("ImprovementSymbolOf(%s) = %+v, the shape of %s", improvementType, symbol, other)
+		}
+		if other, found := seen[symbol]; found {
+			t.Errorf("ImprovementSymbolOf(%s) = %+v, the same as %s", improvementType, symbol, other)
+		}
+		seen[symbol] = improvementType
+	}
+}
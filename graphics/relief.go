@@ -0,0 +1,100 @@
+package graphics
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/samuelyuan/Civ5MapImage/fileio"
+)
+
+// Shaded relief lights the map from the northwest, 45 degrees above the horizon, the same as
+// most printed relief maps
+const (
+	reliefLightAzimuth  = 3 * math.Pi / 4 // Counterclockwise from east
+	reliefLightAltitude = math.Pi / 4
+	// ReliefStrength is how far the brightest and darkest slopes are blended towards white and black
+	ReliefStrength = 0.45
+)
+
+// arcLines approximates the upper half of a circle with line segments
+func arcLines(centerX, centerY, radius float64, segments int) []Line {
+	lines := make([]Line, segments)
+	for k := 0; k < segments; k++ {
+		angle1 := math.Pi * float64(k) / float64(segments)
+		angle2 := math.Pi * float64(k+1) / float64(segments)
+		lines[k] = Line{
+			X1: centerX + radius*math.Cos(angle1),
+			Y1: centerY + radius*math.Sin(angle1),
+			X2: centerX + radius*math.Cos(angle2),
+			Y2: centerY + radius*math.Sin(angle2),
+		}
+	}
+	return lines
+}
+
+// reliefHeight is the height of tile (row, col) used for shaded relief. Water is level with flat
+// land, so coasts aren't shaded as cliffs.
+func reliefHeight(mapData *fileio.Civ5MapData, row, col int) float64 {
+	if fileio.IsWaterTile(mapData, row, col) {
+		return fileio.ElevationFlat
+	}
+	return float64(mapData.MapTiles[row][col].Elevation)
+}
+
+// TileHillshade returns how much brighter (positive) or darker (negative) tile (row, col) is
+// than flat land under shaded relief, between -1 and 1. The slope of the tile is the least
+// squares gradient of the elevation differences to its six neighbors, so a tile on a ridge that
+// faces the light is lit and one facing away is in shadow. Neighbors off the map are taken to
+// be level with the tile.
+func TileHillshade(mapData *fileio.Civ5MapData, mapHeight, mapWidth, row, col int) float64 {
+	height := reliefHeight(mapData, row, col)
+	centerX, centerY := fileio.GetImagePosition(row, col, 1)
+
+	// Gradient in elevation levels per tile, with y pointing north since row 0 is the south edge
+	var gradientX, gradientY float64
+	for _, neighbor := range fileio.GetNeighbors(col, row) {
+		newX, newY := neighbor[0], neighbor[1]
+		if newX < 0 || newY < 0 || newX >= mapWidth || newY >= mapHeight {
+			continue
+		}
+		x, y := fileio.GetImagePosition(newY, newX, 1)
+		distance := math.Hypot(x-centerX, y-centerY)
+		difference := reliefHeight(mapData, newY, newX) - height
+		gradientX += difference * (x - centerX) / distance
+		gradientY += difference * (y - centerY) / distance
+	}
+	// Six unit vectors spread evenly around a circle sum to 3 on each axis
+	gradientX /= 3
+	gradientY /= 3
+
+	// Lambertian shading of the surface normal (-gradientX, -gradientY, 1)
+	lightX := math.Cos(reliefLightAltitude) * math.Cos(reliefLightAzimuth)
+	lightY := math.Cos(reliefLightAltitude) * math.Sin(reliefLightAzimuth)
+	lightZ := math.Sin(reliefLightAltitude)
+	brightness := (-gradientX*lightX - gradientY*lightY + lightZ) / math.Sqrt(gradientX*gradientX+gradientY*gradientY+1)
+
+	// Flat land has brightness lightZ, so scale the difference to -1 (facing away) and 1 (facing the light)
+	if brightness >= lightZ {
+		return (brightness - lightZ) / (1 - lightZ)
+	}
+	return math.Max(-1, (brightness-lightZ)/lightZ)
+}
+
+// shadeColor blends c towards white for a positive shade and towards black for a negative one
+func shadeColor(c color.RGBA, shade float64) color.RGBA {
+	if shade >= 0 {
+		return blendColor(c, color.RGBA{255, 255, 255, 255}, shade*ReliefStrength)
+	}
+	return blendColor(c, color.RGBA{0, 0, 0, 255}, -shade*ReliefStrength)
+}
+
+// ShadeHexTile returns hex with its fill color shaded by the relief of tile (row, col). Water
+// tiles are left as they are.
+func ShadeHexTile(mapData *fileio.Civ5MapData, mapHeight, mapWidth, row, col int, hex HexTile) HexTile {
+	if fileio.IsWaterTile(mapData, row, col) {
+		return hex
+	}
+	shaded := shadeColor(color.RGBA{hex.R, hex.G, hex.B, 255}, TileHillshade(mapData, mapHeight, mapWidth, row, col))
+	hex.R, hex.G, hex.B = shaded.R, shaded.G, shaded.B
+	return hex
+}
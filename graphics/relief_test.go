@@ -0,0 +1,189 @@
+package graphics
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"testing"
+
+	"github.com/samuelyuan/Civ5MapImage/fileio"
+)
+
+// newReliefTestMapData returns a 3x3 grass map with the given elevations, listed by row from the
+// south edge
+func newReliefTestMapData(elevations [3][3]int) *fileio.Civ5MapData {
+	mapData := &fileio.Civ5MapData{TerrainList: []string{"TERRAIN_GRASS", "TERRAIN_OCEAN"}}
+	for _, row := range elevations {
+		var tiles []*fileio.Civ5MapTilePhysical
+		for _, elevation := range row {
+			tiles = append(tiles, &fileio.Civ5MapTilePhysical{Elevation: elevation})
+		}
+		mapData.MapTiles = append(mapData.MapTiles, tiles)
+	}
+	return mapData
+}
+
+func TestArcLines(t *testing.T) {
+	lines := arcLines(10, 20, 5, 4)
+	if len(lines) != 4 {
+		t.Fatalf("arcLines() returned %d lines, want 4", len(lines))
+	}
+	first, last := lines[0], lines[3]
+	if math.Abs(first.X1-15) > 1e-9 || math.Abs(first.Y1-20) > 1e-9 || math.Abs(last.X2-5) > 1e-9 || math.Abs(last.Y2-20) > 1e-9 {
+		t.Errorf("arcLines() runs from (%v, %v) to (%v, %v), want (15, 20) to (5, 20)", first.X1, first.Y1, last.X2, last.Y2)
+	}
+	for k := 1; k < len(lines); k++ {
+		if lines[k].X1 != lines[k-1].X2 || lines[k].Y1 != lines[k-1].Y2 {
+			t.Errorf("arcLines() segment %d doesn't start where segment %d ends", k, k-1)
+		}
+	}
+}
+
+func TestTileHillshade(t *testing.T) {
+	tests := []struct {
+		name       string
+		elevations [3][3]int
+		wantSign   int
+	}{
+		{"flat", [3][3]int{}, 0},
+		{"single peak", [3][3]int{{0, 0, 0}, {0, 2, 0}, {0, 0, 0}}, 0},
+		// Row 1 is odd, so its northwest neighbor is (row 2, col 1) and its southeast neighbor is (row 0, col 2)
+		{"rising to the northwest", [3][3]int{{0, 0, 0}, {0, 0, 0}, {0, 2, 0}}, -1},
+		{"rising to the southeast", [3][3]int{{0, 0, 2}, {0, 0, 0}, {0, 0, 0}}, 1},
+	}
+	for _, test := range tests {
+		shade := TileHillshade(newReliefTestMapData(test.elevations), 3, 3, 1, 1)
+		if shade < -1 || shade > 1 {
+			t.Errorf("%s: TileHillshade() = %v, want within [-1, 1]", test.name, shade)
+		}
+		gotSign := 0
+		if shade > 1e-9 {
+			gotSign = 1
+		} else if shade < -1e-9 {
+			gotSign = -1
+		}
+		if gotSign != test.wantSign {
+			t.Errorf("%s: TileHillshade() = %v, want sign %d", test.name, shade, test.wantSign)
+		}
+	}
+}
+
+func TestTileHillshadeIgnoresOffMapNeighborsAndWater(t *testing.T) {
+	mapData := newReliefTestMapData([3][3]int{})
+	if shade := TileHillshade(mapData, 3, 3, 0, 0); shade != 0 {
+		t.Errorf("TileHillshade() at the corner of a flat map = %v, want 0", shade)
+	}
+
+	// Ocean next to flat land is level with it
+	mapData.MapTiles[2][1].TerrainType = 1
+	if shade := TileHillshade(mapData, 3, 3, 1, 1); shade != 0 {
+		t.Errorf("TileHillshade() next to ocean = %v, want 0", shade)
+	}
+}
+
+func TestShadeHexTile(t *testing.T) {
+	mapData := newReliefTestMapData([3][3]int{{0, 0, 0}, {0, 0, 0}, {0, 2, 0}})
+	hex := HexTile{X: 1, Y: 2, R: 100, G: 100, B: 100}
+
+	shaded := ShadeHexTile(mapData, 3, 3, 1, 1, hex)
+	if shaded.X != 1 || shaded.Y != 2 || shaded.R >= 100 || shaded.R != shaded.G || shaded.G != shaded.B {
+		t.Errorf("ShadeHexTile() on a slope facing away from the light = %+v, want a darker gray", shaded)
+	}
+
+	mapData.MapTiles[1][1].TerrainType = 1
+	if water := ShadeHexTile(mapData, 3, 3, 1, 1, hex); water != hex {
+		t.Errorf("ShadeHexTile() on water = %+v, want it unchanged", water)
+	}
+}
+
+func TestShadeColor(t *testing.T) {
+	c := color.RGBA{100, 100, 100, 255}
+	if got := shadeColor(c, 0); got != c {
+		t.Errorf("shadeColor(0) = %v, want %v", got, c)
+	}
+	if got := shadeColor(c, 1); got.R <= c.R {
+		t.Errorf("shadeColor(1) = %v, want lighter than %v", got, c)
+	}
+	if got := shadeColor(c, -1); got.R >= c.R {
+		t.Errorf("shadeColor(-1) = %v, want darker than %v", got, c)
+	}
+}
+
+func TestDrawHills(t *testing.T) {
+	mr := NewMapRenderer(DefaultDrawingConfig())
+	canvas := NewMockCanvas(100, 100)
+
+	mr.DrawHills(canvas, 50, 50)
+
+	ops := canvas.GetOperations()
+	// Line width and color, two arcs of 6 segments each drawn and stroked, then the width is reset
+	if len(ops) != 2+2*6*2+1 {
+		t.Fatalf("DrawHills() recorded %d ops, want 27: %v", len(ops), ops)
+	}
+	if ops[len(ops)-1] != "SetLineWidth(1.00)" {
+		t.Errorf("DrawHills() last op = %q, want the line width reset", ops[len(ops)-1])
+	}
+}
+
+// TestDefaultRenderIgnoresHills renders a map with hills and the same map flattened to PNG, which
+// must come out byte for byte the same with the default config and differ with -hills
+func TestDefaultRenderIgnoresHills(t *testing.T) {
+	render := func(config *DrawingConfig, elevations [3][3]int, draw func(*MapRenderer, Canvas, *fileio.Civ5MapData) image.Image) []byte {
+		mapData := newReliefTestMapData(elevations)
+		for _, row := range mapData.MapTiles {
+			var improvements []*fileio.Civ5MapTileImprovement
+			for range row {
+				improvements = append(improvements, &fileio.Civ5MapTileImprovement{Owner: -1, CityId: -1, RouteType: 255})
+			}
+			mapData.MapTileImprovements = append(mapData.MapTileImprovements, improvements)
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, draw(NewMapRenderer(config), NewDrawingContext(1, 1), mapData)); err != nil {
+			t.Fatalf("png.Encode() returned error: %v", err)
+		}
+		return buf.Bytes()
+	}
+	hills := [3][3]int{{0, 1, 0}, {1, 0, 1}, {0, 1, 0}}
+	flat := [3][3]int{}
+	withHills := DefaultDrawingConfig()
+	withHills.ShowHills = true
+
+	for mode, draw := range map[string]func(*MapRenderer, Canvas, *fileio.Civ5MapData) image.Image{
+		"physical":  (*MapRenderer).DrawPhysicalMap,
+		"political": (*MapRenderer).DrawPoliticalMap,
+	} {
+		if !bytes.Equal(render(DefaultDrawingConfig(), hills, draw), render(DefaultDrawingConfig(), flat, draw)) {
+			t.Errorf("%s map with the default config drew the hills", mode)
+		}
+		if bytes.Equal(render(withHills, hills, draw), render(withHills, flat, draw)) {
+			t.Errorf("%s map with hills shown drew no hills", mode)
+		}
+	}
+}
+
+func TestDrawTerrainTilesShadedReliefOnlyWhenEnabled(t *testing.T) {
+	mapData := newReliefTestMapData([3][3]int{{0, 0, 0}, {0, 0, 0}, {0, 2, 0}})
+
+	countGrassFills := func(config *DrawingConfig) int {
+		canvas := NewMockCanvas(200, 200)
+		NewMapRenderer(config).DrawTerrainTiles(canvas, mapData, 3, 3)
+		count := 0
+		for _, op := range canvas.GetOperations() {
+			if op == "SetColor(105, 125, 54)" {
+				count++
+			}
+		}
+		return count
+	}
+
+	if got := countGrassFills(DefaultDrawingConfig()); got != 9 {
+		t.Errorf("DrawTerrainTiles() without relief filled %d tiles with plain grass, want 9", got)
+	}
+	config := DefaultDrawingConfig()
+	config.ShadedRelief = true
+	if got := countGrassFills(config); got >= 9 {
+		t.Errorf("DrawTerrainTiles() with relief filled %d tiles with plain grass, want some shaded", got)
+	}
+}
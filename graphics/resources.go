@@ -0,0 +1,238 @@
+package graphics
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/samuelyuan/Civ5MapImage/fileio"
+)
+
+// ResourceCategory groups resources the way the game does
+type ResourceCategory string
+
+const (
+	ResourceBonus     ResourceCategory = "bonus"
+	ResourceLuxury    ResourceCategory = "luxury"
+	ResourceStrategic ResourceCategory = "strategic"
+	// ResourceOther is any resource the game doesn't define, e.g. one added by a mod
+	ResourceOther ResourceCategory = "other"
+)
+
+// resourceInfo is the category of a resource and the color of its glyph
+type resourceInfo struct {
+	Category ResourceCategory
+	Color    color.RGBA
+}
+
+// resources holds the resources of the base game and its expansions
+var resources = map[string]resourceInfo{
+	"RESOURCE_IRON":     {ResourceStrategic, color.RGBA{140, 144, 156, 255}},
+	"RESOURCE_HORSE":    {ResourceStrategic, color.RGBA{156, 100, 52, 255}},
+	"RESOURCE_COAL":     {ResourceStrategic, color.RGBA{36, 36, 36, 255}},
+	"RESOURCE_OIL":      {ResourceStrategic, color.RGBA{24, 24, 72, 255}},
+	"RESOURCE_ALUMINUM": {ResourceStrategic, color.RGBA{196, 206, 222, 255}},
+	"RESOURCE_URANIUM":  {ResourceStrategic, color.RGBA{120, 224, 60, 255}},
+
+	"RESOURCE_WHALE":     {ResourceLuxury, color.RGBA{80, 100, 140, 255}},
+	"RESOURCE_PEARLS":    {ResourceLuxury, color.RGBA{226, 216, 236, 255}},
+	"RESOURCE_GOLD":      {ResourceLuxury, color.RGBA{242, 200, 40, 255}},
+	"RESOURCE_SILVER":    {ResourceLuxury, color.RGBA{180, 184, 196, 255}},
+	"RESOURCE_GEMS":      {ResourceLuxury, color.RGBA{204, 40, 120, 255}},
+	"RESOURCE_MARBLE":    {ResourceLuxury, color.RGBA{204, 196, 184, 255}},
+	"RESOURCE_IVORY":     {ResourceLuxury, color.RGBA{255, 240, 190, 255}},
+	"RESOURCE_FUR":       {ResourceLuxury, color.RGBA{124, 80, 48, 255}},
+	"RESOURCE_DYE":       {ResourceLuxury, color.RGBA{150, 60, 200, 255}},
+	"RESOURCE_SPICES":    {ResourceLuxury, color.RGBA{204, 90, 30, 255}},
+	"RESOURCE_SILK":      {ResourceLuxury, color.RGBA{232, 150, 190, 255}},
+	"RESOURCE_SUGAR":     {ResourceLuxury, color.RGBA{170, 220, 120, 255}},
+	"RESOURCE_COTTON":    {ResourceLuxury, color.RGBA{246, 246, 246, 255}},
+	"RESOURCE_WINE":      {ResourceLuxury, color.RGBA{120, 20, 60, 255}},
+	"RESOURCE_INCENSE":   {ResourceLuxury, color.RGBA{172, 132, 92, 255}},
+	"RESOURCE_CITRUS":    {ResourceLuxury, color.RGBA{250, 160, 30, 255}},
+	"RESOURCE_CRAB":      {ResourceLuxury, color.RGBA{220, 70, 50, 255}},
+	"RESOURCE_TRUFFLES":  {ResourceLuxury, color.RGBA{92, 62, 40, 255}},
+	"RESOURCE_COPPER":    {ResourceLuxury, color.RGBA{200, 112, 60, 255}},
+	"RESOURCE_SALT":      {ResourceLuxury, color.RGBA{232, 232, 212, 255}},
+	"RESOURCE_COCOA":     {ResourceLuxury, color.RGBA{112, 60, 30, 255}},
+	"RESOURCE_NUTMEG":    {ResourceLuxury, color.RGBA{152, 92, 62, 255}},
+	"RESOURCE_CLOVES":    {ResourceLuxury, color.RGBA{100, 50, 50, 255}},
+	"RESOURCE_PEPPER":    {ResourceLuxury, color.RGBA{60, 120, 40, 255}},
+	"RESOURCE_JEWELRY":   {ResourceLuxury, color.RGBA{60, 180, 200, 255}},
+	"RESOURCE_PORCELAIN": {ResourceLuxury, color.RGBA{60, 90, 200, 255}},
+
+	"RESOURCE_WHEAT":  {ResourceBonus, color.RGBA{232, 200, 90, 255}},
+	"RESOURCE_COW":    {ResourceBonus, color.RGBA{250, 250, 250, 255}},
+	"RESOURCE_SHEEP":  {ResourceBonus, color.RGBA{220, 220, 200, 255}},
+	"RESOURCE_DEER":   {ResourceBonus, color.RGBA{164, 112, 60, 255}},
+	"RESOURCE_BANANA": {ResourceBonus, color.RGBA{242, 232, 60, 255}},
+	"RESOURCE_FISH":   {ResourceBonus, color.RGBA{80, 160, 220, 255}},
+	"RESOURCE_STONE":  {ResourceBonus, color.RGBA{150, 150, 150, 255}},
+	"RESOURCE_BISON":  {ResourceBonus, color.RGBA{110, 70, 40, 255}},
+}
+
+// resourceCategorySides is the shape of the glyph for each category: circles for bonus
+// resources, diamonds for luxuries and squares for strategic resources
+var resourceCategorySides = map[ResourceCategory]struct {
+	Sides    int
+	Rotation float64
+}{
+	ResourceBonus:     {16, 0},
+	ResourceLuxury:    {4, math.Pi / 4},
+	ResourceStrategic: {4, 0},
+	ResourceOther:     {3, math.Pi},
+}
+
+// ResourceCategoryOf returns the category of a resource type, ResourceOther if the game doesn't
+// define it
+func ResourceCategoryOf(resource string) ResourceCategory {
+	if info, ok := resources[resource]; ok {
+		return info.Category
+	}
+	return ResourceOther
+}
+
+// ResourceFilter chooses which resources the resource layer draws. The zero value draws none.
+type ResourceFilter struct {
+	Categories []ResourceCategory
+	Types      []string
+}
+
+// ParseResourceFilter parses a comma separated list of categories (bonus, luxury, strategic,
+// other or all) and resource types, e.g. "strategic" or "RESOURCE_IRON,RESOURCE_OIL".
+// Categories and types can be mixed and are case insensitive.
+func ParseResourceFilter(spec string) (ResourceFilter, error) {
+	var filter ResourceFilter
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		switch category := ResourceCategory(strings.ToLower(item)); category {
+		case "all":
+			filter.Categories = append(filter.Categories, ResourceBonus, ResourceLuxury, ResourceStrategic, ResourceOther)
+		case ResourceBonus, ResourceLuxury, ResourceStrategic, ResourceOther:
+			filter.Categories = append(filter.Categories, category)
+		default:
+			resource := strings.ToUpper(item)
+			if !strings.HasPrefix(resource, "RESOURCE_") {
+				return ResourceFilter{}, fmt.Errorf("%q is not a resource category (bonus, luxury, strategic, other, all) or type (RESOURCE_*)", item)
+			}
+			filter.Types = append(filter.Types, resource)
+		}
+	}
+	return filter, nil
+}
+
+// Includes reports whether the filter draws resource
+func (filter ResourceFilter) Includes(resource string) bool {
+	category := ResourceCategoryOf(resource)
+	for _, c := range filter.Categories {
+		if c == category {
+			return true
+		}
+	}
+	for _, t := range filter.Types {
+		if t == resource {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEmpty reports whether the filter draws no resources at all
+func (filter ResourceFilter) IsEmpty() bool {
+	return len(filter.Categories) == 0 && len(filter.Types) == 0
+}
+
+// ResourceMarker is a resource glyph in the upper left of a tile, plus a label with the amount
+// for strategic resources. Label.Text is empty for other resources.
+type ResourceMarker struct {
+	Resource string
+	Category ResourceCategory
+	X, Y     float64
+	Color    color.RGBA
+	Label    ColoredText
+}
+
+// resourceGlyphOffset places the glyph in the upper left of the tile, clear of city icons and
+// feature patterns in the middle
+const (
+	resourceGlyphOffsetX = -0.4
+	resourceGlyphOffsetY = 0.35
+)
+
+// ResourceMarkers returns a marker for every resource on the map that filter includes
+func ResourceMarkers(mapData *fileio.Civ5MapData, mapHeight, mapWidth int, radius float64, filter ResourceFilter) []ResourceMarker {
+	var markers []ResourceMarker
+	for i := 0; i < mapHeight; i++ {
+		for j := 0; j < mapWidth; j++ {
+			resource := fileio.GetResourceString(mapData, i, j)
+			if resource == "" || !filter.Includes(resource) {
+				continue
+			}
+
+			category := ResourceCategoryOf(resource)
+			glyphColor := color.RGBA{128, 128, 128, 255}
+			if info, ok := resources[resource]; ok {
+				glyphColor = info.Color
+			}
+			x, y := fileio.GetImagePosition(i, j, radius)
+			marker := ResourceMarker{
+				Resource: resource,
+				Category: category,
+				X:        x + resourceGlyphOffsetX*radius,
+				Y:        y + resourceGlyphOffsetY*radius,
+				Color:    glyphColor,
+			}
+
+			amount := mapData.MapTiles[i][j].ResourceAmount
+			if category == ResourceStrategic && amount > 0 {
+				// Label coordinates are row-inverted, so the glyph is above the tile center
+				labelX, labelY := fileio.GetImagePosition(InvertedRow(mapHeight, i), j, radius)
+				marker.Label = ColoredText{
+					Text: strconv.Itoa(amount),
+					X:    labelX + (resourceGlyphOffsetX+0.3)*radius,
+					Y:    labelY - resourceGlyphOffsetY*radius + 4,
+					R:    255,
+					G:    255,
+					B:    255,
+				}
+			}
+			markers = append(markers, marker)
+		}
+	}
+	return markers
+}
+
+// DrawResourceGlyph draws a resource glyph in its category's shape, ringed in white
+func (mr *MapRenderer) DrawResourceGlyph(canvas Canvas, imageX, imageY float64, category ResourceCategory, glyphColor color.RGBA) {
+	shape := resourceCategorySides[category]
+	canvas.DrawRegularPolygon(shape.Sides, imageX, imageY, mr.config.Radius*0.3, shape.Rotation)
+	canvas.SetColor(250, 250, 240) // white
+	canvas.Fill()
+
+	canvas.DrawRegularPolygon(shape.Sides, imageX, imageY, mr.config.Radius*0.2, shape.Rotation)
+	canvas.SetColor(glyphColor.R, glyphColor.G, glyphColor.B)
+	canvas.Fill()
+}
+
+// DrawResources draws the glyph of every resource the configured filter includes
+func (mr *MapRenderer) DrawResources(canvas Canvas, mapData *fileio.Civ5MapData, mapHeight, mapWidth int) {
+	for _, marker := range ResourceMarkers(mapData, mapHeight, mapWidth, mr.config.Radius, mr.config.Resources) {
+		mr.DrawResourceGlyph(canvas, marker.X, marker.Y, marker.Category, marker.Color)
+	}
+}
+
+// DrawResourceAmounts draws the amount next to every strategic resource the configured filter includes
+func (mr *MapRenderer) DrawResourceAmounts(canvas Canvas, mapData *fileio.Civ5MapData, mapHeight, mapWidth int) {
+	for _, marker := range ResourceMarkers(mapData, mapHeight, mapWidth, mr.config.Radius, mr.config.Resources) {
+		if marker.Label.Text == "" {
+			continue
+		}
+		canvas.SetColor(marker.Label.R, marker.Label.G, marker.Label.B)
+		canvas.DrawString(marker.Label.Text, marker.Label.X, marker.Label.Y)
+	}
+}
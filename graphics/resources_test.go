@@ -0,0 +1,85 @@
+package graphics
+
+import (
+	"testing"
+
+	"github.com/samuelyuan/Civ5MapImage/fileio"
+)
+
+func newResourceTestMapData() *fileio.Civ5MapData {
+	mapData := newLayerTestMapData(2, 3)
+	mapData.ResourceList = []string{"RESOURCE_IRON", "RESOURCE_WHEAT", "RESOURCE_SILK", "RESOURCE_TEA"}
+	for i, tile := range []*fileio.Civ5MapTilePhysical{mapData.MapTiles[0][0], mapData.MapTiles[0][1], mapData.MapTiles[1][0], mapData.MapTiles[1][1]} {
+		tile.ResourceType, tile.ResourceAmount = i, 1
+	}
+	mapData.MapTiles[0][0].ResourceAmount = 6
+	return mapData
+}
+
+func TestParseResourceFilter(t *testing.T) {
+	filter, err := ParseResourceFilter("Strategic, resource_wheat")
+	if err != nil {
+		t.Fatalf("ParseResourceFilter() error = %v", err)
+	}
+	tests := map[string]bool{
+		"RESOURCE_IRON":  true,
+		"RESOURCE_OIL":   true,
+		"RESOURCE_WHEAT": true,
+		"RESOURCE_COW":   false,
+		"RESOURCE_SILK":  false,
+		"RESOURCE_TEA":   false,
+	}
+	for resource, want := range tests {
+		if got := filter.Includes(resource); got != want {
+			t.Errorf("Includes(%q) = %v, want %v", resource, got, want)
+		}
+	}
+
+	all, err := ParseResourceFilter("all")
+	if err != nil || !all.Includes("RESOURCE_TEA") || !all.Includes("RESOURCE_SILK") {
+		t.Errorf("ParseResourceFilter(\"all\") = %+v, %v, want every resource", all, err)
+	}
+	empty, err := ParseResourceFilter("")
+	if err != nil || !empty.IsEmpty() || empty.Includes("RESOURCE_IRON") {
+		t.Errorf("ParseResourceFilter(\"\") = %+v, %v, want an empty filter", empty, err)
+	}
+	if _, err := ParseResourceFilter("strategic,iron"); err == nil {
+		t.Errorf("ParseResourceFilter(\"strategic,iron\") should fail")
+	}
+}
+
+func TestResourceMarkers(t *testing.T) {
+	filter, _ := ParseResourceFilter("all")
+	markers := ResourceMarkers(newResourceTestMapData(), 2, 3, 16, filter)
+
+	want := []struct {
+		resource string
+		category ResourceCategory
+		amount   string
+		row, col int
+	}{
+		{"RESOURCE_IRON", ResourceStrategic, "6", 0, 0},
+		{"RESOURCE_WHEAT", ResourceBonus, "", 0, 1},
+		{"RESOURCE_SILK", ResourceLuxury, "", 1, 0},
+		{"RESOURCE_TEA", ResourceOther, "", 1, 1},
+	}
+	if len(markers) != len(want) {
+		t.Fatalf("ResourceMarkers() = %+v, want %d markers", markers, len(want))
+	}
+	for i, w := range want {
+		marker := markers[i]
+		x, y := fileio.GetImagePosition(w.row, w.col, 16)
+		if marker.Resource != w.resource || marker.Category != w.category || marker.Label.Text != w.amount {
+			t.Errorf("markers[%d] = %+v, want %s (%s) with amount %q", i, marker, w.resource, w.category, w.amount)
+		}
+		// The glyph sits in the upper left of the tile
+		if marker.X >= x || marker.Y <= y {
+			t.Errorf("markers[%d] at (%v, %v), want up and left of (%v, %v)", i, marker.X, marker.Y, x, y)
+		}
+	}
+
+	strategic, _ := ParseResourceFilter("strategic")
+	if markers := ResourceMarkers(newResourceTestMapData(), 2, 3, 16, strategic); len(markers) != 1 || markers[0].Resource != "RESOURCE_IRON" {
+		t.Errorf("ResourceMarkers(strategic) = %+v, want only RESOURCE_IRON", markers)
+	}
+}
@@ -0,0 +1,154 @@
+package graphics
+
+import (
+	"image/color"
+	"math"
+	"strings"
+
+	"github.com/samuelyuan/Civ5MapImage/fileio"
+)
+
+// NaturalWonderIcon is the symbol drawn inside a natural wonder's gold badge: a regular polygon
+// with its own number of sides, rotation and color
+type NaturalWonderIcon struct {
+	Sides    int
+	Rotation float64
+	Color    color.RGBA
+}
+
+// naturalWonder is the display name and icon of a natural wonder
+type naturalWonder struct {
+	Name string
+	Icon NaturalWonderIcon
+}
+
+// naturalWonders holds the natural wonders of the base game and its expansions. Peaks are
+// triangles, water is round and treasures are squares or diamonds, each in its own color.
+var naturalWonders = map[string]naturalWonder{
+	"FEATURE_CRATER":         {"Barringer Crater", NaturalWonderIcon{16, 0, color.RGBA{120, 92, 64, 255}}},
+	"FEATURE_FUJI":           {"Mt. Fuji", NaturalWonderIcon{3, math.Pi, color.RGBA{236, 240, 250, 255}}},
+	"FEATURE_MESA":           {"Grand Mesa", NaturalWonderIcon{4, 0, color.RGBA{178, 94, 56, 255}}},
+	"FEATURE_REEF":           {"Great Barrier Reef", NaturalWonderIcon{5, 0, color.RGBA{240, 120, 130, 255}}},
+	"FEATURE_VOLCANO":        {"Krakatoa", NaturalWonderIcon{3, math.Pi, color.RGBA{214, 48, 32, 255}}},
+	"FEATURE_GIBRALTAR":      {"Rock of Gibraltar", NaturalWonderIcon{3, math.Pi, color.RGBA{150, 150, 140, 255}}},
+	"FEATURE_GEYSER":         {"Old Faithful", NaturalWonderIcon{3, 0, color.RGBA{150, 210, 240, 255}}},
+	"FEATURE_FOUNTAIN_YOUTH": {"Fountain of Youth", NaturalWonderIcon{16, 0, color.RGBA{110, 220, 210, 255}}},
+	"FEATURE_POTOSI":         {"Cerro de Potosi", NaturalWonderIcon{4, 0, color.RGBA{200, 204, 214, 255}}},
+	"FEATURE_EL_DORADO":      {"El Dorado", NaturalWonderIcon{4, 0, color.RGBA{250, 200, 30, 255}}},
+	"FEATURE_SRI_PADA":       {"Sri Pada", NaturalWonderIcon{3, math.Pi, color.RGBA{90, 160, 80, 255}}},
+	"FEATURE_MT_SINAI":       {"Mt. Sinai", NaturalWonderIcon{3, math.Pi, color.RGBA{210, 170, 110, 255}}},
+	"FEATURE_MT_KAILASH":     {"Mt. Kailash", NaturalWonderIcon{3, math.Pi, color.RGBA{120, 140, 220, 255}}},
+	"FEATURE_ULURU":          {"Uluru", NaturalWonderIcon{6, math.Pi / 2, color.RGBA{200, 80, 40, 255}}},
+	"FEATURE_LAKE_VICTORIA":  {"Lake Victoria", NaturalWonderIcon{16, 0, color.RGBA{40, 90, 200, 255}}},
+	"FEATURE_KILIMANJARO":    {"Mt. Kilimanjaro", NaturalWonderIcon{3, math.Pi, color.RGBA{180, 210, 120, 255}}},
+	"FEATURE_SOLOMONS_MINES": {"King Solomon's Mines", NaturalWonderIcon{4, math.Pi / 4, color.RGBA{230, 140, 30, 255}}},
+}
+
+// NaturalWonderName returns the name to label a natural wonder with, e.g. "Mt. Fuji" for
+// FEATURE_FUJI. Wonders added by mods are named after their type, e.g. FEATURE_LAKE_TITICACA
+// becomes "Lake Titicaca".
+func NaturalWonderName(feature string) string {
+	if wonder, ok := naturalWonders[feature]; ok {
+		return wonder.Name
+	}
+	return titleCaseType(feature, "FEATURE_")
+}
+
+// titleCaseType turns a type name into words, e.g. "Lake Titicaca" for FEATURE_LAKE_TITICACA
+// with the prefix FEATURE_
+func titleCaseType(typeName, prefix string) string {
+	words := strings.Split(strings.ToLower(strings.TrimPrefix(typeName, prefix)), "_")
+	for i, word := range words {
+		if word != "" {
+			words[i] = strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// tileNaturalWonder returns the natural wonder on tile (row, col), or "" if it has none. Maps
+// index their own natural wonder list, while replay tiles store the wonder as a feature.
+func tileNaturalWonder(mapData *fileio.Civ5MapData, row, col int) string {
+	if wonder := fileio.GetFeatureWonderString(mapData, row, col); wonder != "" {
+		return wonder
+	}
+	if feature := fileio.GetFeatureString(mapData, row, col); naturalWonders[feature].Name != "" {
+		return feature
+	}
+	return ""
+}
+
+// NaturalWonderMarker is a natural wonder's icon plus a label with its name below it.
+type NaturalWonderMarker struct {
+	Feature string
+	X, Y    float64
+	Icon    NaturalWonderIcon
+	Label   ColoredText
+}
+
+// NaturalWonderMarkers returns a marker for every natural wonder on the map. Wonders added by
+// mods get a white pentagon.
+func NaturalWonderMarkers(mapData *fileio.Civ5MapData, mapHeight, mapWidth int, radius float64) []NaturalWonderMarker {
+	var markers []NaturalWonderMarker
+	for i := 0; i < mapHeight; i++ {
+		for j := 0; j < mapWidth; j++ {
+			feature := tileNaturalWonder(mapData, i, j)
+			if feature == "" {
+				continue
+			}
+
+			icon := NaturalWonderIcon{Sides: 5, Rotation: math.Pi, Color: color.RGBA{255, 255, 255, 255}}
+			if wonder, ok := naturalWonders[feature]; ok {
+				icon = wonder.Icon
+			}
+			name := NaturalWonderName(feature)
+			x, y := fileio.GetImagePosition(i, j, radius)
+			labelX, labelY := fileio.GetImagePosition(InvertedRow(mapHeight, i), j, radius)
+			markers = append(markers, NaturalWonderMarker{
+				Feature: feature,
+				X:       x,
+				Y:       y,
+				Icon:    icon,
+				Label: ColoredText{
+					Text: name,
+					X:    labelX - (6.0 * float64(len(name)) / 2.0),
+					Y:    labelY + radius*1.3,
+					R:    255,
+					G:    228,
+					B:    140,
+				},
+			})
+		}
+	}
+	return markers
+}
+
+// DrawNaturalWonderIcon draws a gold badge with the wonder's symbol inside it
+func (mr *MapRenderer) DrawNaturalWonderIcon(canvas Canvas, imageX, imageY float64, icon NaturalWonderIcon) {
+	canvas.DrawRegularPolygon(24, imageX, imageY, mr.config.Radius*0.6, 0)
+	canvas.SetColor(212, 175, 55) // gold
+	canvas.Fill()
+
+	canvas.DrawRegularPolygon(24, imageX, imageY, mr.config.Radius*0.48, 0)
+	canvas.SetColor(40, 36, 60) // dark purple
+	canvas.Fill()
+
+	canvas.DrawRegularPolygon(icon.Sides, imageX, imageY, mr.config.Radius*0.34, icon.Rotation)
+	canvas.SetColor(icon.Color.R, icon.Color.G, icon.Color.B)
+	canvas.Fill()
+}
+
+// DrawNaturalWonders draws the icon of every natural wonder on the map
+func (mr *MapRenderer) DrawNaturalWonders(canvas Canvas, mapData *fileio.Civ5MapData, mapHeight, mapWidth int) {
+	for _, marker := range NaturalWonderMarkers(mapData, mapHeight, mapWidth, mr.config.Radius) {
+		mr.DrawNaturalWonderIcon(canvas, marker.X, marker.Y, marker.Icon)
+	}
+}
+
+// DrawNaturalWonderNames draws the name of every natural wonder below its icon
+func (mr *MapRenderer) DrawNaturalWonderNames(canvas Canvas, mapData *fileio.Civ5MapData, mapHeight, mapWidth int) {
+	for _, marker := range NaturalWonderMarkers(mapData, mapHeight, mapWidth, mr.config.Radius) {
+		canvas.SetColor(marker.Label.R, marker.Label.G, marker.Label.B)
+		canvas.DrawString(marker.Label.Text, marker.Label.X, marker.Label.Y)
+	}
+}
@@ -0,0 +1,50 @@
+package graphics
+
+import (
+	"testing"
+
+	"github.com/samuelyuan/Civ5MapImage/fileio"
+)
+
+func newWonderTestMapData() *fileio.Civ5MapData {
+	mapData := newLayerTestMapData(2, 3)
+	mapData.FeatureTerrainList = fileio.ReplayFeatureList
+	mapData.FeatureWonderList = []string{"FEATURE_FUJI", "FEATURE_LAKE_TITICACA"}
+	mapData.MapTiles[0][0].FeatureWonderType = 0
+	mapData.MapTiles[0][2].FeatureWonderType = 1
+	// Replay tiles store the wonder as a feature
+	mapData.MapTiles[1][0].FeatureTerrainType = 11
+	mapData.MapTiles[1][1].FeatureTerrainType = 5
+	return mapData
+}
+
+func TestNaturalWonderMarkers(t *testing.T) {
+	markers := NaturalWonderMarkers(newWonderTestMapData(), 2, 3, 16)
+
+	want := []struct {
+		feature, label string
+		row, col       int
+	}{
+		{"FEATURE_FUJI", "Mt. Fuji", 0, 0},
+		{"FEATURE_LAKE_TITICACA", "Lake Titicaca", 0, 2},
+		{"FEATURE_VOLCANO", "Krakatoa", 1, 0},
+	}
+	if len(markers) != len(want) {
+		t.Fatalf("NaturalWonderMarkers() = %+v, want %d markers", markers, len(want))
+	}
+	for i, w := range want {
+		marker := markers[i]
+		x, y := fileio.GetImagePosition(w.row, w.col, 16)
+		if marker.Feature != w.feature || marker.Label.Text != w.label || marker.X != x || marker.Y != y {
+			t.Errorf("markers[%d] = %+v, want %s labeled %q at (%v, %v)", i, marker, w.feature, w.label, x, y)
+		}
+		// The label is centered below the tile, in the row-inverted text coordinates
+		labelX, labelY := fileio.GetImagePosition(InvertedRow(2, w.row), w.col, 16)
+		if marker.Label.X >= labelX || marker.Label.Y <= labelY {
+			t.Errorf("markers[%d] label at (%v, %v), want left of and below (%v, %v)", i, marker.Label.X, marker.Label.Y, labelX, labelY)
+		}
+	}
+	if markers[1].Icon.Sides != 5 || markers[1].Icon.Color.R != 255 {
+		t.Errorf("mod wonder icon = %+v, want a white pentagon", markers[1].Icon)
+	}
+}
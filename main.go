@@ -4,7 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"path/filepath"
+	"os"
 	"strings"
 
 	"github.com/samuelyuan/Civ5MapImage/fileio"
@@ -19,13 +19,26 @@ const (
 	ModePolitical  DrawingMode = "political"
 	ModeReplay     DrawingMode = "replay"
 	ModeExportJSON DrawingMode = "exportjson"
+	// ModeExportMap writes the input map as a game-loadable .civ5map, see fileio.WriteCiv5MapFile
+	ModeExportMap DrawingMode = "exportmap"
+	// ModeExportCompact writes the input map as a compact json, see fileio.Civ5CompactMapJson
+	ModeExportCompact DrawingMode = "exportcompact"
+	// ModeExportText writes the input map in the text map format, see fileio.WriteCiv5MapText
+	ModeExportText DrawingMode = "exporttext"
+	ModeExportCSV  DrawingMode = "exportcsv"
+	ModeEvents     DrawingMode = "events"
+	// ModeChronicle writes a Markdown or HTML history of a replay, see fileio.BuildChronicle
+	ModeChronicle DrawingMode = "chronicle"
 )
 
 func loadMapDataFromFile(filename string) *fileio.Civ5MapData {
-	mapFileExtension := filepath.Ext(filename)
+	fileType, err := fileio.DetectFileTypeFromFile(filename)
+	if err != nil {
+		log.Fatal("Failed to read input file: ", err)
+	}
 
-	switch strings.ToLower(mapFileExtension) {
-	case string(fileio.FileTypeJSON):
+	switch fileType {
+	case fileio.FileTypeJSON:
 		fmt.Println("Importing map file from json")
 		mapData, err := fileio.ImportCiv5MapFileFromJson(filename)
 		if err != nil {
@@ -33,7 +46,15 @@ func loadMapDataFromFile(filename string) *fileio.Civ5MapData {
 		}
 		graphics.OverrideColorMap(mapData.CivColorOverrides)
 		return mapData
-	case string(fileio.FileTypeCiv5Map):
+	case fileio.FileTypeCiv5MapText:
+		fmt.Println("Reading map from text map file")
+		mapData, err := fileio.ReadCiv5MapTextFile(filename)
+		if err != nil {
+			log.Fatal("Failed to read text map: ", err)
+		}
+		graphics.OverrideColorMap(mapData.CivColorOverrides)
+		return mapData
+	case fileio.FileTypeCiv5Map:
 		fmt.Println("Reading map from .civ5map file")
 		mapData, err := fileio.ReadCiv5MapFile(filename)
 		if err != nil {
@@ -41,16 +62,66 @@ func loadMapDataFromFile(filename string) *fileio.Civ5MapData {
 		}
 		return mapData
 	default:
-		log.Fatalf("Input map file has invalid file extension. Filename: %s, extension: %s", filename, mapFileExtension)
+		log.Fatalf("Input file %s is a %s file, which doesn't contain a map", filename, fileType)
 	}
 	return nil
 }
 
+func exportMapFile(mapData *fileio.Civ5MapData, outputFilename string) {
+	outputFile, err := os.Create(outputFilename)
+	if err != nil {
+		log.Fatal("Failed to create output file: ", err)
+	}
+
+	// The .civ5map format has no place for color overrides, so the game uses the civs' own colors
+	if len(mapData.CivColorOverrides) > 0 {
+		fmt.Printf("Warning: %d civ color overrides are not saved in a .civ5map file\n", len(mapData.CivColorOverrides))
+	}
+
+	fmt.Println("Exporting map to", outputFilename)
+	if err := fileio.WriteCiv5MapFile(mapData, outputFile); err != nil {
+		outputFile.Close()
+		log.Fatal("Failed to export map: ", err)
+	}
+	if err := outputFile.Close(); err != nil {
+		log.Fatal("Failed to write output file: ", err)
+	}
+}
+
+// listReplayEvents prints the classified events of a replay, keeping only the kinds in the
+// comma separated kindFilter if it isn't empty
+func listReplayEvents(replayData *fileio.Civ5ReplayData, kindFilter string) {
+	kinds := map[fileio.ReplayEventKind]bool{}
+	for _, kind := range strings.Split(kindFilter, ",") {
+		if kind = strings.TrimSpace(kind); kind != "" {
+			kinds[fileio.ReplayEventKind(strings.ToLower(kind))] = true
+		}
+	}
+
+	for _, typedEvent := range fileio.ClassifyReplayEvents(replayData) {
+		if len(kinds) > 0 && !kinds[typedEvent.Kind] {
+			continue
+		}
+		fmt.Printf("Turn %d\t%s\t%s\n", typedEvent.Event.Turn, typedEvent.Kind, typedEvent.Description())
+	}
+}
+
 func main() {
 	inputPtr := flag.String("input", "", "Input filename")
 	outputPtr := flag.String("output", "output.png", "Output filename")
 	replayFilePtr := flag.String("replay", "", "Replay filename for replay mode")
 	modePtr := flag.String("mode", "physical", "Drawing mode")
+	startPositionsPtr := flag.Bool("startpositions", false, "Draw player start positions on physical and political maps")
+	featuresPtr := flag.Bool("features", false, "Draw forests, jungles, marshes and other terrain features on physical and political maps")
+	hillsPtr := flag.Bool("hills", false, "Draw hills on physical and political maps")
+	reliefPtr := flag.Bool("relief", false, "Shade the terrain by elevation on physical and political maps")
+	wondersPtr := flag.Bool("wonders", false, "Draw natural wonders and their names on physical and political maps")
+	resourcesPtr := flag.String("resources", "", "Resources to draw on physical and political maps: bonus, luxury, strategic, all or a comma separated list of types, e.g. RESOURCE_IRON,RESOURCE_OIL")
+	improvementsPtr := flag.Bool("improvements", false, "Draw farms, mines, forts and other tile improvements with a legend on physical and political maps")
+	widePtr := flag.Bool("wide", false, "Write one column per civ in exportcsv mode")
+	eventKindsPtr := flag.String("eventkinds", "", "Comma separated event kinds to list in events mode, e.g. wardeclared,peacemade")
+	groupByPtr := flag.String("groupby", "era", "Split the chronicle by era or by turns")
+	turnRangePtr := flag.Int("turnrange", fileio.DefaultChronicleTurnsPerSection, "Turns per chronicle section when grouping by turns")
 
 	flag.Parse()
 
@@ -66,11 +137,64 @@ func main() {
 		return
 	}
 
+	if mode == string(ModeExportCSV) {
+		replayData := fileio.LoadReplayDataFromFile(inputFilename)
+		fmt.Println("Exporting replay datasets to", outputFilename)
+		if err := fileio.ExportReplayDatasetsCsv(replayData, outputFilename, *widePtr); err != nil {
+			log.Fatal("Failed to export replay datasets: ", err)
+		}
+		return
+	}
+
+	if mode == string(ModeEvents) {
+		listReplayEvents(fileio.LoadReplayDataFromFile(inputFilename), *eventKindsPtr)
+		return
+	}
+
+	if mode == string(ModeChronicle) {
+		groupBy := fileio.ChronicleGrouping(strings.ToLower(*groupByPtr))
+		if groupBy != fileio.ChronicleByEra && groupBy != fileio.ChronicleByTurns {
+			log.Fatal("Invalid chronicle grouping: " + *groupByPtr + ". Grouping must be era or turns.")
+		}
+		replayData := fileio.LoadReplayDataFromFile(inputFilename)
+		fmt.Println("Writing chronicle to", outputFilename)
+		options := fileio.ChronicleOptions{GroupBy: groupBy, TurnsPerSection: *turnRangePtr}
+		if err := fileio.ExportChronicle(replayData, outputFilename, options); err != nil {
+			log.Fatal("Failed to write chronicle: ", err)
+		}
+		return
+	}
+
+	if mode == string(ModeReplay) {
+		// The map is optional for replays, which carry their own tiles
+		var mapData *fileio.Civ5MapData
+		if inputFilename != "" {
+			mapData = loadMapDataFromFile(inputFilename)
+		}
+		replayData := fileio.LoadReplayDataFromFile(*replayFilePtr)
+		if err := graphics.DrawReplay(mapData, replayData, outputFilename); err != nil {
+			log.Fatal("Failed to draw replay: ", err)
+		}
+		return
+	}
+
 	mapData := loadMapDataFromFile(inputFilename)
 
+	resourceFilter, err := graphics.ParseResourceFilter(*resourcesPtr)
+	if err != nil {
+		log.Fatal("Invalid resource filter: ", err)
+	}
+
 	switch mode {
 	case string(ModePhysical):
 		config := graphics.DefaultDrawingConfig()
+		config.ShowStartPositions = *startPositionsPtr
+		config.ShowFeatures = *featuresPtr
+		config.ShowHills = *hillsPtr
+		config.ShadedRelief = *reliefPtr
+		config.ShowNaturalWonders = *wondersPtr
+		config.Resources = resourceFilter
+		config.ShowImprovements = *improvementsPtr
 		renderer := graphics.NewMapRenderer(config)
 		canvas := graphics.NewDrawingContext(800, 600)
 		renderer.DrawPhysicalMap(canvas, mapData)
@@ -78,19 +202,34 @@ func main() {
 		return
 	case string(ModePolitical):
 		config := graphics.DefaultDrawingConfig()
+		config.ShowStartPositions = *startPositionsPtr
+		config.ShowFeatures = *featuresPtr
+		config.ShowHills = *hillsPtr
+		config.ShadedRelief = *reliefPtr
+		config.ShowNaturalWonders = *wondersPtr
+		config.Resources = resourceFilter
+		config.ShowImprovements = *improvementsPtr
 		renderer := graphics.NewMapRenderer(config)
 		canvas := graphics.NewDrawingContext(800, 600)
 		renderer.DrawPoliticalMap(canvas, mapData)
 		renderer.SaveImage(canvas, outputFilename)
 		return
-	case string(ModeReplay):
-		replayFilename := *replayFilePtr
-		replayData := fileio.LoadReplayDataFromFile(replayFilename)
-		if err := graphics.DrawReplay(mapData, replayData, outputFilename); err != nil {
-			log.Fatal("Failed to draw replay: ", err)
+	case string(ModeExportMap):
+		exportMapFile(mapData, outputFilename)
+		return
+	case string(ModeExportCompact):
+		fmt.Println("Exporting compact map to", outputFilename)
+		if err := fileio.ExportCiv5MapFileCompact(mapData, outputFilename); err != nil {
+			log.Fatal("Failed to export compact map: ", err)
+		}
+		return
+	case string(ModeExportText):
+		fmt.Println("Exporting text map to", outputFilename)
+		if err := fileio.ExportCiv5MapTextFile(mapData, outputFilename); err != nil {
+			log.Fatal("Failed to export text map: ", err)
 		}
 		return
 	default:
-		log.Fatal("Invalid drawing mode: " + mode + ". Mode must be in this list [physical, political, replay, exportjson].")
+		log.Fatal("Invalid drawing mode: " + mode + ". Mode must be in this list [physical, political, replay, exportjson, exportmap, exportcompact, exporttext, exportcsv, events, chronicle].")
 	}
 }